@@ -3,22 +3,33 @@ package main
 import (
 	"context"
 	"flag"
+	"io"
 	"log"
+	"os"
 
 	"the_game_card_game/pkg/bot"
+	"the_game_card_game/pkg/game"
 	pb "the_game_card_game/proto"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 var (
 	serverAddr = flag.String("server", "localhost:50051", "The server address in the format of host:port")
-	strategy   = flag.String("strategy", "random", "The bot's strategy (e.g., random, minimal-jump)")
+	strategy   = flag.String("strategy", "random", "The bot's strategy spec (e.g., random, phased:early=60,mid=25)")
+	list       = flag.Bool("list_strategies", false, "List the available strategies and their parameters, then exit")
 	numGames   = flag.Int("num_games", 1, "The number of games the bot should play")
+	seed       = flag.Int64("seed", 0, "If non-zero, game i is dealt from seed+i so runs can be replayed")
 )
 
 func main() {
 	flag.Parse()
+	if *list {
+		bot.DefaultRegistry.Describe(os.Stdout)
+		return
+	}
 
 	conn, err := grpc.Dial(*serverAddr, grpc.WithInsecure())
 	if err != nil {
@@ -28,33 +39,32 @@ func main() {
 
 	client := pb.NewGameServiceClient(conn)
 
-	var botStrategy bot.Strategy
-	switch *strategy {
-	case "random":
-		botStrategy = bot.NewRandomStrategy()
-	case "minimal-jump":
-		botStrategy = bot.NewMinimalJumpStrategy()
-	case "safe-ten":
-		botStrategy = bot.NewSafeTenStrategy()
-	case "smart":
-		botStrategy = bot.NewSmartStrategy()
-	case "phased":
-		botStrategy = bot.NewPhasedStrategy()
-	case "two-card-greedy":
-		botStrategy = bot.NewTwoCardGreedyStrategy()
-	default:
-		log.Fatalf("Unknown strategy: %s", *strategy)
+	spec, err := bot.DefaultRegistry.Parse(*strategy)
+	if err != nil {
+		log.Fatalf("Invalid strategy: %v", err)
+	}
+	botStrategy, err := spec.New()
+	if err != nil {
+		log.Fatalf("Could not build strategy %s: %v", spec, err)
+	}
+	if c, ok := botStrategy.(io.Closer); ok {
+		defer c.Close()
 	}
 
 	for i := 0; i < *numGames; i++ {
 		log.Printf("--- Starting Game %d of %d ---", i+1, *numGames)
-		playGame(client, botStrategy, *strategy)
+		createReq := &pb.CreateGameRequest{}
+		if *seed != 0 {
+			gameSeed := *seed + int64(i)
+			createReq.Seed = &gameSeed
+		}
+		playGame(client, botStrategy, spec.String(), createReq)
 	}
 }
 
-func playGame(client pb.GameServiceClient, botStrategy bot.Strategy, strategyName string) {
+func playGame(client pb.GameServiceClient, botStrategy bot.TurnStrategy, strategyName string, createReq *pb.CreateGameRequest) {
 	// Create a new game
-	createGameResp, err := client.CreateGame(context.Background(), &pb.CreateGameRequest{})
+	createGameResp, err := client.CreateGame(context.Background(), createReq)
 	if err != nil {
 		log.Printf("could not create game: %v", err)
 		return
@@ -62,7 +72,12 @@ func playGame(client pb.GameServiceClient, botStrategy bot.Strategy, strategyNam
 	gameID := createGameResp.GameState.GameId
 	playerID := createGameResp.GameState.PlayerIds[0]
 
-	log.Printf("Game created with ID: %s, Player ID: %s", gameID, playerID)
+	// The server hides the seed from players, so log the one that was asked for.
+	if createReq.Seed != nil {
+		log.Printf("Game created with ID: %s, Player ID: %s, Seed: %d", gameID, playerID, createReq.GetSeed())
+	} else {
+		log.Printf("Game created with ID: %s, Player ID: %s, Seed: random", gameID, playerID)
+	}
 
 	// Join the game
 	joinRes, err := client.JoinGame(context.Background(), &pb.JoinGameRequest{
@@ -76,24 +91,46 @@ func playGame(client pb.GameServiceClient, botStrategy bot.Strategy, strategyNam
 	}
 	log.Printf("Successfully joined game: %v", joinRes.GetSuccess())
 
+	// Start the game
+	startRes, err := client.StartGame(context.Background(), &pb.StartGameRequest{GameId: gameID, PlayerId: playerID})
+	if err != nil {
+		log.Printf("could not start game: %v", err)
+		return
+	}
+	if !startRes.GetSuccess() {
+		log.Printf("could not start game: %s", startRes.GetMessage())
+		return
+	}
+
+	ctx := context.Background()
+	observer, _ := botStrategy.(bot.GameObserver)
+	if observer != nil {
+		view := &bot.View{PlayerID: playerID, State: startRes.GetGameState()}
+		if err := observer.GameStarted(ctx, view); err != nil {
+			log.Printf("strategy could not start the game: %v", err)
+			return
+		}
+	}
+
 	// Game loop
+	var history []bot.Turn
+	var turn bot.Turn
+turns:
 	for {
 		// Get game state
 		// In a real bot, you'd likely have a streaming connection, but for this simple one,
 		// we'll just get the state at the beginning of our turn.
-		stream, err := client.StreamGameState(context.Background(), &pb.StreamGameStateRequest{GameId: gameID})
-		if err != nil {
-			log.Printf("could not open stream: %v", err)
-			return
-		}
-		gameState, err := stream.Recv()
+		gameState, err := readState(ctx, client, gameID, playerID)
 		if err != nil {
-			log.Printf("could not receive game state: %v", err)
+			log.Printf("could not read game state: %v", err)
 			return
 		}
 
-		if gameState.GetGameOver() {
+		if game.IsOver(gameState) {
 			log.Printf("Game is over: %s", gameState.GetMessage())
+			if observer != nil {
+				observer.GameOver(ctx, &bot.View{PlayerID: playerID, State: gameState, History: history})
+			}
 			break
 		}
 
@@ -101,41 +138,68 @@ func playGame(client pb.GameServiceClient, botStrategy bot.Strategy, strategyNam
 		if gameState.CurrentTurnPlayerId != playerID {
 			continue
 		}
+		// Cards played before a retry stay part of the turn.
+		if turn.Number != gameState.GetTurnNumber() {
+			turn = bot.Turn{Number: gameState.GetTurnNumber(), PlayerID: playerID}
+		}
 
-		// It's our turn, get the next move from the strategy
-		playReq, endTurnReq, err := botStrategy.GetNextMove(playerID, gameState)
+		// It's our turn, plan it with the strategy
+		moves, err := botStrategy.PlanTurn(ctx, &bot.View{PlayerID: playerID, State: gameState, History: history})
 		if err != nil {
 			log.Printf("strategy error: %v", err)
 			return
 		}
 
-		if playReq != nil {
-			// Play a card
-			_, err := client.PlayCard(context.Background(), playReq)
+		for _, move := range moves {
+			playRes, err := client.PlayCard(ctx, &pb.PlayCardRequest{GameId: gameID, PlayerId: playerID, Card: move.Card, PileId: move.Pile})
+			if status.Code(err) == codes.Aborted {
+				// Someone else moved first; re-read the game and plan again.
+				log.Printf("game changed before our move was saved, retrying: %v", err)
+				continue turns
+			}
 			if err != nil {
 				log.Printf("could not play card: %v", err)
 				return
 			}
-			log.Printf("Played card: %v on pile %s", playReq.Card.Value, playReq.PileId)
-		} else if endTurnReq != nil {
-			// End the turn
-			endTurnResp, err := client.EndTurn(context.Background(), endTurnReq)
-			if err != nil {
-				log.Printf("could not end turn: %v", err)
+			if !playRes.Success {
+				log.Printf("Could not play card %d on pile %s: %s. Stopping.", move.Card.GetValue(), move.Pile, playRes.Message)
 				return
 			}
-			if !endTurnResp.Success {
-				log.Printf("Could not end turn: %s. Stopping.", endTurnResp.Message)
-				break
-			}
-			log.Printf("Ended turn")
-		} else {
-			log.Println("Strategy returned no move, ending turn by default.")
-			_, err := client.EndTurn(context.Background(), &pb.EndTurnRequest{GameId: gameID, PlayerId: playerID})
-			if err != nil {
-				log.Printf("could not end turn: %v", err)
-				return
+			turn.Moves = append(turn.Moves, move)
+			log.Printf("Played card: %v on pile %s", move.Card.GetValue(), move.Pile)
+		}
+
+		// End the turn
+		endTurnResp, err := client.EndTurn(ctx, &pb.EndTurnRequest{GameId: gameID, PlayerId: playerID})
+		if status.Code(err) == codes.Aborted {
+			log.Printf("game changed before our turn ended, retrying: %v", err)
+			continue
+		}
+		if err != nil {
+			log.Printf("could not end turn: %v", err)
+			return
+		}
+		if !endTurnResp.Success {
+			// The last card played may have ended the game, which the loop reports.
+			if state, err := readState(ctx, client, gameID, playerID); err == nil && game.IsOver(state) {
+				history = append(history, turn)
+				continue
 			}
+			log.Printf("Could not end turn: %s. Stopping.", endTurnResp.Message)
+			break
 		}
+		log.Printf("Ended turn")
+		history = append(history, turn)
+	}
+}
+
+// readState returns the player's current view of the game.
+func readState(ctx context.Context, client pb.GameServiceClient, gameID, playerID string) (*pb.GameState, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	stream, err := client.StreamGameState(ctx, &pb.StreamGameStateRequest{GameId: gameID, PlayerId: playerID})
+	if err != nil {
+		return nil, err
 	}
+	return stream.Recv()
 }
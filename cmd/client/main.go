@@ -2,10 +2,8 @@ package main
 
 import (
 	"context"
-	"errors"
 	"flag"
 	"fmt"
-	"io"
 	"log"
 	"os"
 	"sort"
@@ -13,6 +11,7 @@ import (
 	"strings"
 	"time"
 
+	"the_game_card_game/pkg/game"
 	pb "the_game_card_game/proto"
 
 	tea "github.com/charmbracelet/bubbletea"
@@ -31,15 +30,13 @@ type model struct {
 	hand           []int32
 	mode           string // "select-card", "select-pile", "confirm-quit"
 	selectedCard   int32
-	selectedPile   int // 0: up1, 1: up2, 2: down1, 3: down2
+	selectedPile   int // Index into the game's pile IDs, in rule order.
 	status         string
 	err            error
 	gameOver       bool
 	gameOverMessage string
 }
 
-var pileIDs = []string{"up1", "up2", "down1", "down2"}
-
 type stateUpdateMsg *pb.GameState
 type statusUpdateMsg string
 type errMsg struct{ err error }
@@ -62,7 +59,7 @@ func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case stateUpdateMsg:
 		m.state = msg
 
-		if m.state.GameOver {
+		if game.IsOver(m.state) {
 			m.gameOver = true
 			m.gameOverMessage = m.state.Message
 			return m, nil
@@ -119,6 +116,14 @@ func handleKeyPress(m model, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 		return m, nil
 	}
 
+	// In the lobby, any seated player can start the game.
+	if m.state.GetStatus() == pb.GameStatus_WAITING {
+		if msg.String() == "s" {
+			return m, m.startGameCmd()
+		}
+		return m, nil
+	}
+
 	// Only allow actions if it's our turn
 	if m.state.CurrentTurnPlayerId != m.playerID {
 		return m, nil
@@ -134,19 +139,20 @@ func handleKeyPress(m model, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 				m.status = fmt.Sprintf("Selected card %d. Use ←/→ to pick a pile, Enter to play.", m.selectedCard)
 			}
 		} else if msg.String() == "e" {
-			if m.state.CardsPlayedThisTurn >= 2 {
+			if minPlays := game.MinPlaysToEndTurn(m.state); m.state.CardsPlayedThisTurn >= minPlays {
 				return m, m.endTurnCmd()
 			} else {
-				m.status = fmt.Sprintf("You must play at least 2 cards to end your turn (played %d).", m.state.CardsPlayedThisTurn)
+				m.status = fmt.Sprintf("You must play at least %d cards to end your turn (played %d).", minPlays, m.state.CardsPlayedThisTurn)
 			}
 		}
 
 	case "select-pile":
+		numPiles := len(m.state.Piles)
 		switch msg.String() {
 		case "left":
-			m.selectedPile = (m.selectedPile - 1 + 4) % 4
+			m.selectedPile = (m.selectedPile - 1 + numPiles) % numPiles
 		case "right":
-			m.selectedPile = (m.selectedPile + 1) % 4
+			m.selectedPile = (m.selectedPile + 1) % numPiles
 		case "enter":
 			return m, m.playCardCmd()
 		case "esc":
@@ -159,12 +165,13 @@ func handleKeyPress(m model, msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 }
 
 func (m *model) playCardCmd() tea.Cmd {
+	pileID := game.PileIDs(m.state)[m.selectedPile]
 	return func() tea.Msg {
 		req := &pb.PlayCardRequest{
 			GameId:   m.gameID,
 			PlayerId: m.playerID,
 			Card:     &pb.Card{Value: m.selectedCard},
-			PileId:   pileIDs[m.selectedPile],
+			PileId:   pileID,
 		}
 		res, err := m.client.PlayCard(context.Background(), req)
 		if err != nil {
@@ -173,7 +180,20 @@ func (m *model) playCardCmd() tea.Cmd {
 		if !res.Success {
 			return statusUpdateMsg(fmt.Sprintf("Invalid move: %s", res.Message))
 		}
-		return statusUpdateMsg(fmt.Sprintf("Played %d on %s.", m.selectedCard, pileIDs[m.selectedPile]))
+		return statusUpdateMsg(fmt.Sprintf("Played %d on %s.", m.selectedCard, pileID))
+	}
+}
+
+func (m *model) startGameCmd() tea.Cmd {
+	return func() tea.Msg {
+		res, err := m.client.StartGame(context.Background(), &pb.StartGameRequest{GameId: m.gameID, PlayerId: m.playerID})
+		if err != nil {
+			return statusUpdateMsg(fmt.Sprintf("Error starting game: %v", err))
+		}
+		if !res.Success {
+			return statusUpdateMsg(fmt.Sprintf("Could not start game: %s", res.Message))
+		}
+		return statusUpdateMsg("Game started.")
 	}
 }
 
@@ -210,7 +230,7 @@ func (m model) View() string {
 	// Piles View
 	var pileViews []string
 	isMyTurn := m.state.CurrentTurnPlayerId == m.playerID
-	for i, id := range pileIDs {
+	for i, id := range game.PileIDs(m.state) {
 		style := pileStyle
 		if isMyTurn && m.mode == "select-pile" && i == m.selectedPile {
 			style = selectedPileStyle
@@ -237,7 +257,7 @@ func (m model) View() string {
 	} else if isMyTurn {
 		if m.mode == "select-pile" {
 			help += " | 'esc': cancel selection"
-		} else if m.state.CardsPlayedThisTurn >= 2 {
+		} else if m.state.CardsPlayedThisTurn >= game.MinPlaysToEndTurn(m.state) {
 			help += " | 'e': end turn"
 		}
 	}
@@ -246,18 +266,21 @@ func (m model) View() string {
 }
 
 func getPileView(name string, pile *pb.Pile, style lipgloss.Style) string {
-	initial, displayName := "1", "UP ⬆"
-	if strings.HasPrefix(name, "down") {
-		initial, displayName = "100", "DOWN ⬇"
+	displayName := "UP ⬆"
+	if !pile.GetAscending() {
+		displayName = "DOWN ⬇"
 	}
-	topCard := initial
+	topCard := "-"
 	if pile != nil && len(pile.Cards) > 0 {
 		topCard = strconv.Itoa(int(pile.Cards[len(pile.Cards)-1].Value))
 	}
-	return style.Render(fmt.Sprintf("%s\n\n%s", displayName, topCard))
+	return style.Render(fmt.Sprintf("%s\n%s\n%s", displayName, name, topCard))
 }
 
 func (m *model) getTurnStatus() string {
+	if m.state.GetStatus() == pb.GameStatus_WAITING {
+		return fmt.Sprintf("Waiting for players (%d joined, game %s). Press 's' to start.", len(m.state.PlayerIds), m.gameID)
+	}
 	if m.state.CurrentTurnPlayerId == m.playerID {
 		return fmt.Sprintf("Your turn! Select a card (1-%d). %d card(s) played.", len(m.hand), m.state.CardsPlayedThisTurn)
 	}
@@ -287,28 +310,59 @@ func main() {
 		*gameID = res.GetGameState().GetGameId()
 		log.Printf("Game created: %s. Starting TUI...", *gameID)
 		time.Sleep(1 * time.Second)
+	} else {
+		res, err := client.JoinGame(context.Background(), &pb.JoinGameRequest{GameId: *gameID, PlayerId: *playerID})
+		if err != nil { log.Fatalf("Failed to join game: %v", err) }
+		if !res.GetSuccess() { log.Fatalf("Failed to join game %s", *gameID) }
 	}
 
 	m := newModel(client, *playerID, *gameID)
 	p := tea.NewProgram(m, tea.WithAltScreen())
-	go streamState(p, client, *gameID)
+	go streamState(p, client, *gameID, *playerID)
 
 	if _, err := p.Run(); err != nil { log.Fatalf("Error running TUI: %v", err) }
 }
 
-func streamState(p *tea.Program, client pb.GameServiceClient, gameID string) {
-	stream, err := client.StreamGameState(context.Background(), &pb.StreamGameStateRequest{GameId: gameID})
+func streamState(p *tea.Program, client pb.GameServiceClient, gameID, playerID string) {
+	// Reconnect after a dropped stream, resuming from the last state received.
+	req := &pb.StreamGameStateRequest{GameId: gameID, PlayerId: playerID}
+	failures := 0
+	for {
+		received, err := followState(p, client, req)
+		if received {
+			failures = 0
+		}
+		failures++
+		if failures > maxStreamRetries {
+			p.Send(errMsg{err})
+			return
+		}
+		time.Sleep(time.Duration(failures) * time.Second)
+	}
+}
+
+// maxStreamRetries is how many times in a row the client tries to reconnect a
+// dropped game stream before giving up.
+const maxStreamRetries = 5
+
+// followState forwards states from one stream to the TUI until it ends,
+// recording in req the version of the last one so that a new stream can resume
+// from it. It reports whether any state was received.
+func followState(p *tea.Program, client pb.GameServiceClient, req *pb.StreamGameStateRequest) (bool, error) {
+	stream, err := client.StreamGameState(context.Background(), req)
 	if err != nil {
-		p.Send(errMsg{err})
-		return
+		return false, err
 	}
+	received := false
 	for {
 		state, err := stream.Recv()
-		if errors.Is(err, io.EOF) { break }
 		if err != nil {
-			p.Send(errMsg{err})
-			return
+			// The server never ends a stream on its own, so EOF is a drop too.
+			return received, err
 		}
+		received = true
+		version := state.GetVersion()
+		req.ResumeAfter = &version
 		p.Send(stateUpdateMsg(state))
 	}
-} 
\ No newline at end of file
+}
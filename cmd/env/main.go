@@ -0,0 +1,58 @@
+// Command env serves a reinforcement learning environment over its standard
+// input and output, one JSON object per line, so that agents written in other
+// languages train against the rules the server enforces. See env.Serve for
+// the protocol.
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"the_game_card_game/pkg/bot"
+	"the_game_card_game/pkg/env"
+)
+
+var (
+	players  = flag.Int("players", 1, "The number of players at the table; the agent is p1")
+	opponent = flag.String("opponent", "smart", "Strategy spec of the other players")
+
+	cardPlayed     = flag.Float64("reward_card_played", env.DefaultReward().CardPlayed, "Reward for every card played at the table")
+	backJump       = flag.Float64("reward_back_jump", env.DefaultReward().BackJump, "Reward for every back-jump the agent plays")
+	win            = flag.Float64("reward_win", env.DefaultReward().Win, "Reward for winning")
+	loss           = flag.Float64("reward_loss", env.DefaultReward().Loss, "Reward for losing")
+	cardsRemaining = flag.Float64("reward_cards_remaining", env.DefaultReward().CardsRemaining, "Reward for every card left when the game ends")
+)
+
+func main() {
+	flag.Parse()
+
+	spec, err := bot.DefaultRegistry.Parse(*opponent)
+	if err != nil {
+		log.Fatalf("Invalid opponent: %v", err)
+	}
+	e, err := env.New(env.Config{
+		Players:  *players,
+		Opponent: spec.New,
+		Reward: env.Reward{
+			CardPlayed:     *cardPlayed,
+			BackJump:       *backJump,
+			Win:            *win,
+			Loss:           *loss,
+			CardsRemaining: *cardsRemaining,
+		},
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Standard output carries the protocol, unbuffered so that every reply
+	// reaches the client at once; logs go to standard error.
+	err = env.Serve(e, os.Stdin, os.Stdout)
+	if closeErr := e.Close(); closeErr != nil {
+		log.Printf("Could not stop the opponents: %v", closeErr)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
@@ -0,0 +1,179 @@
+// Command regress compares two strategies on the same deals of a seed corpus,
+// deal by deal, and exits with status 1 if the candidate is significantly
+// worse than the base by more than the thresholds allow, or fails a deal that
+// the base plays to the end.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	"the_game_card_game/pkg/bot"
+	"the_game_card_game/pkg/sim"
+	pb "the_game_card_game/proto"
+)
+
+var (
+	corpusPath  = flag.String("corpus", "corpus/seeds-v1.json", "The seed corpus to play")
+	candidate   = flag.String("candidate", "", "Strategy spec of the candidate")
+	base        = flag.String("base", "smart", "Strategy spec of the base to compare against")
+	baseResults = flag.String("base_results", "", "Results recorded with -record to compare against, in place of -base; use this to compare two versions of the code")
+	record      = flag.String("record", "", "File to record the candidate's results in, for a later -base_results")
+	players     = flag.Int("players", 1, "The number of players at the table, all playing the same strategy")
+	workers     = flag.Int("workers", 0, "The number of games played in parallel (0 means one per CPU)")
+	jsonOut     = flag.String("json", "", "File to write the comparison to as JSON")
+
+	maxWinRateDrop   = flag.Float64("max_win_rate_drop", 0.01, "Largest fall in win rate, as a fraction, that is not a regression")
+	maxCardsIncrease = flag.Float64("max_cards_increase", 0.5, "Largest rise in mean cards remaining that is not a regression")
+	alpha            = flag.Float64("alpha", 0.05, "A difference is only a regression if its p-value is below this")
+)
+
+// recording is the results of one strategy on a corpus, as written by -record.
+type recording struct {
+	Strategy      string         `json:"strategy"`
+	CorpusVersion int            `json:"corpus_version"`
+	Players       int            `json:"players"`
+	Games         []recordedGame `json:"games"`
+}
+
+type recordedGame struct {
+	Seed           int64  `json:"seed"`
+	Status         string `json:"status"`
+	CardsRemaining int    `json:"cards_remaining"`
+	Error          string `json:"error,omitempty"`
+}
+
+func main() {
+	flag.Parse()
+	if *candidate == "" {
+		log.Fatal("-candidate is required")
+	}
+	corpus, err := sim.LoadCorpus(*corpusPath)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	candidateName, candidateResults, err := play(ctx, corpus, *candidate)
+	if err != nil {
+		log.Fatalf("candidate: %v", err)
+	}
+	if *record != "" {
+		if err := save(*record, candidateName, corpus, candidateResults); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var baseName string
+	var baseResultsList []sim.Result
+	if *baseResults != "" {
+		baseName, baseResultsList, err = load(*baseResults, corpus)
+	} else {
+		baseName, baseResultsList, err = play(ctx, corpus, *base)
+	}
+	if err != nil {
+		log.Fatalf("base: %v", err)
+	}
+
+	paired, err := sim.ComparePaired(baseResultsList, candidateResults)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *jsonOut != "" {
+		data, err := json.MarshalIndent(paired, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(*jsonOut, append(data, '\n'), 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	fmt.Printf("corpus:          v%d, %d deals, %d players\n", corpus.Version, len(corpus.Seeds), *players)
+	fmt.Printf("base:            %s\n", baseName)
+	fmt.Printf("candidate:       %s\n", candidateName)
+	if paired.Failed > 0 {
+		fmt.Printf("failed:          %d deals left out, %d failed only by the candidate\n", paired.Failed, paired.CandidateFailed)
+	}
+	fmt.Printf("win rate:        %.2f%% -> %.2f%% (%+.2f points, p=%.3g; won only by base %d, only by candidate %d)\n",
+		100*rate(paired.BaseWon, paired.Games), 100*rate(paired.CandidateWon, paired.Games), 100*paired.WinRateDiff,
+		paired.WinRateP, paired.BaseOnly, paired.CandidateOnly)
+	fmt.Printf("cards remaining: %+.2f per deal (95%% CI %+.2f to %+.2f, p=%.3g; better on %d, worse on %d, tied on %d)\n",
+		paired.CardsDiff, paired.CardsLow, paired.CardsHigh, paired.CardsP, paired.Better, paired.Worse, paired.Tied)
+
+	if regressions := paired.Regressions(*maxWinRateDrop, *maxCardsIncrease, *alpha); len(regressions) > 0 {
+		fmt.Printf("REGRESSION:      %s\n", strings.Join(regressions, "; "))
+		os.Exit(1)
+	}
+	fmt.Printf("no regression\n")
+}
+
+func rate(won, games int) float64 {
+	if games == 0 {
+		return 0
+	}
+	return float64(won) / float64(games)
+}
+
+// play plays every deal of corpus with spec in every seat.
+func play(ctx context.Context, corpus *sim.Corpus, spec string) (string, []sim.Result, error) {
+	seats, err := sim.SeatsFor(bot.DefaultRegistry, []string{spec})
+	if err != nil {
+		return "", nil, err
+	}
+	results, err := sim.Run(ctx, sim.Config{Players: *players, Seats: seats, Seeds: corpus.Seeds, Workers: *workers})
+	if err != nil {
+		return "", nil, err
+	}
+	return seats[0].Name, results, nil
+}
+
+func save(path, name string, corpus *sim.Corpus, results []sim.Result) error {
+	r := recording{Strategy: name, CorpusVersion: corpus.Version, Players: *players}
+	for _, result := range results {
+		g := recordedGame{Seed: result.Seed, Status: result.Status.String(), CardsRemaining: result.CardsRemaining}
+		if result.Err != nil {
+			g.Error = result.Err.Error()
+		}
+		r.Games = append(r.Games, g)
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// load reads results recorded with -record, which must have been played on
+// corpus at the same table size.
+func load(path string, corpus *sim.Corpus) (string, []sim.Result, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", nil, err
+	}
+	var r recording
+	if err := json.Unmarshal(data, &r); err != nil {
+		return "", nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if r.CorpusVersion != corpus.Version || r.Players != *players {
+		return "", nil, fmt.Errorf("%s was recorded on corpus v%d with %d players, not v%d with %d",
+			path, r.CorpusVersion, r.Players, corpus.Version, *players)
+	}
+	results := make([]sim.Result, len(r.Games))
+	for i, g := range r.Games {
+		results[i] = sim.Result{Game: i, Seed: g.Seed, Status: pb.GameStatus(pb.GameStatus_value[g.Status]), CardsRemaining: g.CardsRemaining}
+		if g.Error != "" {
+			results[i].Err = errors.New(g.Error)
+		}
+	}
+	return r.Strategy + " (recorded)", results, nil
+}
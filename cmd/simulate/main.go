@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"the_game_card_game/pkg/bot"
+	"the_game_card_game/pkg/sim"
+)
+
+var (
+	strategies = flag.String("strategies", "smart", "Strategy specs in seating order, separated by semicolons and repeated round the table (e.g., smart;phased:early=60,mid=25)")
+	players    = flag.Int("players", 1, "The number of players at the table")
+	numGames   = flag.Int("num_games", 1000, "The number of games to simulate")
+	seed       = flag.Int64("seed", 1, "Game i is dealt from seed+i so runs can be replayed")
+	workers    = flag.Int("workers", 0, "The number of games played in parallel (0 means one per CPU)")
+	verbose    = flag.Bool("v", false, "Log every game that could not be played to the end")
+	list       = flag.Bool("list_strategies", false, "List the available strategies and their parameters, then exit")
+)
+
+func main() {
+	flag.Parse()
+
+	if *list {
+		bot.DefaultRegistry.Describe(os.Stdout)
+		return
+	}
+
+	seats, err := sim.SeatsFor(bot.DefaultRegistry, strings.Split(*strategies, ";"))
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	// Interrupting a long run still reports the games played so far.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	start := time.Now()
+	results, err := sim.Run(ctx, sim.Config{
+		Players: *players,
+		Seats:   seats,
+		Games:   *numGames,
+		Seed:    *seed,
+		Workers: *workers,
+	})
+	if err != nil && results == nil {
+		log.Fatalf("simulation failed: %v", err)
+	}
+	if err != nil {
+		log.Printf("simulation interrupted: %v", err)
+	}
+
+	if *verbose {
+		for _, r := range results {
+			if r.Err != nil {
+				log.Printf("game %d (seed %d): %v", r.Game, r.Seed, r.Err)
+			}
+		}
+	}
+
+	names := make([]string, len(seats))
+	for i, seat := range seats {
+		names[i] = seat.Name
+	}
+	fmt.Printf("strategies:      %s (%d players)\n", strings.Join(names, "; "), *players)
+	sim.Summarize(results).Print(os.Stdout)
+	fmt.Printf("elapsed:         %s\n", time.Since(start).Round(time.Millisecond))
+}
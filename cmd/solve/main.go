@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"runtime"
+	"sync"
+	"time"
+
+	"the_game_card_game/pkg/game"
+	"the_game_card_game/pkg/solver"
+)
+
+var (
+	numGames = flag.Int("num_games", 100, "The number of deals to solve")
+	seed     = flag.Int64("seed", 1, "Deal i is dealt from seed+i, as in the simulator")
+	maxNodes = flag.Int("max_nodes", solver.DefaultMaxNodes, "Positions searched per deal before giving up")
+	workers  = flag.Int("workers", 0, "The number of deals solved in parallel (0 means one per CPU)")
+	verbose  = flag.Bool("v", false, "Print the outcome and difficulty of every deal")
+)
+
+type outcome struct {
+	seed   int64
+	result *solver.Result
+	err    error
+}
+
+func main() {
+	flag.Parse()
+
+	// Interrupting a long run still reports the deals solved so far.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	n := *workers
+	if n <= 0 {
+		n = runtime.NumCPU()
+	}
+	start := time.Now()
+	outcomes := make([]outcome, *numGames)
+	next := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < n; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range next {
+				s := *seed + int64(i)
+				state, err := game.StartGame(game.NewSeededGame(fmt.Sprintf("solve-%d", i), "p1", s))
+				if err != nil {
+					outcomes[i] = outcome{seed: s, err: err}
+					continue
+				}
+				r, err := solver.Solve(ctx, state, solver.Options{MaxNodes: *maxNodes})
+				outcomes[i] = outcome{seed: s, result: r, err: err}
+			}
+		}()
+	}
+	solved := 0
+	for ; solved < *numGames && ctx.Err() == nil; solved++ {
+		next <- solved
+	}
+	close(next)
+	wg.Wait()
+	if ctx.Err() != nil {
+		log.Printf("interrupted after %d deals", solved)
+	}
+
+	counts := make(map[solver.Difficulty]int)
+	total, winnable, cards := 0, 0, 0
+	for _, o := range outcomes[:solved] {
+		if o.err != nil {
+			log.Printf("seed %d: %v", o.seed, o.err)
+			continue
+		}
+		d := o.result.Difficulty()
+		counts[d]++
+		total++
+		cards += o.result.CardsRemaining
+		if o.result.Winnable {
+			winnable++
+		}
+		if *verbose {
+			fmt.Printf("seed %d: %s, %d cards left, %d positions\n", o.seed, d, o.result.CardsRemaining, o.result.Nodes)
+		}
+	}
+	if total == 0 {
+		return
+	}
+
+	fmt.Printf("deals:           %d\n", total)
+	fmt.Printf("winnable:        %d (%.2f%%, at least)\n", winnable, 100*float64(winnable)/float64(total))
+	fmt.Printf("fewest cards:    mean %.2f\n", float64(cards)/float64(total))
+	for _, d := range []solver.Difficulty{solver.Easy, solver.Medium, solver.Hard, solver.Unwinnable, solver.Unknown} {
+		fmt.Printf("%-16s %d\n", d.String()+":", counts[d])
+	}
+	fmt.Printf("elapsed:         %s\n", time.Since(start).Round(time.Millisecond))
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+
+	"the_game_card_game/pkg/bot"
+	"the_game_card_game/pkg/sim"
+)
+
+var (
+	strategies = flag.String("strategies", "random;smart;counting", "Strategy specs taking part, separated by semicolons")
+	players    = flag.Int("players", 3, "The number of players at every table")
+	numGames   = flag.Int("num_games", 200, "Deals each lineup plays, once per rotation of its seats; every lineup plays the same deals")
+	seed       = flag.Int64("seed", 1, "Game i is dealt from seed+i, as in the simulator")
+	workers    = flag.Int("workers", 0, "The number of games played in parallel (0 means one per CPU)")
+	baseline   = flag.String("baseline", "", "Strategy spec that stands in for an absent player in seat values; defaults to the first of -strategies")
+	jsonOut    = flag.String("json", "", "File to write the results to as JSON")
+	markdown   = flag.String("markdown", "-", "File to write the results to as Markdown tables; - for standard output")
+)
+
+func main() {
+	flag.Parse()
+
+	specs := strings.Split(*strategies, ";")
+	seats, err := sim.SeatsFor(bot.DefaultRegistry, specs)
+	if err != nil {
+		log.Fatal(err)
+	}
+	base := 0
+	if *baseline != "" {
+		// Compare canonical names, so that parameters left at their defaults
+		// do not matter.
+		b, err := sim.SeatsFor(bot.DefaultRegistry, []string{*baseline})
+		if err != nil {
+			log.Fatal(err)
+		}
+		base = -1
+		for i, seat := range seats {
+			if seat.Name == b[0].Name {
+				base = i
+			}
+		}
+		if base < 0 {
+			// The baseline must sit at tables to be compared against.
+			seats = append(seats, b[0])
+			base = len(seats) - 1
+		}
+	}
+
+	// Report strategies as they were given rather than with every parameter
+	// spelt out.
+	for i, spec := range specs {
+		seats[i].Name = strings.TrimSpace(spec)
+	}
+	if base == len(specs) {
+		seats[base].Name = strings.TrimSpace(*baseline)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	result, err := sim.RunTournament(ctx, sim.TournamentConfig{
+		Seats:    seats,
+		Players:  *players,
+		Games:    *numGames,
+		Seed:     *seed,
+		Workers:  *workers,
+		Baseline: base,
+	})
+	if err != nil {
+		log.Fatalf("tournament failed: %v", err)
+	}
+
+	if *jsonOut != "" {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			log.Fatal(err)
+		}
+		if err := os.WriteFile(*jsonOut, append(data, '\n'), 0o644); err != nil {
+			log.Fatal(err)
+		}
+	}
+	switch *markdown {
+	case "":
+	case "-":
+		result.WriteMarkdown(os.Stdout)
+	default:
+		f, err := os.Create(*markdown)
+		if err != nil {
+			log.Fatal(err)
+		}
+		result.WriteMarkdown(f)
+		if err := f.Close(); err != nil {
+			log.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"math/rand"
+	"os"
+	"os/signal"
+	"sort"
+	"time"
+
+	"the_game_card_game/pkg/bot"
+	"the_game_card_game/pkg/sim"
+)
+
+var (
+	population  = flag.Int("population", 16, "Candidates in each generation")
+	generations = flag.Int("generations", 10, "Generations to evolve")
+	numGames    = flag.Int("num_games", 200, "Games each candidate is measured on; every candidate plays the same deals")
+	seed        = flag.Int64("seed", 1, "Game i is dealt from seed+i, as in the simulator")
+	players     = flag.Int("players", 1, "The number of players at the table, all playing the candidate")
+	elite       = flag.Int("elite", 2, "Best candidates carried unchanged into the next generation")
+	mutation    = flag.Float64("mutation", 0.3, "Standard deviation of a mutation, relative to the size of the weight")
+	rngSeed     = flag.Int64("rng_seed", 0, "Seed for the search itself; 0 seeds from the clock")
+	workers     = flag.Int("workers", 0, "The number of games played in parallel (0 means one per CPU)")
+	out         = flag.String("out", "tuned.json", "The parameter file to write, loaded by the weighted strategy's file parameter (e.g., cmd/bot -strategy=weighted:file=tuned.json)")
+)
+
+// tournamentSize is how many candidates compete to be each parent.
+const tournamentSize = 3
+
+type candidate struct {
+	weights bot.Weights
+	fitness sim.Summary
+}
+
+// better reports whether a played fewer cards short of a win than b, breaking
+// ties by wins.
+func better(a, b sim.Summary) bool {
+	if a.MeanCardsRemaining != b.MeanCardsRemaining {
+		return a.MeanCardsRemaining < b.MeanCardsRemaining
+	}
+	return a.WinRate > b.WinRate
+}
+
+func main() {
+	flag.Parse()
+	if *population < 2 || *elite < 0 || *elite >= *population {
+		log.Fatalf("need a population of at least 2 and fewer elite than that")
+	}
+
+	// Interrupting a long run still writes the best weights found so far.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	s := *rngSeed
+	if s == 0 {
+		s = time.Now().UnixNano()
+	}
+	rng := rand.New(rand.NewSource(s))
+	start := time.Now()
+
+	// The first generation is the hand-tuned weights and variations on them.
+	pop := make([]candidate, *population)
+	pop[0].weights = bot.DefaultWeights()
+	for i := 1; i < len(pop); i++ {
+		pop[i].weights = mutate(rng, bot.DefaultWeights(), 1)
+	}
+
+	var best candidate
+	for gen := 0; gen < *generations; gen++ {
+		for i := range pop {
+			// Elite carried over from the last generation are already measured
+			// on the same deals.
+			if gen > 0 && i < *elite {
+				continue
+			}
+			summary, err := evaluate(ctx, pop[i].weights)
+			if err != nil {
+				if ctx.Err() != nil {
+					log.Printf("interrupted in generation %d", gen)
+					save(best)
+					return
+				}
+				log.Fatalf("evaluate: %v", err)
+			}
+			pop[i].fitness = summary
+		}
+		sort.SliceStable(pop, func(i, j int) bool { return better(pop[i].fitness, pop[j].fitness) })
+		best = pop[0]
+		log.Printf("generation %d: best %.2f cards remaining (%.2f%% won), median candidate %.2f, %v",
+			gen, best.fitness.MeanCardsRemaining, 100*best.fitness.WinRate,
+			pop[len(pop)/2].fitness.MeanCardsRemaining, formatWeights(best.weights))
+
+		if gen == *generations-1 {
+			break
+		}
+		next := make([]candidate, 0, len(pop))
+		next = append(next, pop[:*elite]...)
+		for len(next) < len(pop) {
+			a, b := selectParent(rng, pop), selectParent(rng, pop)
+			next = append(next, candidate{weights: mutate(rng, crossover(rng, a.weights, b.weights), *mutation)})
+		}
+		pop = next
+	}
+
+	save(best)
+	fmt.Printf("elapsed:         %s\n", time.Since(start).Round(time.Millisecond))
+}
+
+// evaluate plays the deals with every player using weights w.
+func evaluate(ctx context.Context, w bot.Weights) (sim.Summary, error) {
+	results, err := sim.Run(ctx, sim.Config{
+		Players: *players,
+		Seats: []sim.Seat{{
+			Name: "weighted",
+			New: func() (bot.TurnStrategy, error) {
+				return bot.AdaptStrategy(bot.NewWeightedStrategy(w)), nil
+			},
+		}},
+		Games:   *numGames,
+		Seed:    *seed,
+		Workers: *workers,
+	})
+	if err != nil {
+		return sim.Summary{}, err
+	}
+	summary := sim.Summarize(results)
+	if summary.Failed > 0 {
+		return summary, fmt.Errorf("%d games failed", summary.Failed)
+	}
+	return summary, nil
+}
+
+// selectParent returns the best of a few candidates picked at random.
+func selectParent(rng *rand.Rand, pop []candidate) candidate {
+	best := pop[rng.Intn(len(pop))]
+	for i := 1; i < tournamentSize; i++ {
+		if c := pop[rng.Intn(len(pop))]; better(c.fitness, best.fitness) {
+			best = c
+		}
+	}
+	return best
+}
+
+// crossover blends each weight of the parents at a random point between them.
+func crossover(rng *rand.Rand, a, b bot.Weights) bot.Weights {
+	va, vb := a.Vector(), b.Vector()
+	child := make([]float64, len(va))
+	for i := range child {
+		t := rng.Float64()
+		child[i] = va[i] + t*(vb[i]-va[i])
+	}
+	return bot.WeightsFromVector(child)
+}
+
+// mutate adds gaussian noise to each weight, scaled by the weight so that
+// large and small weights change in proportion. Zero weights move by up to
+// about scale, so that unused features can be discovered.
+func mutate(rng *rand.Rand, w bot.Weights, scale float64) bot.Weights {
+	v := w.Vector()
+	for i := range v {
+		size := v[i]
+		if size < 0 {
+			size = -size
+		}
+		if size < 1 {
+			size = 1
+		}
+		v[i] += rng.NormFloat64() * scale * size
+	}
+	return bot.WeightsFromVector(v)
+}
+
+func formatWeights(w bot.Weights) string {
+	s := ""
+	for i, v := range w.Vector() {
+		if i > 0 {
+			s += ","
+		}
+		s += fmt.Sprintf("%s=%.3g", bot.WeightNames[i], v)
+	}
+	return s
+}
+
+func save(best candidate) {
+	if best.fitness.Games == 0 {
+		return
+	}
+	f := &bot.WeightsFile{
+		Weights:            best.weights,
+		Players:            *players,
+		Games:              *numGames,
+		Seed:               *seed,
+		MeanCardsRemaining: best.fitness.MeanCardsRemaining,
+		WinRate:            best.fitness.WinRate,
+	}
+	if err := f.Save(*out); err != nil {
+		log.Fatalf("write %s: %v", *out, err)
+	}
+	fmt.Printf("best:            %.2f cards remaining (%.2f%% won) over %d games\n",
+		f.MeanCardsRemaining, 100*f.WinRate, f.Games)
+	fmt.Printf("written to:      %s\n", *out)
+}
@@ -3,7 +3,15 @@ CREATE TABLE IF NOT EXISTS games (
     game_id VARCHAR(255) PRIMARY KEY,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
     is_active BOOLEAN DEFAULT TRUE,
-    winner VARCHAR(255)
+    -- Kept for the games of earlier versions. The game is cooperative, so
+    -- there is no single winner: the outcome is in status, and new games
+    -- leave winner NULL.
+    winner VARCHAR(255),
+    -- Filled in when the game ends.
+    status VARCHAR(20),
+    cards_remaining INT,
+    turns INT,
+    finished_at TIMESTAMP WITH TIME ZONE
 );
 
 -- Create a table to store individual moves within a game
@@ -11,14 +19,46 @@ CREATE TABLE IF NOT EXISTS moves (
     move_id SERIAL PRIMARY KEY,
     game_id VARCHAR(255) REFERENCES games(game_id),
     player_id VARCHAR(255) NOT NULL,
-    card_played INT NOT NULL,
-    pile_id VARCHAR(50) NOT NULL,
+    kind VARCHAR(20) NOT NULL DEFAULT 'play', -- 'play', 'end_turn', 'leave' or 'game_over'
+    game_version BIGINT NOT NULL DEFAULT 0,   -- Game version after the move; orders a game's moves
+    turn_number INT NOT NULL DEFAULT 0,
+    card_played INT,                          -- NULL unless kind = 'play'
+    pile_id VARCHAR(50),                      -- NULL unless kind = 'play'
+    piles_before JSONB,                       -- Top card of every pile, e.g. {"up1": 12, "down1": 88}
+    piles_after JSONB,
     move_timestamp TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
 );
 
+-- Bring databases created from an earlier version of this script up to date.
+-- Every statement is safe to repeat and none removes data, so the script can
+-- be run against an existing database to migrate it.
+ALTER TABLE games ADD COLUMN IF NOT EXISTS status VARCHAR(20);
+ALTER TABLE games ADD COLUMN IF NOT EXISTS cards_remaining INT;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS turns INT;
+ALTER TABLE games ADD COLUMN IF NOT EXISTS finished_at TIMESTAMP WITH TIME ZONE;
+
+ALTER TABLE moves ADD COLUMN IF NOT EXISTS kind VARCHAR(20) NOT NULL DEFAULT 'play';
+ALTER TABLE moves ADD COLUMN IF NOT EXISTS game_version BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE moves ADD COLUMN IF NOT EXISTS turn_number INT NOT NULL DEFAULT 0;
+ALTER TABLE moves ADD COLUMN IF NOT EXISTS piles_before JSONB;
+ALTER TABLE moves ADD COLUMN IF NOT EXISTS piles_after JSONB;
+ALTER TABLE moves ALTER COLUMN card_played DROP NOT NULL;
+ALTER TABLE moves ALTER COLUMN pile_id DROP NOT NULL;
+
+CREATE INDEX IF NOT EXISTS moves_game_id_idx ON moves (game_id, game_version);
+
 -- Create a table for players (optional, but good for tracking stats)
 CREATE TABLE IF NOT EXISTS players (
     player_id VARCHAR(255) PRIMARY KEY,
     name VARCHAR(255) NOT NULL,
     created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
-); 
\ No newline at end of file
+);
+
+-- Create a table recording who sat at which game
+CREATE TABLE IF NOT EXISTS game_players (
+    game_id VARCHAR(255) REFERENCES games(game_id),
+    player_id VARCHAR(255) REFERENCES players(player_id),
+    seat INT NOT NULL,
+    joined_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (game_id, player_id)
+);
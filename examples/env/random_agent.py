@@ -0,0 +1,59 @@
+#!/usr/bin/env python3
+"""Plays the environment served by cmd/env with random legal actions.
+
+    go build -o env ./cmd/env
+    python3 examples/env/random_agent.py ./env
+
+A learning agent would choose its actions from the observation instead; the
+mask rules out the illegal ones.
+"""
+
+import json
+import random
+import subprocess
+import sys
+
+
+class Env:
+    def __init__(self, command):
+        self.proc = subprocess.Popen(command, stdin=subprocess.PIPE, stdout=subprocess.PIPE, text=True)
+
+    def call(self, request):
+        self.proc.stdin.write(json.dumps(request) + "\n")
+        self.proc.stdin.flush()
+        reply = json.loads(self.proc.stdout.readline())
+        if reply["type"] == "error":
+            raise RuntimeError(reply["error"])
+        return reply
+
+    def spec(self):
+        return self.call({"type": "spec"})
+
+    def reset(self, seed):
+        return self.call({"type": "reset", "seed": seed})
+
+    def step(self, action):
+        return self.call({"type": "step", "action": action})
+
+    def close(self):
+        self.proc.stdin.close()
+        self.proc.wait()
+
+
+def main():
+    env = Env(sys.argv[1:] or ["go", "run", "./cmd/env"])
+    spec = env.spec()
+    print("observations of", spec["observation_size"], "values,", spec["action_size"], "actions")
+    episodes, total = 20, 0.0
+    for seed in range(1, episodes + 1):
+        step = env.reset(seed)
+        while not step["done"]:
+            legal = [a for a, ok in enumerate(step["mask"]) if ok]
+            step = env.step(random.choice(legal))
+            total += step["reward"]
+    env.close()
+    print("mean reward over", episodes, "episodes:", total / episodes)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,49 @@
+#!/usr/bin/env python3
+"""A bot for the external strategy protocol, written in Python.
+
+It plays the card that moves its pile the least, preferring back-jumps, and
+ends the turn as soon as it may. Run it with:
+
+    go run ./cmd/simulate -strategies="external:cmd=python3 examples/external/minimal_jump.py"
+
+See ExternalStrategy in pkg/bot for the protocol.
+"""
+
+import json
+import sys
+
+PROTOCOL_VERSION = 1
+
+
+def top(pile):
+    return pile["cards"][-1]["value"]
+
+
+def jump(state, move):
+    pile = state["piles"][move["pile"]]
+    distance = move["card"] - top(pile)
+    return distance if pile.get("ascending") else -distance
+
+
+def choose(request):
+    if request.get("can_end_turn"):
+        return {"type": "end_turn"}
+    state = request["state"]
+    best = min(request["legal_moves"], key=lambda move: jump(state, move))
+    return {"type": "play", "card": best["card"], "pile": best["pile"]}
+
+
+def main():
+    for line in sys.stdin:
+        request = json.loads(line)
+        if request["type"] == "hello":
+            reply = {"type": "hello", "version": PROTOCOL_VERSION, "name": "minimal-jump.py"}
+        elif request["type"] == "move":
+            reply = choose(request)
+        else:
+            continue
+        print(json.dumps(reply), flush=True)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,351 @@
+// Package bottest checks that a strategy keeps to the rules: that it only
+// plans legal moves, ends its turns only when it may, finishes its turns and
+// games, plays the same way twice given the same seed, and does not panic on
+// odd states. Every strategy in bot.DefaultRegistry is held to it; authors of
+// new strategies can run it from their own tests with Test.
+package bottest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"testing"
+	"time"
+
+	"the_game_card_game/pkg/bot"
+	"the_game_card_game/pkg/game"
+	"the_game_card_game/pkg/sim"
+	pb "the_game_card_game/proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Factory builds a fresh strategy. Strategies that make random choices should
+// be built with a fixed seed, so that the suite can check that they are
+// deterministic. Strategies not built by a registry should be wrapped with
+// bot.Checked, as registered ones are.
+type Factory func() (bot.TurnStrategy, error)
+
+// Config says how hard the suite tests a strategy.
+type Config struct {
+	// States is the number of random states the strategy plans a turn in.
+	States int
+	// Games is the number of whole games the strategy plays.
+	Games int
+	// Seed seeds the random states and the games' deals.
+	Seed int64
+	// TurnTimeout bounds the planning of each turn.
+	TurnTimeout time.Duration
+}
+
+// DefaultConfig returns the configuration every registered strategy is held
+// to.
+func DefaultConfig() Config {
+	return Config{States: 100, Games: 3, Seed: 1, TurnTimeout: 10 * time.Second}
+}
+
+// Test runs the suite against strategies built by newStrategy, as subtests of
+// t.
+func Test(t *testing.T, newStrategy Factory, cfg Config) {
+	t.Run("LegalTurns", func(t *testing.T) {
+		s := build(t, newStrategy)
+		for i, state := range RandomStates(cfg.Seed, cfg.States) {
+			moves, err := plan(s, state, state.CurrentTurnPlayerId, cfg.TurnTimeout)
+			if err != nil {
+				t.Fatalf("state %d: %v", i, err)
+			}
+			if err := CheckTurn(state, state.CurrentTurnPlayerId, moves); err != nil {
+				t.Fatalf("state %d: %v", i, err)
+			}
+		}
+	})
+
+	t.Run("WholeGames", func(t *testing.T) {
+		for players := 1; players <= 3; players++ {
+			strategies := make([]bot.TurnStrategy, players)
+			for i := range strategies {
+				strategies[i] = build(t, newStrategy)
+			}
+			for g := 0; g < cfg.Games; g++ {
+				// Every turn plays a card, so a game has no more turns than the
+				// deck has cards.
+				ctx, cancel := context.WithTimeout(context.Background(), time.Duration(game.DefaultRuleSet().MaxCard)*cfg.TurnTimeout)
+				r := sim.PlayGame(ctx, "bottest", cfg.Seed+int64(g), nil, strategies)
+				cancel()
+				if r.Err != nil {
+					t.Fatalf("%d players, seed %d: %v", players, r.Seed, r.Err)
+				}
+				if r.Status != pb.GameStatus_WON && r.Status != pb.GameStatus_LOST {
+					t.Fatalf("%d players, seed %d: game ended %s", players, r.Seed, r.Status)
+				}
+			}
+		}
+	})
+
+	t.Run("Deterministic", func(t *testing.T) {
+		a, b := build(t, newStrategy), build(t, newStrategy)
+		for i, state := range RandomStates(cfg.Seed+1, cfg.States/4+1) {
+			first, err := plan(a, state, state.CurrentTurnPlayerId, cfg.TurnTimeout)
+			if err != nil {
+				t.Fatalf("state %d: %v", i, err)
+			}
+			second, err := plan(b, state, state.CurrentTurnPlayerId, cfg.TurnTimeout)
+			if err != nil {
+				t.Fatalf("state %d: %v", i, err)
+			}
+			if describe(first) != describe(second) {
+				t.Fatalf("state %d: planned %s, then %s", i, describe(first), describe(second))
+			}
+		}
+	})
+
+	// The edge cases are put to the strategy itself as well as to it checked,
+	// so that they test the strategy rather than bot.Checked.
+	t.Run("EdgeCases", func(t *testing.T) {
+		checked := build(t, newStrategy)
+		for _, c := range EdgeCases(cfg.Seed) {
+			checkEdgeCase(t, checked, c, true, cfg.TurnTimeout)
+			checkEdgeCase(t, bot.Unchecked(checked), c, false, cfg.TurnTimeout)
+		}
+	})
+}
+
+// checkEdgeCase asks s to plan the turn of an edge case, and reports a
+// failure to cope with it. Any strategy must cope without panicking, hanging
+// or changing its view, and must plan a legal turn in a playable state. Only a
+// checked strategy is held to planning nothing in a state that cannot arise in
+// play: bot.Checked refuses those before the strategy itself sees them.
+func checkEdgeCase(t *testing.T, s bot.TurnStrategy, c EdgeCase, checked bool, timeout time.Duration) {
+	t.Helper()
+	name := c.Name
+	if !checked {
+		name += " (unchecked)"
+	}
+	moves, err := plan(s, c.State, c.PlayerID, timeout)
+	var f *failure
+	if errors.As(err, &f) {
+		t.Errorf("%s: %v", name, err)
+		return
+	}
+	if err != nil {
+		// Refusing a state the strategy cannot play is fine.
+		return
+	}
+	if len(moves) > 0 && !c.Playable && checked {
+		t.Errorf("%s: planned %s", name, describe(moves))
+	}
+	if c.Playable {
+		if err := CheckTurn(c.State, c.PlayerID, moves); err != nil {
+			t.Errorf("%s: %v", name, err)
+		}
+	}
+}
+
+func build(t *testing.T, newStrategy Factory) bot.TurnStrategy {
+	t.Helper()
+	s, err := newStrategy()
+	if err != nil {
+		t.Fatalf("build strategy: %v", err)
+	}
+	if c, ok := s.(io.Closer); ok {
+		t.Cleanup(func() { c.Close() })
+	}
+	return s
+}
+
+// failure is an error plan reports for a strategy that misbehaved, rather
+// than one that refused to plan.
+type failure struct{ reason string }
+
+func (f *failure) Error() string { return f.reason }
+
+// plan asks s to plan the turn of playerID in its view of state. A panic, a
+// plan that takes too long or a change to the view is reported as a
+// *failure.
+func plan(s bot.TurnStrategy, state *pb.GameState, playerID string, timeout time.Duration) (moves []game.Move, err error) {
+	view := &bot.View{PlayerID: playerID, State: game.PlayerView(state, playerID)}
+	before := proto.Clone(view.State)
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	defer func() {
+		if r := recover(); r != nil {
+			moves, err = nil, &failure{fmt.Sprintf("panic: %v", r)}
+		}
+	}()
+	moves, err = s.PlanTurn(ctx, view)
+	if ctx.Err() != nil {
+		return nil, &failure{fmt.Sprintf("turn not planned within %s", timeout)}
+	}
+	if err == nil && !proto.Equal(before, view.State) {
+		return nil, &failure{"the strategy changed its view of the game"}
+	}
+	return moves, err
+}
+
+func describe(moves []game.Move) string {
+	s := "["
+	for i, m := range moves {
+		if i > 0 {
+			s += " "
+		}
+		s += fmt.Sprintf("%d/%s", m.Card.GetValue(), m.Pile)
+	}
+	return s + "]"
+}
+
+// CheckTurn reports whether moves are a legal turn for playerID in state: each
+// move is legal in turn, and once they are played the player may end the
+// turn. A player who cannot play may end the turn early, which loses the
+// game; one who can must play the cards the rules require first.
+func CheckTurn(state *pb.GameState, playerID string, moves []game.Move) error {
+	if state.CurrentTurnPlayerId != playerID && len(moves) > 0 {
+		return fmt.Errorf("%s planned %s out of turn", playerID, describe(moves))
+	}
+	for i, m := range moves {
+		if game.IsOver(state) {
+			return fmt.Errorf("planned %s after the game ended", describe(moves[i:]))
+		}
+		next, err := game.PlayCard(state, playerID, m.Card.GetValue(), m.Pile)
+		if err != nil {
+			return fmt.Errorf("move %d of %s: %w", i+1, describe(moves), err)
+		}
+		state = next
+	}
+	if game.IsOver(state) || state.CurrentTurnPlayerId != playerID {
+		return nil
+	}
+	if state.CardsPlayedThisTurn < game.MinPlaysToEndTurn(state) && len(game.GetPossibleMoves(playerID, state)) > 0 {
+		return fmt.Errorf("ended the turn after %s, %d of the %d cards required, with cards still playable",
+			describe(moves), state.CardsPlayedThisTurn, game.MinPlaysToEndTurn(state))
+	}
+	return nil
+}
+
+// ruleVariants are the rules random states are dealt with.
+var ruleVariants = []*game.RuleSet{
+	nil,
+	{MinCard: 2, MaxCard: 40, HandSize: 5, BackJump: proto.Int32(5)},
+	{MinCard: 1, MaxCard: 60, MinPlaysPerTurn: 3, MinPlaysDeckEmpty: 2, Piles: []*pb.PileRule{
+		{Id: "up", Ascending: true}, {Id: "down", Ascending: false}, {Id: "up2", Ascending: true},
+	}},
+}
+
+// RandomStates returns n states of games in progress, dealt with a variety of
+// rules and table sizes and played at random up to some point, sometimes part
+// way through a turn. The deck and every hand are in them; strategies should
+// be given the current player's view.
+func RandomStates(seed int64, n int) []*pb.GameState {
+	rng := rand.New(rand.NewSource(seed))
+	var states []*pb.GameState
+	for len(states) < n {
+		rules := ruleVariants[rng.Intn(len(ruleVariants))]
+		players := 1 + rng.Intn(int(game.ResolveRuleSet(rules).MaxPlayers))
+		state, err := deal(rng.Int63(), rules, players)
+		if err != nil {
+			panic(err)
+		}
+		stopAt := rng.Intn(120)
+		for plays := 0; plays < stopAt && !game.IsOver(state); plays++ {
+			state = randomStep(rng, state)
+		}
+		if !game.IsOver(state) {
+			states = append(states, state)
+		}
+	}
+	return states
+}
+
+func deal(seed int64, rules *game.RuleSet, players int) (*pb.GameState, error) {
+	state := game.NewGameWithRules("bottest", "p1", seed, rules)
+	for i := 2; i <= players; i++ {
+		var err error
+		if state, err = game.AddPlayer(state, fmt.Sprintf("p%d", i)); err != nil {
+			return nil, err
+		}
+	}
+	return game.StartGame(state)
+}
+
+// randomStep plays a random card for the current player, or ends the turn if
+// the player may and chooses to.
+func randomStep(rng *rand.Rand, state *pb.GameState) *pb.GameState {
+	playerID := state.CurrentTurnPlayerId
+	moves := game.GetPossibleMoves(playerID, state)
+	canEnd := state.CardsPlayedThisTurn >= game.MinPlaysToEndTurn(state)
+	if len(moves) == 0 || (canEnd && rng.Intn(3) == 0) {
+		next, err := game.EndTurn(state, playerID)
+		if err != nil {
+			// Stuck short of the minimum: the game is lost.
+			state = proto.Clone(state).(*pb.GameState)
+			state.Status = pb.GameStatus_LOST
+			return state
+		}
+		return next
+	}
+	m := moves[rng.Intn(len(moves))]
+	next, err := game.PlayCard(state, playerID, m.Card.GetValue(), m.Pile)
+	if err != nil {
+		panic(err)
+	}
+	return next
+}
+
+// EdgeCase is a state a strategy must cope with without panicking.
+type EdgeCase struct {
+	Name     string
+	State    *pb.GameState
+	PlayerID string
+	// Playable is set if the state can arise in play, so that any plan made
+	// must be a legal turn. Otherwise a checked strategy must plan no moves,
+	// or refuse with an error.
+	Playable bool
+}
+
+// EdgeCases returns states at the edges of the rules, and states that cannot
+// arise in play at all.
+func EdgeCases(seed int64) []EdgeCase {
+	fresh := func() *pb.GameState {
+		state, err := deal(seed, nil, 1)
+		if err != nil {
+			panic(err)
+		}
+		return state
+	}
+	var cases []EdgeCase
+	add := func(name string, playerID string, playable bool, edit func(*pb.GameState)) {
+		state := fresh()
+		edit(state)
+		cases = append(cases, EdgeCase{Name: name, State: state, PlayerID: playerID, Playable: playable})
+	}
+
+	add("unknown player", "p9", false, func(*pb.GameState) {})
+	add("game won", "p1", false, func(s *pb.GameState) { s.Status = pb.GameStatus_WON })
+	add("game lost", "p1", false, func(s *pb.GameState) { s.Status = pb.GameStatus_LOST })
+	add("game not started", "p1", false, func(s *pb.GameState) { s.Status = pb.GameStatus_WAITING })
+	add("not the player's turn", "p1", false, func(s *pb.GameState) { s.CurrentTurnPlayerId = "p2" })
+	add("empty hand and deck", "p1", true, func(s *pb.GameState) {
+		s.Hands["p1"].Cards = nil
+		s.Deck, s.DeckSize = nil, 0
+	})
+	add("no playable card", "p1", true, func(s *pb.GameState) {
+		s.Piles["up1"].Cards = append(s.Piles["up1"].Cards, &pb.Card{Value: 99})
+		s.Piles["up2"].Cards = append(s.Piles["up2"].Cards, &pb.Card{Value: 98})
+		s.Piles["down1"].Cards = append(s.Piles["down1"].Cards, &pb.Card{Value: 2})
+		s.Piles["down2"].Cards = append(s.Piles["down2"].Cards, &pb.Card{Value: 3})
+		s.Hands["p1"].Cards = []*pb.Card{{Value: 50}, {Value: 51}}
+	})
+	add("single card in hand", "p1", true, func(s *pb.GameState) {
+		s.Hands["p1"].Cards = s.Hands["p1"].Cards[:1]
+		s.Deck, s.DeckSize = nil, 0
+	})
+	add("empty pile", "p1", false, func(s *pb.GameState) { s.Piles["up1"].Cards = nil })
+	add("nil pile", "p1", false, func(s *pb.GameState) { s.Piles["down1"] = nil })
+	add("no piles", "p1", false, func(s *pb.GameState) { s.Piles = nil })
+	add("no hands", "p1", false, func(s *pb.GameState) { s.Hands = nil })
+	add("card out of range", "p1", false, func(s *pb.GameState) {
+		s.Hands["p1"].Cards = append(s.Hands["p1"].Cards, &pb.Card{Value: 500}, &pb.Card{Value: -3})
+	})
+	return cases
+}
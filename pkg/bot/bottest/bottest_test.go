@@ -0,0 +1,73 @@
+package bottest
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"testing"
+
+	"the_game_card_game/pkg/bot"
+)
+
+// specs holds the spec each registered strategy is tested with, where its
+// defaults will not do: seeded, so that it is deterministic, or cut down, so
+// that the suite runs quickly.
+var specs = map[string]string{
+	"random":     "random:seed=1",
+	"montecarlo": "montecarlo:rollouts=4,seed=1",
+	"external":   "external:cmd=" + os.Args[0] + " -test.run=^TestExternalProgram$",
+}
+
+// externalProgramEnv names the environment variable that makes the test binary
+// act as the program the external strategy runs.
+const externalProgramEnv = "BOTTEST_EXTERNAL_PROGRAM"
+
+// TestExternalProgram is not a test: it is the program the external strategy
+// runs, by starting the test binary again. It plays the first legal move it is
+// offered, and ends the turn as soon as it may.
+func TestExternalProgram(t *testing.T) {
+	if os.Getenv(externalProgramEnv) == "" {
+		t.Skip("run as a program by TestRegisteredStrategies")
+	}
+	type move struct {
+		Card int32  `json:"card"`
+		Pile string `json:"pile"`
+	}
+	out := json.NewEncoder(os.Stdout)
+	in := bufio.NewScanner(os.Stdin)
+	in.Buffer(nil, 1<<20)
+	for in.Scan() {
+		var req struct {
+			Type       string `json:"type"`
+			LegalMoves []move `json:"legal_moves"`
+			CanEndTurn bool   `json:"can_end_turn"`
+		}
+		if err := json.Unmarshal(in.Bytes(), &req); err != nil {
+			os.Exit(2)
+		}
+		switch req.Type {
+		case "hello":
+			out.Encode(map[string]interface{}{"type": "hello", "version": bot.ExternalProtocolVersion, "name": "first-move"})
+		case "move":
+			if req.CanEndTurn {
+				out.Encode(map[string]interface{}{"type": "end_turn"})
+			} else {
+				out.Encode(map[string]interface{}{"type": "play", "card": req.LegalMoves[0].Card, "pile": req.LegalMoves[0].Pile})
+			}
+		}
+	}
+	os.Exit(0)
+}
+
+func TestRegisteredStrategies(t *testing.T) {
+	t.Setenv(externalProgramEnv, "1")
+	for _, def := range bot.DefaultRegistry.Definitions() {
+		spec := def.Name
+		if s, ok := specs[def.Name]; ok {
+			spec = s
+		}
+		t.Run(def.Name, func(t *testing.T) {
+			Test(t, func() (bot.TurnStrategy, error) { return bot.DefaultRegistry.New(spec) }, DefaultConfig())
+		})
+	}
+}
@@ -0,0 +1,183 @@
+package bot
+
+import (
+	"fmt"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+func init() {
+	registerMoveStrategy("random", "Plays a random valid card.",
+		[]Param{{Name: "seed", Type: ParamInt, Default: 0, Description: "Seed for the choices; 0 seeds from the clock"}},
+		func(p Params) Strategy {
+			if seed := p.Int("seed"); seed != 0 {
+				return NewSeededRandomStrategy(int64(seed))
+			}
+			return NewRandomStrategy()
+		})
+	registerMoveStrategy("minimal-jump", "Plays the card closest to the top of its pile.", nil,
+		func(Params) Strategy { return NewMinimalJumpStrategy() })
+	registerMoveStrategy("safe-ten", "Plays back-jumps when it can, otherwise the minimal jump.", nil,
+		func(Params) Strategy { return NewSafeTenStrategy() })
+	registerMoveStrategy("smart", "Plays the back-jump that makes the most room, otherwise the minimal jump.", nil,
+		func(Params) Strategy { return NewSmartStrategy() })
+	registerMoveStrategy("two-card-greedy", "Plays the minimal jump until the turn's minimum is met, then ends the turn.", nil,
+		func(Params) Strategy { return NewTwoCardGreedyStrategy() })
+	registerMoveStrategy("phased", "Plays extreme cards early, middle cards mid-game and minimal jumps late, judged by deck size.",
+		[]Param{
+			{Name: "early", Type: ParamInt, Default: defaultEarlyGameDeckSize, Description: "Deck size above which the game is early"},
+			{Name: "mid", Type: ParamInt, Default: defaultMidGameDeckSize, Description: "Deck size above which the game is in its middle"},
+			{Name: "extreme_min", Type: ParamInt, Default: defaultExtremeCardMin, Description: "Cards below this are extreme"},
+			{Name: "extreme_max", Type: ParamInt, Default: defaultExtremeCardMax, Description: "Cards above this are extreme"},
+			{Name: "mid_min", Type: ParamInt, Default: defaultMidCardMin, Description: "Lowest middle card"},
+			{Name: "mid_max", Type: ParamInt, Default: defaultMidCardMax, Description: "Highest middle card"},
+		},
+		func(p Params) Strategy {
+			return &PhasedStrategy{
+				EarlyDeckSize:  int32(p.Int("early")),
+				MidDeckSize:    int32(p.Int("mid")),
+				ExtremeCardMin: int32(p.Int("extreme_min")),
+				ExtremeCardMax: int32(p.Int("extreme_max")),
+				MidCardMin:     int32(p.Int("mid_min")),
+				MidCardMax:     int32(p.Int("mid_max")),
+			}
+		})
+	registerMoveStrategy("counting", "Counts cards: plays the card that puts the fewest unplayed cards out of reach, valuing back-jumps still to come.",
+		[]Param{
+			{Name: "held_weight", Type: ParamFloat, Default: defaultCountingHeldWeight, Description: "Cost of putting a card in the bot's own hand out of reach"},
+			{Name: "back_jump_value", Type: ParamFloat, Default: defaultCountingBackJumpValue, Description: "Worth of a back-jump that is sure to be played"},
+			{Name: "draws", Type: ParamInt, Default: defaultCountingDraws, Description: "Cards ahead the bot looks for back-jump cards"},
+			{Name: "max_extra_cost", Type: ParamFloat, Default: defaultCountingMaxExtraCost, Description: "Highest cost of a play beyond the turn's minimum"},
+		},
+		func(p Params) Strategy {
+			return &CountingStrategy{
+				HeldWeight:    p.Float("held_weight"),
+				BackJumpValue: p.Float("back_jump_value"),
+				Draws:         p.Int("draws"),
+				MaxExtraCost:  p.Float("max_extra_cost"),
+			}
+		})
+	defaults := DefaultWeights()
+	DefaultRegistry.MustRegister(Definition{
+		Name:        "weighted",
+		Description: "Plays the card whose features cost least under tunable weights; cmd/tune searches for the weights.",
+		Params: []Param{
+			{Name: "file", Type: ParamString, Default: "", Description: "Parameter file written by cmd/tune; when set, its weights replace the parameters below"},
+			{Name: "jump", Type: ParamFloat, Default: defaults.Jump, Description: "Weight of the distance a play moves its pile"},
+			{Name: "late_jump", Type: ParamFloat, Default: defaults.LateJump, Description: "Weight of that distance as the deck runs out"},
+			{Name: "skipped", Type: ParamFloat, Default: defaults.Skipped, Description: "Weight of each unplayed card put out of reach"},
+			{Name: "skipped_held", Type: ParamFloat, Default: defaults.SkippedHeld, Description: "Weight of each card in the bot's hand put out of reach"},
+			{Name: "back_jump", Type: ParamFloat, Default: defaults.BackJump, Description: "Cost of a back-jump"},
+			{Name: "back_jump_chance", Type: ParamFloat, Default: defaults.BackJumpChance, Description: "Weight of the chance of a later back-jump given up"},
+			{Name: "extra_play", Type: ParamFloat, Default: defaults.ExtraPlay, Description: "Highest cost of a play beyond the turn's minimum"},
+		},
+		New: func(p Params) (TurnStrategy, error) {
+			if path := p.String("file"); path != "" {
+				f, err := LoadWeightsFile(path)
+				if err != nil {
+					return nil, err
+				}
+				return AdaptStrategy(NewWeightedStrategy(f.Weights)), nil
+			}
+			return AdaptStrategy(NewWeightedStrategy(Weights{
+				Jump:           p.Float("jump"),
+				LateJump:       p.Float("late_jump"),
+				Skipped:        p.Float("skipped"),
+				SkippedHeld:    p.Float("skipped_held"),
+				BackJump:       p.Float("back_jump"),
+				BackJumpChance: p.Float("back_jump_chance"),
+				ExtraPlay:      p.Float("extra_play"),
+			})), nil
+		},
+	})
+	DefaultRegistry.MustRegister(Definition{
+		Name:        "lookahead",
+		Description: "Searches every sequence of plays in the turn and plays the one that leaves the most room on the piles.",
+		Params: []Param{
+			{Name: "bonus", Type: ParamFloat, Default: defaultLookaheadPlayBonus, Description: "Score of each card played; higher plays more cards per turn"},
+			{Name: "max_plays", Type: ParamInt, Default: defaultLookaheadMaxPlays, Description: "Most cards played in a turn, unless the rules require more"},
+			{Name: "max_nodes", Type: ParamInt, Default: defaultLookaheadMaxNodes, Description: "Positions searched per turn before the search is cut short"},
+		},
+		New: func(p Params) (TurnStrategy, error) {
+			return &LookaheadStrategy{PlayBonus: p.Float("bonus"), MaxPlays: p.Int("max_plays"), MaxNodes: p.Int("max_nodes")}, nil
+		},
+	})
+	DefaultRegistry.MustRegister(Definition{
+		Name:        "montecarlo",
+		Description: "Deals the unseen cards at random and plays each move out to the end of the game; slow, for use as a reference.",
+		Params: []Param{
+			{Name: "rollouts", Type: ParamInt, Default: defaultMonteCarloRollouts, Description: "Deals each decision is simulated on"},
+			{Name: "budget_ms", Type: ParamInt, Default: 0, Description: "Milliseconds each decision may take; 0 for no limit"},
+			{Name: "seed", Type: ParamInt, Default: 0, Description: "Seed for the deals; 0 seeds from the clock"},
+		},
+		New: func(p Params) (TurnStrategy, error) {
+			if p.Int("rollouts") < 1 {
+				return nil, fmt.Errorf("montecarlo needs at least one rollout")
+			}
+			return &MonteCarloStrategy{
+				Rollouts:   p.Int("rollouts"),
+				MoveBudget: time.Duration(p.Int("budget_ms")) * time.Millisecond,
+				Seed:       int64(p.Int("seed")),
+			}, nil
+		},
+	})
+	DefaultRegistry.MustRegister(Definition{
+		Name:        "external",
+		Description: "Asks another program for each move over line-delimited JSON on its standard input and output, so that bots can be written in any language.",
+		Params: []Param{
+			{Name: "cmd", Type: ParamString, Default: "", Description: "The program to run and its arguments, separated by spaces"},
+			{Name: "timeout_ms", Type: ParamInt, Default: int(defaultExternalMoveTimeout / time.Millisecond), Description: "Milliseconds the program may take over each move; 0 for no limit"},
+			{Name: "start_timeout_ms", Type: ParamInt, Default: int(defaultExternalStartTimeout / time.Millisecond), Description: "Milliseconds the program may take to start and answer the handshake; 0 for no limit"},
+		},
+		New: func(p Params) (TurnStrategy, error) {
+			command := strings.Fields(p.String("cmd"))
+			if len(command) == 0 {
+				return nil, fmt.Errorf("external needs a program to run, e.g. external:cmd=python3 bot.py")
+			}
+			if _, err := exec.LookPath(command[0]); err != nil {
+				return nil, err
+			}
+			s := NewExternalStrategy(command...)
+			s.MoveTimeout = time.Duration(p.Int("timeout_ms")) * time.Millisecond
+			s.StartTimeout = time.Duration(p.Int("start_timeout_ms")) * time.Millisecond
+			return s, nil
+		},
+	})
+	DefaultRegistry.MustRegister(Definition{
+		Name:        "endgame",
+		Description: "Plays another strategy until the deck runs out in a solo game, then plays the best line an exact search finds.",
+		Params: []Param{
+			{Name: "base", Type: ParamString, Default: "lookahead", Description: "Strategy played until then, by name with its default parameters"},
+			{Name: "max_nodes", Type: ParamInt, Default: defaultEndgameMaxNodes, Description: "Positions searched per turn before falling back to the base strategy"},
+		},
+		New: func(p Params) (TurnStrategy, error) {
+			if p.String("base") == "endgame" {
+				return nil, fmt.Errorf("endgame cannot wrap itself")
+			}
+			spec, err := DefaultRegistry.Parse(p.String("base"))
+			if err != nil {
+				return nil, fmt.Errorf("endgame base: %w", err)
+			}
+			// The endgame strategy is checked as a whole, so its base need not be.
+			base, err := spec.Definition.New(spec.Params)
+			if err != nil {
+				return nil, fmt.Errorf("endgame base: %w", err)
+			}
+			return &EndgameStrategy{Base: base, MaxNodes: p.Int("max_nodes")}, nil
+		},
+	})
+}
+
+// registerMoveStrategy registers a Strategy in the DefaultRegistry, adapted
+// to plan whole turns.
+func registerMoveStrategy(name, description string, params []Param, newFn func(Params) Strategy) {
+	DefaultRegistry.MustRegister(Definition{
+		Name:        name,
+		Description: description,
+		Params:      params,
+		New: func(p Params) (TurnStrategy, error) {
+			return AdaptStrategy(newFn(p)), nil
+		},
+	})
+}
@@ -0,0 +1,62 @@
+package bot
+
+import (
+	pb "the_game_card_game/proto"
+)
+
+const (
+	defaultCountingHeldWeight    = 2.0
+	defaultCountingBackJumpValue = 10.0
+	defaultCountingDraws         = 2
+	defaultCountingMaxExtraCost  = 0.0
+)
+
+// CountingStrategy counts cards. Every card is unique, so the cards on the
+// piles show which cards are still to come, and a jump costs only the cards
+// it puts out of reach that have not been played yet: jumping over cards
+// already played is free. A back-jump gains the cards it brings back within
+// reach.
+//
+// A pile whose back-jump card is still to come can be pulled back later, so
+// playing on it also costs the chance of that back-jump, in proportion to the
+// chance that the card turns up.
+type CountingStrategy struct {
+	// HeldWeight is the cost of putting a card in the player's own hand out
+	// of reach, where any other card costs 1.
+	HeldWeight float64
+	// BackJumpValue is the worth of a back-jump that is sure to be played.
+	BackJumpValue float64
+	// Draws is how many cards ahead the player looks for back-jump cards.
+	Draws int
+	// MaxExtraCost is the highest cost of a play beyond the turn's minimum.
+	MaxExtraCost float64
+}
+
+// NewCountingStrategy creates a CountingStrategy with the default settings.
+func NewCountingStrategy() *CountingStrategy {
+	return &CountingStrategy{
+		HeldWeight:    defaultCountingHeldWeight,
+		BackJumpValue: defaultCountingBackJumpValue,
+		Draws:         defaultCountingDraws,
+		MaxExtraCost:  defaultCountingMaxExtraCost,
+	}
+}
+
+// GetNextMove implements the Strategy interface for CountingStrategy.
+func (s *CountingStrategy) GetNextMove(playerID string, gameState *pb.GameState) (*pb.PlayCardRequest, *pb.EndTurnRequest, error) {
+	return s.weighted().GetNextMove(playerID, gameState)
+}
+
+// weighted returns the WeightedStrategy that plays like s. Counting is the
+// weighted cost with only the cards skipped and the back-jump chance weighed.
+func (s *CountingStrategy) weighted() *WeightedStrategy {
+	return &WeightedStrategy{
+		Weights: Weights{
+			Skipped:        1,
+			SkippedHeld:    s.HeldWeight,
+			BackJumpChance: s.BackJumpValue,
+			ExtraPlay:      s.MaxExtraCost,
+		},
+		Draws: s.Draws,
+	}
+}
@@ -0,0 +1,50 @@
+package bot
+
+import (
+	"testing"
+
+	"the_game_card_game/pkg/game"
+	pb "the_game_card_game/proto"
+
+	"github.com/stretchr/testify/require"
+)
+
+// countingState returns the view of a solo game in which 11 to 24 have been
+// played on up2, now topped by 90, and up1 is topped by 10.
+func countingState(t *testing.T, hand ...int32) *pb.GameState {
+	t.Helper()
+	state := startedGame(t, 1)
+	state.Piles["up1"].Cards = []*pb.Card{{Value: 1}, {Value: 10}}
+	state.Piles["up2"].Cards = []*pb.Card{{Value: 1}}
+	for v := int32(11); v <= 24; v++ {
+		state.Piles["up2"].Cards = append(state.Piles["up2"].Cards, &pb.Card{Value: v})
+	}
+	state.Piles["up2"].Cards = append(state.Piles["up2"].Cards, &pb.Card{Value: 90})
+	state.Hands["p1"].Cards = nil
+	for _, v := range hand {
+		state.Hands["p1"].Cards = append(state.Hands["p1"].Cards, &pb.Card{Value: v})
+	}
+	return game.PlayerView(state, "p1")
+}
+
+func TestCountingStrategy_JumpsOverPlayedCards(t *testing.T) {
+	// 94 on up2 is the smallest jump, but it puts 91 to 93 out of reach, while 25
+	// only jumps cards that have been played.
+	state := countingState(t, 94, 25)
+
+	play, end, err := NewCountingStrategy().GetNextMove("p1", state)
+	require.NoError(t, err)
+	require.Nil(t, end)
+	require.Equal(t, int32(25), play.Card.Value)
+	require.Equal(t, "up1", play.PileId)
+}
+
+func TestCountingStrategy_EndsTurnRatherThanWasteCards(t *testing.T) {
+	state := countingState(t, 94, 50)
+	state.CardsPlayedThisTurn = 2
+
+	play, end, err := NewCountingStrategy().GetNextMove("p1", state)
+	require.NoError(t, err)
+	require.Nil(t, play)
+	require.NotNil(t, end)
+}
@@ -0,0 +1,65 @@
+package bot
+
+import (
+	"context"
+
+	"the_game_card_game/pkg/game"
+	"the_game_card_game/pkg/solver"
+)
+
+// defaultEndgameMaxNodes bounds the endgame search. Once the deck is empty a
+// hand holds at most eight cards, which rarely takes more than a few thousand
+// positions.
+const defaultEndgameMaxNodes = 100000
+
+// EndgameStrategy plays a solo game with Base until the deck runs out. From
+// then on nothing is hidden, and it plays the best line found by an exact
+// search of the plays left. If the search cannot be completed, Base plays the
+// turn instead.
+type EndgameStrategy struct {
+	Base TurnStrategy
+	// MaxNodes bounds the positions searched each turn.
+	MaxNodes int
+}
+
+// NewEndgameStrategy wraps base with an exact endgame search.
+func NewEndgameStrategy(base TurnStrategy) *EndgameStrategy {
+	return &EndgameStrategy{Base: base, MaxNodes: defaultEndgameMaxNodes}
+}
+
+// PlanTurn implements TurnStrategy.
+func (s *EndgameStrategy) PlanTurn(ctx context.Context, view *View) ([]game.Move, error) {
+	state := view.State
+	if len(state.PlayerIds) != 1 || state.DeckSize > 0 {
+		return s.Base.PlanTurn(ctx, view)
+	}
+	r, err := solver.Solve(ctx, state, solver.Options{MaxNodes: s.MaxNodes})
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, err
+		}
+		return s.Base.PlanTurn(ctx, view)
+	}
+	if !r.Exact {
+		return s.Base.PlanTurn(ctx, view)
+	}
+	if len(r.Turns) == 0 {
+		return nil, nil
+	}
+	return r.Turns[0], nil
+}
+
+// GameStarted implements GameObserver for bases that observe games.
+func (s *EndgameStrategy) GameStarted(ctx context.Context, view *View) error {
+	if o, ok := s.Base.(GameObserver); ok {
+		return o.GameStarted(ctx, view)
+	}
+	return nil
+}
+
+// GameOver implements GameObserver for bases that observe games.
+func (s *EndgameStrategy) GameOver(ctx context.Context, view *View) {
+	if o, ok := s.Base.(GameObserver); ok {
+		o.GameOver(ctx, view)
+	}
+}
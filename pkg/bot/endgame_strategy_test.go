@@ -0,0 +1,79 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"the_game_card_game/pkg/game"
+	pb "the_game_card_game/proto"
+
+	"github.com/stretchr/testify/require"
+)
+
+// countingStrategy plans with a TwoCardGreedyStrategy, counting its turns.
+type countingStrategy struct {
+	turns int
+}
+
+func (s *countingStrategy) PlanTurn(ctx context.Context, view *View) ([]game.Move, error) {
+	s.turns++
+	return AdaptStrategy(NewTwoCardGreedyStrategy()).PlanTurn(ctx, view)
+}
+
+// endgameState returns a solo game whose deck is empty, with the piles at
+// tops and hand left to play.
+func endgameState(t *testing.T, tops map[string]int32, hand ...int32) *pb.GameState {
+	t.Helper()
+	state := startedGame(t, 1)
+	state.Deck = nil
+	state.DeckSize = 0
+	state.Hands["p1"].Cards = nil
+	for _, v := range hand {
+		state.Hands["p1"].Cards = append(state.Hands["p1"].Cards, &pb.Card{Value: v})
+	}
+	for id, top := range tops {
+		state.Piles[id].Cards = append(state.Piles[id].Cards, &pb.Card{Value: top})
+	}
+	return state
+}
+
+func TestEndgameStrategy_SolvesOnceTheDeckIsEmpty(t *testing.T) {
+	// The greedy base alone plays 42, 45 and 35, and is left holding 32.
+	state := endgameState(t, map[string]int32{"up1": 40, "up2": 95, "down1": 5, "down2": 3}, 42, 45, 32, 35)
+	base := &countingStrategy{}
+	s := NewEndgameStrategy(base)
+
+	for !game.IsOver(state) {
+		moves, err := s.PlanTurn(context.Background(), &View{PlayerID: "p1", State: game.PlayerView(state, "p1")})
+		require.NoError(t, err)
+		for _, move := range moves {
+			state, err = game.PlayCard(state, "p1", move.Card.Value, move.Pile)
+			require.NoError(t, err)
+		}
+		if !game.IsOver(state) {
+			state, err = game.EndTurn(state, "p1")
+			require.NoError(t, err)
+		}
+	}
+	require.Equal(t, pb.GameStatus_WON, state.Status)
+	require.Zero(t, base.turns, "the base strategy is not needed")
+}
+
+func TestEndgameStrategy_UsesTheBaseWhileCardsAreHidden(t *testing.T) {
+	base := &countingStrategy{}
+	s := NewEndgameStrategy(base)
+
+	_, err := s.PlanTurn(context.Background(), &View{PlayerID: "p1", State: game.PlayerView(startedGame(t, 1), "p1")})
+	require.NoError(t, err)
+	require.Equal(t, 1, base.turns)
+}
+
+func TestEndgameStrategy_Registered(t *testing.T) {
+	s, err := DefaultRegistry.New("endgame:base=smart")
+	require.NoError(t, err)
+	endgame := s.(*checkedStrategy).s.(*EndgameStrategy)
+	require.IsType(t, &strategyAdapter{}, endgame.Base, "the base is checked with the endgame strategy")
+
+	_, err = DefaultRegistry.New("endgame:base=endgame")
+	require.Error(t, err)
+}
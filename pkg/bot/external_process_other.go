@@ -0,0 +1,15 @@
+//go:build !unix
+
+package bot
+
+import "os/exec"
+
+// isolate does nothing where process groups are not supported.
+func isolate(cmd *exec.Cmd) {}
+
+// killProcess kills the command. Programs it started in turn are left
+// running, but closing its output in kill means they cannot hold up the
+// strategy.
+func killProcess(cmd *exec.Cmd) {
+	cmd.Process.Kill()
+}
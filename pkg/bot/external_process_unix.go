@@ -0,0 +1,22 @@
+//go:build unix
+
+package bot
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// isolate starts cmd in a process group of its own, so that killProcess also
+// stops any programs it starts in turn, such as those a shell or launcher
+// runs.
+func isolate(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+}
+
+// killProcess kills the process group of a command started after isolate.
+func killProcess(cmd *exec.Cmd) {
+	if err := syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL); err != nil {
+		cmd.Process.Kill()
+	}
+}
@@ -0,0 +1,378 @@
+package bot
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"the_game_card_game/pkg/game"
+	pb "the_game_card_game/proto"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// ExternalProtocolVersion is the version of the protocol ExternalStrategy
+// speaks. A program that answers the handshake with another version is not
+// used.
+//
+// The protocol is line-delimited JSON over the program's standard input and
+// output, one object per line, each with a "type":
+//
+//	-> {"type":"hello","version":1}
+//	<- {"type":"hello","version":1,"name":"my-bot"}
+//	-> {"type":"game_started","player_id":"p1","state":{...}}
+//	-> {"type":"move","player_id":"p1","state":{...},"legal_moves":[{"card":5,"pile":"up1"}],"can_end_turn":false}
+//	<- {"type":"play","card":5,"pile":"up1"}  or  {"type":"end_turn"}
+//	-> {"type":"game_over","player_id":"p1","state":{...}}
+//
+// Only hello and move are answered. A program that cannot carry on may answer
+// {"type":"error","error":"..."}. State is the player's view of the game as a
+// GameState in protobuf JSON with the field names of the .proto file, and with
+// its rules filled in. The program should exit when its standard input is
+// closed.
+const ExternalProtocolVersion = 1
+
+const (
+	defaultExternalMoveTimeout  = time.Second
+	defaultExternalStartTimeout = 10 * time.Second
+	// externalMaxLine bounds a line from the program.
+	externalMaxLine = 1 << 20
+)
+
+// ExternalStrategy plays by asking another program for each move, so that
+// bots can be written in any language. The program is started when it is
+// first needed. If it crashes, breaks the protocol or takes too long to
+// answer, the turn fails and the program is stopped; it is started afresh
+// when next needed.
+type ExternalStrategy struct {
+	// Command is the program to run and its arguments.
+	Command []string
+	// MoveTimeout bounds the wait for each move.
+	MoveTimeout time.Duration
+	// StartTimeout bounds the wait for the handshake, and for the program to
+	// exit once it is closed; zero means no limit.
+	StartTimeout time.Duration
+	// Stderr receives the program's standard error; nil passes it through to
+	// this process's.
+	Stderr io.Writer
+
+	mu   sync.Mutex
+	proc *externalProcess
+	// name is the name the program gave in the handshake.
+	name string
+}
+
+// NewExternalStrategy creates an ExternalStrategy that runs command.
+func NewExternalStrategy(command ...string) *ExternalStrategy {
+	return &ExternalStrategy{
+		Command:      command,
+		MoveTimeout:  defaultExternalMoveTimeout,
+		StartTimeout: defaultExternalStartTimeout,
+	}
+}
+
+// Name returns the name the program gave in its last handshake, or "" if it
+// has not been started.
+func (s *ExternalStrategy) Name() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.name
+}
+
+type externalMove struct {
+	Card int32  `json:"card"`
+	Pile string `json:"pile"`
+}
+
+type externalRequest struct {
+	Type       string          `json:"type"`
+	Version    int             `json:"version,omitempty"`
+	PlayerID   string          `json:"player_id,omitempty"`
+	State      json.RawMessage `json:"state,omitempty"`
+	LegalMoves []externalMove  `json:"legal_moves,omitempty"`
+	CanEndTurn bool            `json:"can_end_turn,omitempty"`
+}
+
+type externalReply struct {
+	Type    string `json:"type"`
+	Version int    `json:"version"`
+	Name    string `json:"name"`
+	Card    int32  `json:"card"`
+	Pile    string `json:"pile"`
+	Error   string `json:"error"`
+}
+
+// externalProcess is a running program.
+type externalProcess struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout io.ReadCloser
+	// lines carries the program's output, line by line. It is closed once the
+	// program has exited, after which exitErr says how.
+	lines   chan []byte
+	exitErr error
+}
+
+func (s *ExternalStrategy) start(ctx context.Context) (*externalProcess, error) {
+	if len(s.Command) == 0 {
+		return nil, errors.New("no command to run")
+	}
+	cmd := exec.Command(s.Command[0], s.Command[1:]...)
+	cmd.Stderr = s.Stderr
+	if cmd.Stderr == nil {
+		cmd.Stderr = os.Stderr
+	}
+	isolate(cmd)
+	// A program the command started may keep its output open after it has
+	// been killed; Wait gives up on copying it after this long.
+	cmd.WaitDelay = time.Second
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("start %s: %w", s.Command[0], err)
+	}
+	p := &externalProcess{cmd: cmd, stdin: stdin, stdout: stdout, lines: make(chan []byte)}
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 0, 4096), externalMaxLine)
+		for scanner.Scan() {
+			p.lines <- append([]byte(nil), scanner.Bytes()...)
+		}
+		// Wait must not be called before every read from stdout is done.
+		p.exitErr = scanner.Err()
+		if err := cmd.Wait(); err != nil && p.exitErr == nil {
+			p.exitErr = err
+		}
+		close(p.lines)
+	}()
+
+	reply, err := p.exchange(ctx, s.StartTimeout, externalRequest{Type: "hello", Version: ExternalProtocolVersion})
+	if err == nil && reply.Type != "hello" {
+		err = fmt.Errorf("answered the handshake with %q", reply.Type)
+	}
+	if err == nil && reply.Version != ExternalProtocolVersion {
+		err = fmt.Errorf("speaks protocol version %d, want %d", reply.Version, ExternalProtocolVersion)
+	}
+	if err != nil {
+		p.kill()
+		return nil, fmt.Errorf("handshake: %w", err)
+	}
+	s.name = reply.Name
+	return p, nil
+}
+
+// exchange sends req and waits up to timeout for the reply. A zero timeout
+// waits for as long as ctx allows.
+func (p *externalProcess) exchange(ctx context.Context, timeout time.Duration, req externalRequest) (*externalReply, error) {
+	if err := p.send(req); err != nil {
+		return nil, err
+	}
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case line, ok := <-p.lines:
+		if !ok {
+			return nil, p.exited()
+		}
+		reply := &externalReply{}
+		if err := json.Unmarshal(line, reply); err != nil {
+			return nil, fmt.Errorf("unreadable reply %q: %w", line, err)
+		}
+		if reply.Type == "error" {
+			return nil, fmt.Errorf("program error: %s", reply.Error)
+		}
+		return reply, nil
+	case <-expired:
+		return nil, fmt.Errorf("no reply to %s within %s", req.Type, timeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+func (p *externalProcess) send(req externalRequest) error {
+	line, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	if _, err := p.stdin.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("send %s: %w", req.Type, err)
+	}
+	return nil
+}
+
+// exited describes how the program exited. It may only be called once lines
+// is closed.
+func (p *externalProcess) exited() error {
+	if p.exitErr != nil {
+		return fmt.Errorf("program exited: %w", p.exitErr)
+	}
+	return errors.New("program exited")
+}
+
+// kill stops the program, and any programs it started, and waits for it to
+// exit. Its output is closed first, so that a program that escaped the kill
+// and still holds it cannot keep the wait from ending.
+func (p *externalProcess) kill() {
+	p.stdin.Close()
+	killProcess(p.cmd)
+	p.stdout.Close()
+	for range p.lines {
+	}
+}
+
+// do runs fn with the running program. If none is running it starts one, or
+// if start is false does nothing. If fn fails the program is stopped, since
+// it may no longer be in step with the game.
+func (s *ExternalStrategy) do(ctx context.Context, start bool, fn func(p *externalProcess) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.proc == nil {
+		if !start {
+			return nil
+		}
+		p, err := s.start(ctx)
+		if err != nil {
+			return err
+		}
+		s.proc = p
+	}
+	if err := fn(s.proc); err != nil {
+		s.proc.kill()
+		s.proc = nil
+		return err
+	}
+	return nil
+}
+
+// encodeState returns the view in protobuf JSON, with its rules filled in.
+func encodeState(state *pb.GameState) (json.RawMessage, error) {
+	state = proto.Clone(state).(*pb.GameState)
+	state.Rules = game.RulesOf(state)
+	return protojson.MarshalOptions{UseProtoNames: true}.Marshal(state)
+}
+
+func (s *ExternalStrategy) notify(ctx context.Context, kind string, start bool, view *View) error {
+	state, err := encodeState(view.State)
+	if err != nil {
+		return err
+	}
+	return s.do(ctx, start, func(p *externalProcess) error {
+		return p.send(externalRequest{Type: kind, PlayerID: view.PlayerID, State: state})
+	})
+}
+
+// GameStarted implements GameObserver. It starts the program if it is not
+// running.
+func (s *ExternalStrategy) GameStarted(ctx context.Context, view *View) error {
+	return s.notify(ctx, "game_started", true, view)
+}
+
+// GameOver implements GameObserver. A program that is not running, for
+// example because it crashed, is not started just to be told.
+func (s *ExternalStrategy) GameOver(ctx context.Context, view *View) {
+	s.notify(ctx, "game_over", false, view)
+}
+
+// PlanTurn implements TurnStrategy. The turn is planned by asking the program
+// for one move at a time, applying each move to a copy of the player's view,
+// until it ends the turn or no card can be played.
+func (s *ExternalStrategy) PlanTurn(ctx context.Context, view *View) ([]game.Move, error) {
+	state := view.State
+	var moves []game.Move
+	for !game.IsOver(state) {
+		possible := game.GetPossibleMoves(view.PlayerID, state)
+		if len(possible) == 0 {
+			break
+		}
+		req := externalRequest{
+			Type:       "move",
+			PlayerID:   view.PlayerID,
+			LegalMoves: make([]externalMove, len(possible)),
+			CanEndTurn: state.CardsPlayedThisTurn >= game.MinPlaysToEndTurn(state),
+		}
+		for i, m := range possible {
+			req.LegalMoves[i] = externalMove{Card: m.Card.GetValue(), Pile: m.Pile}
+		}
+		var err error
+		if req.State, err = encodeState(state); err != nil {
+			return nil, err
+		}
+
+		var reply *externalReply
+		err = s.do(ctx, true, func(p *externalProcess) error {
+			reply, err = p.exchange(ctx, s.MoveTimeout, req)
+			if err != nil {
+				return err
+			}
+			if reply.Type != "play" && reply.Type != "end_turn" {
+				return fmt.Errorf("answered a move with %q", reply.Type)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		if reply.Type == "end_turn" {
+			if !req.CanEndTurn {
+				return nil, fmt.Errorf("program ended the turn after %d of %d cards", state.CardsPlayedThisTurn, game.MinPlaysToEndTurn(state))
+			}
+			break
+		}
+		next, err := game.PlayCard(state, view.PlayerID, reply.Card, reply.Pile)
+		if err != nil {
+			return nil, fmt.Errorf("program chose an illegal move: %w", err)
+		}
+		moves = append(moves, game.Move{Card: &pb.Card{Value: reply.Card}, Pile: reply.Pile})
+		state = next
+	}
+	return moves, nil
+}
+
+// Close stops the program, giving it StartTimeout to exit once its standard
+// input is closed. A zero StartTimeout waits for as long as it takes.
+func (s *ExternalStrategy) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	p := s.proc
+	if p == nil {
+		return nil
+	}
+	s.proc = nil
+	p.stdin.Close()
+	var expired <-chan time.Time
+	if s.StartTimeout > 0 {
+		timer := time.NewTimer(s.StartTimeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	for {
+		select {
+		case _, ok := <-p.lines:
+			if !ok {
+				return nil
+			}
+		case <-expired:
+			p.kill()
+			return fmt.Errorf("program did not exit within %s", s.StartTimeout)
+		}
+	}
+}
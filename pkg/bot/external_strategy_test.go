@@ -0,0 +1,159 @@
+package bot
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"testing"
+	"time"
+
+	"the_game_card_game/pkg/game"
+
+	"github.com/stretchr/testify/require"
+)
+
+// externalHelperEnv names the environment variable that makes the test binary
+// act as an external program; its value is how the program behaves.
+const externalHelperEnv = "BOT_EXTERNAL_HELPER"
+
+// TestExternalHelperProcess is not a test: it is the program the other tests
+// run, by starting the test binary again.
+func TestExternalHelperProcess(t *testing.T) {
+	mode := os.Getenv(externalHelperEnv)
+	if mode == "" {
+		t.Skip("run as a program by the external strategy tests")
+	}
+	out := json.NewEncoder(os.Stdout)
+	in := bufio.NewScanner(os.Stdin)
+	in.Buffer(nil, externalMaxLine)
+	for in.Scan() {
+		var req externalRequest
+		if err := json.Unmarshal(in.Bytes(), &req); err != nil {
+			os.Exit(2)
+		}
+		switch req.Type {
+		case "hello":
+			version := ExternalProtocolVersion
+			if mode == "old" {
+				version = 0
+			}
+			out.Encode(externalReply{Type: "hello", Version: version, Name: "helper-" + mode})
+		case "move":
+			switch mode {
+			case "crash":
+				os.Exit(3)
+			case "hang":
+				time.Sleep(time.Minute)
+			case "orphan":
+				// Leave a program holding the output open, as a shell or
+				// launcher would, then hang.
+				child := exec.Command("sleep", "60")
+				child.Stdout = os.Stdout
+				child.Start()
+				time.Sleep(time.Minute)
+			}
+			if req.CanEndTurn {
+				out.Encode(externalReply{Type: "end_turn"})
+			} else {
+				out.Encode(externalReply{Type: "play", Card: req.LegalMoves[0].Card, Pile: req.LegalMoves[0].Pile})
+			}
+		}
+	}
+	os.Exit(0)
+}
+
+// helperStrategy returns an ExternalStrategy that runs the test binary as a
+// program behaving as mode.
+func helperStrategy(t *testing.T, mode string) *ExternalStrategy {
+	t.Helper()
+	t.Setenv(externalHelperEnv, mode)
+	s := NewExternalStrategy(os.Args[0], "-test.run=^TestExternalHelperProcess$")
+	s.MoveTimeout = 5 * time.Second
+	s.Stderr = io.Discard
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestExternalStrategy_PlaysAGame(t *testing.T) {
+	s := helperStrategy(t, "minimal")
+	ctx := context.Background()
+	state := startedGame(t, 7)
+	view := func() *View { return &View{PlayerID: "p1", State: game.PlayerView(state, "p1")} }
+
+	require.NoError(t, s.GameStarted(ctx, view()))
+	require.Equal(t, "helper-minimal", s.Name())
+	for !game.IsOver(state) {
+		moves, err := s.PlanTurn(ctx, view())
+		require.NoError(t, err)
+		for _, move := range moves {
+			state, err = game.PlayCard(state, "p1", move.Card.Value, move.Pile)
+			require.NoError(t, err)
+		}
+		if !game.IsOver(state) {
+			state, err = game.EndTurn(state, "p1")
+			require.NoError(t, err)
+		}
+	}
+	s.GameOver(ctx, view())
+	require.NoError(t, s.Close())
+}
+
+func TestExternalStrategy_CloseWithoutTimeout(t *testing.T) {
+	s := helperStrategy(t, "minimal")
+	s.StartTimeout = 0 // No limit.
+	view := &View{PlayerID: "p1", State: game.PlayerView(startedGame(t, 7), "p1")}
+	_, err := s.PlanTurn(context.Background(), view)
+	require.NoError(t, err)
+
+	// The program exits once its input is closed, and is waited for.
+	require.NoError(t, s.Close())
+}
+
+func TestExternalStrategy_Failures(t *testing.T) {
+	for _, tc := range []struct {
+		mode string
+		want string
+	}{
+		{"old", "protocol version 0"},
+		{"crash", "exit status 3"},
+		{"hang", "no reply to move"},
+		{"orphan", "no reply to move"},
+	} {
+		t.Run(tc.mode, func(t *testing.T) {
+			if tc.mode == "orphan" {
+				if _, err := exec.LookPath("sleep"); err != nil {
+					t.Skip("no sleep program to leave running")
+				}
+			}
+			s := helperStrategy(t, tc.mode)
+			s.MoveTimeout = 200 * time.Millisecond
+			view := &View{PlayerID: "p1", State: game.PlayerView(startedGame(t, 7), "p1")}
+
+			_, err := s.PlanTurn(context.Background(), view)
+			require.ErrorContains(t, err, tc.want)
+
+			// The program is started afresh for the next turn, and fails the
+			// same way.
+			_, err = s.PlanTurn(context.Background(), view)
+			require.ErrorContains(t, err, tc.want)
+		})
+	}
+}
+
+func TestExternalStrategy_Registered(t *testing.T) {
+	_, err := DefaultRegistry.New("external")
+	require.Error(t, err, "a program must be given")
+
+	_, err = DefaultRegistry.New("external:cmd=no-such-program-" + fmt.Sprint(os.Getpid()))
+	require.Error(t, err)
+
+	strategy, err := DefaultRegistry.New("external:cmd=" + os.Args[0] + " -test.run=x,timeout_ms=250")
+	require.NoError(t, err)
+	external := strategy.(*checkedStrategy).s.(*ExternalStrategy)
+	require.Equal(t, []string{os.Args[0], "-test.run=x"}, external.Command)
+	require.Equal(t, 250*time.Millisecond, external.MoveTimeout)
+}
@@ -0,0 +1,161 @@
+package bot
+
+import (
+	"the_game_card_game/pkg/game"
+	pb "the_game_card_game/proto"
+)
+
+// Knowledge is what a player knows about the cards of a game. Every card
+// from MinCard to MaxCard is dealt exactly once, so the cards on the piles show
+// which can never come again, and a card the player cannot see is either in
+// the deck or in another player's hand.
+type Knowledge struct {
+	MinCard, MaxCard int32
+
+	// where[v] is what the player knows of card v.
+	where []cardPlace
+	// deckIndex[v] is the position in the deck of a card the view shows there.
+	deckIndex map[int32]int
+
+	unseen int
+	// deckSize and hiddenHandCards are where the unseen cards may be: in the
+	// deck, or in the hands of other players.
+	deckSize        int
+	hiddenHandCards int
+}
+
+type cardPlace int
+
+const (
+	placeUnseen cardPlace = iota
+	placePlayed
+	placeHeld      // In the player's hand.
+	placeOtherHand // In another player's hand, which the view shows.
+	placeDeck      // In the deck, which the view shows.
+)
+
+// NewKnowledge returns what playerID knows from state, the player's view of
+// the game. Hands and a deck that the view shows are known too.
+func NewKnowledge(state *pb.GameState, playerID string) *Knowledge {
+	rules := game.RulesOf(state)
+	k := &Knowledge{
+		MinCard:  rules.MinCard,
+		MaxCard:  rules.MaxCard,
+		where:    make([]cardPlace, rules.MaxCard+1),
+		deckSize: int(state.DeckSize),
+	}
+	see := func(cards []*pb.Card, place cardPlace) {
+		for _, c := range cards {
+			if v := c.GetValue(); k.inRange(v) {
+				k.where[v] = place
+			}
+		}
+	}
+	for _, pile := range state.Piles {
+		see(pile.Cards, placePlayed)
+	}
+	for _, id := range state.PlayerIds {
+		hand, ok := state.Hands[id]
+		switch {
+		case !ok:
+			k.hiddenHandCards += int(state.HandSizes[id])
+		case id == playerID:
+			see(hand.Cards, placeHeld)
+		default:
+			see(hand.Cards, placeOtherHand)
+		}
+	}
+	if len(state.Deck) == int(state.DeckSize) {
+		see(state.Deck, placeDeck)
+		k.deckIndex = make(map[int32]int, len(state.Deck))
+		for i, c := range state.Deck {
+			k.deckIndex[c.GetValue()] = i
+		}
+	}
+	for v := k.MinCard; v <= k.MaxCard; v++ {
+		if k.where[v] == placeUnseen {
+			k.unseen++
+		}
+	}
+	return k
+}
+
+func (k *Knowledge) inRange(v int32) bool {
+	return v >= k.MinCard && v <= k.MaxCard
+}
+
+func (k *Knowledge) is(v int32, place cardPlace) bool {
+	return k.inRange(v) && k.where[v] == place
+}
+
+// Unseen reports whether card v is in the game but hidden from the player.
+func (k *Knowledge) Unseen(v int32) bool {
+	return k.is(v, placeUnseen)
+}
+
+// Held reports whether card v is in the player's hand.
+func (k *Knowledge) Held(v int32) bool {
+	return k.is(v, placeHeld)
+}
+
+// ToPlay reports whether card v has still to be played.
+func (k *Knowledge) ToPlay(v int32) bool {
+	return k.inRange(v) && k.where[v] != placePlayed
+}
+
+// UnseenCount returns the number of unseen cards.
+func (k *Knowledge) UnseenCount() int {
+	return k.unseen
+}
+
+// UnseenCards returns the unseen cards in increasing order.
+func (k *Knowledge) UnseenCards() []int32 {
+	cards := make([]int32, 0, k.unseen)
+	for v := k.MinCard; v <= k.MaxCard; v++ {
+		if k.where[v] == placeUnseen {
+			cards = append(cards, v)
+		}
+	}
+	return cards
+}
+
+// Between returns how many cards still to be played lie strictly between lo
+// and hi: those in the player's hand, and the others.
+func (k *Knowledge) Between(lo, hi int32) (others, held int) {
+	if lo > hi {
+		lo, hi = hi, lo
+	}
+	for v := lo + 1; v < hi; v++ {
+		if k.Held(v) {
+			held++
+		} else if k.ToPlay(v) {
+			others++
+		}
+	}
+	return others, held
+}
+
+// ChanceAvailable estimates the probability that card v can be played by
+// someone at the table before the player has drawn draws more cards: it is in
+// a hand now, or among the player's next draws. Every unseen card is taken to
+// be equally likely to be anywhere the player cannot see.
+func (k *Knowledge) ChanceAvailable(v int32, draws int) float64 {
+	switch {
+	case !k.ToPlay(v):
+		return 0
+	case k.Held(v), k.is(v, placeOtherHand):
+		return 1
+	case k.is(v, placeDeck):
+		if k.deckIndex[v] < draws {
+			return 1
+		}
+		return 0
+	}
+	if k.deckIndex != nil {
+		// The deck is in view, so an unseen card is in another player's hand.
+		draws = 0
+	} else if draws > k.deckSize {
+		draws = k.deckSize
+	}
+	return float64(k.hiddenHandCards+draws) / float64(k.unseen)
+}
@@ -0,0 +1,53 @@
+package bot
+
+import (
+	"testing"
+
+	"the_game_card_game/pkg/game"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestKnowledge_TracksUnseenCards(t *testing.T) {
+	state, err := game.AddPlayer(game.NewSeededGame("bot-game", "p1", 5), "p2")
+	require.NoError(t, err)
+	state, err = game.StartGame(state)
+	require.NoError(t, err)
+	state, err = game.PlayCard(state, "p1", state.Hands["p1"].Cards[0].Value, "up1")
+	require.NoError(t, err)
+	played := state.Piles["up1"].Cards[1].Value
+
+	k := NewKnowledge(game.PlayerView(state, "p1"), "p1")
+	require.Equal(t, len(state.Deck)+len(state.Hands["p2"].Cards), k.UnseenCount())
+	require.Len(t, k.UnseenCards(), k.UnseenCount())
+
+	require.False(t, k.Unseen(played))
+	require.False(t, k.ToPlay(played))
+	own := state.Hands["p1"].Cards[0].Value
+	require.True(t, k.Held(own))
+	require.True(t, k.ToPlay(own))
+	other := state.Hands["p2"].Cards[0].Value
+	require.True(t, k.Unseen(other))
+	require.False(t, k.Unseen(1), "the piles' starting cards are not in play")
+
+	unseen, held := k.Between(1, 100)
+	require.Equal(t, k.UnseenCount(), unseen)
+	require.Equal(t, len(state.Hands["p1"].Cards), held)
+
+	require.Equal(t, 1.0, k.ChanceAvailable(own, 0))
+	require.Zero(t, k.ChanceAvailable(played, 10))
+	hidden := float64(len(state.Hands["p2"].Cards))
+	require.InDelta(t, hidden/float64(k.UnseenCount()), k.ChanceAvailable(other, 0), 1e-9)
+	require.InDelta(t, (hidden+3)/float64(k.UnseenCount()), k.ChanceAvailable(other, 3), 1e-9)
+	require.InDelta(t, 1, k.ChanceAvailable(other, 1000), 1e-9, "the player can draw no more than the deck")
+}
+
+func TestKnowledge_UsesAVisibleDeck(t *testing.T) {
+	state := startedGame(t, 5)
+
+	k := NewKnowledge(state, "p1")
+	require.Zero(t, k.UnseenCount())
+	require.True(t, k.ToPlay(state.Deck[0].Value))
+	require.Equal(t, 1.0, k.ChanceAvailable(state.Deck[1].Value, 2))
+	require.Zero(t, k.ChanceAvailable(state.Deck[2].Value, 2))
+}
@@ -0,0 +1,237 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+
+	"the_game_card_game/pkg/game"
+	pb "the_game_card_game/proto"
+)
+
+const (
+	defaultLookaheadPlayBonus = 3.0
+	defaultLookaheadMaxPlays  = 3
+	defaultLookaheadMaxNodes  = 100000
+
+	// maxSearchPiles is the most piles a turn search can track.
+	maxSearchPiles = 8
+
+	// missedPlayPenalty is subtracted for every card short of the turn's
+	// minimum, so that a plan only falls short when nothing else is possible.
+	missedPlayPenalty = 1e6
+)
+
+// LookaheadStrategy plans a whole turn at once. It searches every legal
+// sequence of plays from its hand, so that it finds combinations such as
+// playing 45 on an ascending pile to make 35 a back-jump on the same pile, and
+// chooses the sequence whose resulting piles score best.
+//
+// The score of a pile configuration is its room: for every pile, the number
+// of cards not yet played that could still go on it without a back-jump. Each
+// card played also earns PlayBonus, so that the strategy plays more than the
+// minimum only when the room it costs is small.
+type LookaheadStrategy struct {
+	// PlayBonus is the score of each card played.
+	PlayBonus float64
+	// MaxPlays is the most cards the strategy plays in a turn, unless the
+	// rules require more.
+	MaxPlays int
+	// MaxNodes bounds the positions searched in a turn. Positions beyond it
+	// are not expanded, so the plan may miss the best sequence.
+	MaxNodes int
+}
+
+// NewLookaheadStrategy creates a LookaheadStrategy with the default settings.
+func NewLookaheadStrategy() *LookaheadStrategy {
+	return &LookaheadStrategy{PlayBonus: defaultLookaheadPlayBonus, MaxPlays: defaultLookaheadMaxPlays, MaxNodes: defaultLookaheadMaxNodes}
+}
+
+// PlanTurn implements TurnStrategy.
+func (s *LookaheadStrategy) PlanTurn(ctx context.Context, view *View) ([]game.Move, error) {
+	state := view.State
+	hand := state.Hands[view.PlayerID].GetCards()
+	if len(hand) > 64 {
+		return nil, fmt.Errorf("a hand of %d cards is too large to search", len(hand))
+	}
+	if len(state.Piles) > maxSearchPiles {
+		return nil, fmt.Errorf("%d piles are too many to search", len(state.Piles))
+	}
+	rules := game.RulesOf(state)
+
+	search := &turnSearch{
+		ctx:      ctx,
+		backJump: rules.GetBackJump(),
+		bonus:    s.PlayBonus,
+		maxNodes: s.MaxNodes,
+		memo:     make(map[turnKey]turnNode),
+	}
+	search.minPlays = int(game.MinPlaysToEndTurn(state) - state.CardsPlayedThisTurn)
+	search.maxPlays = s.MaxPlays
+	if search.maxPlays < search.minPlays {
+		search.maxPlays = search.minPlays
+	}
+	// The search's tables are indexed by card value.
+	for _, c := range hand {
+		if v := c.GetValue(); v < rules.MinCard || v > rules.MaxCard {
+			return nil, fmt.Errorf("card %d is outside the range %d-%d", v, rules.MinCard, rules.MaxCard)
+		}
+		search.hand = append(search.hand, c.GetValue())
+	}
+
+	// Every card that is not on a pile may still have to be played, whether
+	// it is in a hand or in the deck.
+	played := make([]bool, rules.MaxCard+2)
+	var tops [maxSearchPiles]int32
+	search.pileIDs = game.PileIDs(state)
+	for _, id := range search.pileIDs {
+		cards := state.Piles[id].GetCards()
+		if len(cards) == 0 {
+			return nil, fmt.Errorf("pile %s has no cards", id)
+		}
+		for _, c := range cards {
+			if v := c.GetValue(); v >= rules.MinCard && v <= rules.MaxCard {
+				played[v] = true
+			}
+		}
+		tops[len(search.ascending)] = cards[len(cards)-1].GetValue()
+		search.ascending = append(search.ascending, state.Piles[id].GetAscending())
+	}
+	// below[v] and above[v] count the unplayed cards lower and higher than v.
+	search.below = make([]int, rules.MaxCard+2)
+	search.above = make([]int, rules.MaxCard+2)
+	for v := rules.MinCard; v <= rules.MaxCard+1; v++ {
+		search.below[v] = search.below[v-1]
+		if !played[v-1] && v-1 >= rules.MinCard {
+			search.below[v]++
+		}
+	}
+	for v := rules.MaxCard; v >= rules.MinCard-1; v-- {
+		search.above[v] = search.above[v+1]
+		if v+1 <= rules.MaxCard && !played[v+1] {
+			search.above[v]++
+		}
+	}
+
+	if _, err := search.best(0, tops, 0); err != nil {
+		return nil, err
+	}
+	return search.plan(tops, hand), nil
+}
+
+// turnNode is the best way to finish a turn from a position in the search.
+type turnNode struct {
+	score float64
+	card  int // Index in the hand of the next card to play, or -1 to stop.
+	pile  int
+}
+
+// turnSearch finds the best sequence of plays in a turn. A position is the
+// set of hand cards played so far and the top of every pile.
+type turnSearch struct {
+	ctx       context.Context
+	hand      []int32
+	pileIDs   []string
+	ascending []bool
+	backJump  int32
+	minPlays  int
+	maxPlays  int
+	bonus     float64
+
+	below, above []int
+
+	memo     map[turnKey]turnNode
+	nodes    int
+	maxNodes int
+}
+
+// turnKey identifies a position: the hand cards played and the pile tops.
+// Playing the same cards on the same piles in another order reaches the same
+// position, which is only searched once.
+type turnKey struct {
+	mask uint64
+	tops [maxSearchPiles]int32
+}
+
+// best returns the best way to finish the turn from the position where the
+// cards in mask have been played, leaving the piles at tops.
+func (s *turnSearch) best(mask uint64, tops [maxSearchPiles]int32, plays int) (turnNode, error) {
+	key := turnKey{mask, tops}
+	if node, ok := s.memo[key]; ok {
+		return node, nil
+	}
+	if s.nodes%1024 == 0 {
+		if err := s.ctx.Err(); err != nil {
+			return turnNode{}, err
+		}
+	}
+	s.nodes++
+
+	// Stopping is always possible, but costly until the minimum is met.
+	node := turnNode{score: s.evaluate(mask, tops, plays), card: -1}
+	if plays < s.minPlays {
+		node.score -= missedPlayPenalty * float64(s.minPlays-plays)
+	}
+
+	if plays < s.maxPlays && s.nodes <= s.maxNodes {
+		for i, card := range s.hand {
+			if mask&(1<<i) != 0 {
+				continue
+			}
+			for p, ascending := range s.ascending {
+				if !game.CanPlayOnTop(ascending, tops[p], card, s.backJump) {
+					continue
+				}
+				next := tops
+				next[p] = card
+				child, err := s.best(mask|1<<i, next, plays+1)
+				if err != nil {
+					return turnNode{}, err
+				}
+				if child.score > node.score {
+					node = turnNode{score: child.score, card: i, pile: p}
+				}
+			}
+		}
+	}
+
+	s.memo[key] = node
+	return node, nil
+}
+
+// evaluate scores the piles at tops once the cards in mask have been played.
+func (s *turnSearch) evaluate(mask uint64, tops [maxSearchPiles]int32, plays int) float64 {
+	room := 0
+	for p, ascending := range s.ascending {
+		top := tops[p]
+		if ascending {
+			room += s.above[top]
+		} else {
+			room += s.below[top]
+		}
+		// The cards played this turn are still counted by above and below.
+		for i, card := range s.hand {
+			if mask&(1<<i) == 0 {
+				continue
+			}
+			if (ascending && card > top) || (!ascending && card < top) {
+				room--
+			}
+		}
+	}
+	return float64(room) + s.bonus*float64(plays)
+}
+
+// plan follows the best moves found from the start of the turn.
+func (s *turnSearch) plan(tops [maxSearchPiles]int32, hand []*pb.Card) []game.Move {
+	var moves []game.Move
+	mask := uint64(0)
+	for {
+		node, ok := s.memo[turnKey{mask, tops}]
+		if !ok || node.card < 0 {
+			return moves
+		}
+		moves = append(moves, game.Move{Card: hand[node.card], Pile: s.pileIDs[node.pile]})
+		mask |= 1 << node.card
+		tops[node.pile] = s.hand[node.card]
+	}
+}
@@ -0,0 +1,75 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"the_game_card_game/pkg/game"
+	pb "the_game_card_game/proto"
+
+	"github.com/stretchr/testify/require"
+)
+
+// lookaheadView returns p1's view of a game with the given pile tops and hand,
+// under the standard rules.
+func lookaheadView(tops map[string]int32, hand ...int32) *View {
+	state := &pb.GameState{
+		GameId:              "lookahead-game",
+		PlayerIds:           []string{"p1"},
+		CurrentTurnPlayerId: "p1",
+		DeckSize:            50,
+		Piles:               map[string]*pb.Pile{},
+		Hands:               map[string]*pb.Hand{"p1": {}},
+		Status:              pb.GameStatus_IN_PROGRESS,
+	}
+	for _, rule := range game.DefaultRuleSet().Piles {
+		state.Piles[rule.Id] = &pb.Pile{Ascending: rule.Ascending, Cards: []*pb.Card{{Value: tops[rule.Id]}}}
+	}
+	for _, v := range hand {
+		state.Hands["p1"].Cards = append(state.Hands["p1"].Cards, &pb.Card{Value: v})
+	}
+	return &View{PlayerID: "p1", State: state}
+}
+
+// planned returns the moves as "card@pile" strings.
+func planned(t *testing.T, s TurnStrategy, view *View) []string {
+	t.Helper()
+	moves, err := s.PlanTurn(context.Background(), view)
+	require.NoError(t, err)
+	var out []string
+	for _, m := range moves {
+		out = append(out, fmt.Sprintf("%d@%s", m.Card.GetValue(), m.Pile))
+	}
+	return out
+}
+
+func TestLookahead_FindsBackJumpCombination(t *testing.T) {
+	tops := map[string]int32{"up1": 30, "up2": 60, "down1": 70, "down2": 20}
+	view := lookaheadView(tops, 45, 35, 88, 12)
+
+	// 45 on up1 costs room, but it makes 35 a back-jump on the same pile,
+	// which leaves up1 higher than it started only by 5.
+	require.Equal(t, []string{"45@up1", "35@up1"}, planned(t, NewLookaheadStrategy(), view))
+}
+
+func TestLookahead_DecidesHowManyCardsToPlay(t *testing.T) {
+	tops := map[string]int32{"up1": 30, "up2": 60, "down1": 20, "down2": 25}
+
+	// Cards that fit snugly are worth playing beyond the minimum.
+	cheap := planned(t, NewLookaheadStrategy(), lookaheadView(tops, 31, 32, 33, 90))
+	require.Len(t, cheap, 3)
+
+	// Costly cards are held back once the minimum has been played.
+	costly := planned(t, NewLookaheadStrategy(), lookaheadView(tops, 31, 32, 85, 90))
+	require.Len(t, costly, 2)
+}
+
+func TestLookahead_StopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	view := lookaheadView(map[string]int32{"up1": 1, "up2": 1, "down1": 100, "down2": 100}, 10, 20, 30, 40, 50, 60, 70, 80)
+
+	_, err := NewLookaheadStrategy().PlanTurn(ctx, view)
+	require.ErrorIs(t, err, context.Canceled)
+}
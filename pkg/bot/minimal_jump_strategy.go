@@ -33,7 +33,7 @@ func (s *MinimalJumpStrategy) GetNextMove(playerID string, gameState *pb.GameSta
 		cardValue := move.Card.Value
 
 		// Prioritize 10-back moves
-		if (pile.Ascending && cardValue == topCard.Value-10) || (!pile.Ascending && cardValue == topCard.Value+10) {
+		if game.IsBackJump(gameState, pile, cardValue) {
 			bestMove = move
 			break // This is always a good move, so we can take it immediately.
 		}
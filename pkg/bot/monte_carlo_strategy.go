@@ -0,0 +1,247 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"the_game_card_game/pkg/game"
+	pb "the_game_card_game/proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	defaultMonteCarloRollouts = 64
+
+	// rolloutMaxJump is the largest jump the rollout policy makes once it has
+	// played the turn's minimum.
+	rolloutMaxJump = 1
+)
+
+// MonteCarloStrategy chooses each play by simulation. Before every decision it
+// deals the cards it cannot see, the deck and the other players' hands, at
+// random from those not yet visible, and plays every candidate move out to the
+// end of the game with a fast greedy policy. It makes the move that leaves the
+// fewest cards on average.
+//
+// Every candidate is played out on the same deals, so that they are compared
+// on equal terms. It is slow, and meant as a reference opponent for measuring
+// other strategies.
+type MonteCarloStrategy struct {
+	// Rollouts is the number of deals each decision is simulated on.
+	Rollouts int
+	// MoveBudget bounds the time spent on each decision, if positive. At least
+	// one deal is simulated however long it takes.
+	MoveBudget time.Duration
+	// Seed seeds the deals; 0 seeds from the clock.
+	Seed int64
+
+	rng *rand.Rand
+}
+
+// NewMonteCarloStrategy creates a MonteCarloStrategy with the default settings.
+func NewMonteCarloStrategy() *MonteCarloStrategy {
+	return &MonteCarloStrategy{Rollouts: defaultMonteCarloRollouts}
+}
+
+// PlanTurn implements TurnStrategy.
+func (s *MonteCarloStrategy) PlanTurn(ctx context.Context, view *View) ([]game.Move, error) {
+	if s.rng == nil {
+		seed := s.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		s.rng = rand.New(rand.NewSource(seed))
+	}
+	d, err := newDeterminizer(view)
+	if err != nil {
+		return nil, err
+	}
+
+	var moves []game.Move
+	for !d.base.IsOver() {
+		candidates := candidateMoves(d.base)
+		if len(candidates) == 0 {
+			break
+		}
+		best := candidates[0]
+		if len(candidates) > 1 {
+			if best, err = s.choose(ctx, d, candidates); err != nil {
+				return nil, err
+			}
+		}
+		if best.card < 0 {
+			break
+		}
+		card := d.base.Hands[d.base.Current][best.card]
+		moves = append(moves, game.Move{Card: &pb.Card{Value: card}, Pile: d.base.PileIDs[best.pile]})
+		d.base.Play(best.card, best.pile)
+	}
+	return moves, nil
+}
+
+// compactMove is a play of the card at an index of the current hand on the
+// pile at an index, or the end of the turn if card is -1.
+type compactMove struct {
+	card, pile int
+}
+
+// candidateMoves returns every legal play of the current player, and the end
+// of the turn once it is allowed.
+func candidateMoves(c *game.Compact) []compactMove {
+	var moves []compactMove
+	if c.PlayedThisTurn >= c.MinPlaysToEndTurn() {
+		moves = append(moves, compactMove{card: -1})
+	}
+	for i, card := range c.Hands[c.Current] {
+		for p := range c.Tops {
+			if c.CanPlay(card, p) {
+				moves = append(moves, compactMove{card: i, pile: p})
+			}
+		}
+	}
+	return moves
+}
+
+// choose simulates every candidate on fresh deals and returns the one that
+// leaves the fewest cards on average.
+func (s *MonteCarloStrategy) choose(ctx context.Context, d *determinizer, candidates []compactMove) (compactMove, error) {
+	var deadline time.Time
+	if s.MoveBudget > 0 {
+		deadline = time.Now().Add(s.MoveBudget)
+	}
+	totals := make([]int, len(candidates))
+	for n := 0; n == 0 || n < s.Rollouts; n++ {
+		if err := ctx.Err(); err != nil {
+			return compactMove{}, err
+		}
+		if n > 0 && !deadline.IsZero() && time.Now().After(deadline) {
+			break
+		}
+		deal := d.sample(s.rng)
+		for i, move := range candidates {
+			c := deal.Clone()
+			if move.card < 0 {
+				if err := c.EndTurn(); err != nil {
+					return compactMove{}, err
+				}
+			} else {
+				c.Play(move.card, move.pile)
+			}
+			totals[i] += rollout(c)
+		}
+	}
+
+	best := 0
+	for i := range candidates {
+		if totals[i] < totals[best] {
+			best = i
+		}
+	}
+	return candidates[best], nil
+}
+
+// rollout plays c out with the default policy and returns the cards left.
+// The policy plays back-jumps, then the smallest jumps, until the turn's
+// minimum is met, and after that only back-jumps and jumps of rolloutMaxJump.
+func rollout(c *game.Compact) int {
+	for !c.IsOver() {
+		card, pile, jump := rolloutMove(c)
+		if card >= 0 && (c.PlayedThisTurn < c.MinPlaysToEndTurn() || jump <= rolloutMaxJump) {
+			c.Play(card, pile)
+			continue
+		}
+		if c.EndTurn() != nil {
+			// The player is stuck short of the minimum: the game is lost.
+			break
+		}
+	}
+	return c.CardsRemaining()
+}
+
+// rolloutMove returns the current player's cheapest play: a back-jump if there
+// is one, otherwise the smallest jump. A back-jump costs a jump of -1. card is
+// -1 if nothing can be played.
+func rolloutMove(c *game.Compact) (card, pile int, jump int32) {
+	card = -1
+	for i, v := range c.Hands[c.Current] {
+		for p, top := range c.Tops {
+			if !c.CanPlay(v, p) {
+				continue
+			}
+			j := v - top
+			if !c.Ascending[p] {
+				j = top - v
+			}
+			if j < 0 {
+				j = -1
+			}
+			if card < 0 || j < jump {
+				card, pile, jump = i, p, j
+			}
+		}
+	}
+	return card, pile, jump
+}
+
+// determinizer deals the cards a player cannot see.
+type determinizer struct {
+	// base is the game as the player sees it, with the hidden cards zero.
+	base *game.Compact
+	// hidden holds the seats whose hands are hidden.
+	hidden []int
+	// deckHidden is whether the order of the deck is unknown.
+	deckHidden bool
+	// unseen holds the cards that are in a hidden hand or the deck.
+	unseen []int32
+}
+
+func newDeterminizer(view *View) (*determinizer, error) {
+	// The rollouts are dealt around the player's own hand.
+	if _, ok := view.State.GetHands()[view.PlayerID]; !ok {
+		return nil, fmt.Errorf("the view holds no hand for player %s", view.PlayerID)
+	}
+	state := proto.Clone(view.State).(*pb.GameState)
+	d := &determinizer{unseen: NewKnowledge(state, view.PlayerID).UnseenCards()}
+
+	places := 0
+	for i, id := range state.GetPlayerIds() {
+		if _, ok := state.Hands[id]; ok {
+			continue
+		}
+		size := int(state.HandSizes[id])
+		state.Hands[id] = &pb.Hand{Cards: make([]*pb.Card, size)}
+		d.hidden = append(d.hidden, i)
+		places += size
+	}
+	if len(state.Deck) != int(state.DeckSize) {
+		state.Deck = make([]*pb.Card, state.DeckSize)
+		d.deckHidden = true
+		places += int(state.DeckSize)
+	}
+	if len(d.unseen) != places {
+		return nil, fmt.Errorf("%d cards are unseen but %d are hidden", len(d.unseen), places)
+	}
+	base, err := game.NewCompact(state)
+	if err != nil {
+		return nil, err
+	}
+	d.base = base
+	return d, nil
+}
+
+// sample returns a copy of the game with the hidden cards dealt at random.
+func (d *determinizer) sample(rng *rand.Rand) *game.Compact {
+	c := d.base.Clone()
+	rng.Shuffle(len(d.unseen), func(i, j int) { d.unseen[i], d.unseen[j] = d.unseen[j], d.unseen[i] })
+	next := 0
+	for _, seat := range d.hidden {
+		next += copy(c.Hands[seat], d.unseen[next:])
+	}
+	if d.deckHidden {
+		copy(c.Deck, d.unseen[next:])
+	}
+	return c
+}
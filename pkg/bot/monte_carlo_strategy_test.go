@@ -0,0 +1,82 @@
+package bot
+
+import (
+	"context"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"the_game_card_game/pkg/game"
+	pb "the_game_card_game/proto"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMonteCarloStrategy_PlansLegalTurns(t *testing.T) {
+	state := startedGame(t, 7)
+	s := &MonteCarloStrategy{Rollouts: 8, Seed: 1}
+
+	for turn := 0; turn < 3 && !game.IsOver(state); turn++ {
+		moves, err := s.PlanTurn(context.Background(), &View{PlayerID: "p1", State: game.PlayerView(state, "p1")})
+		require.NoError(t, err)
+		for _, move := range moves {
+			state, err = game.PlayCard(state, "p1", move.Card.Value, move.Pile)
+			require.NoError(t, err)
+		}
+		state, err = game.EndTurn(state, "p1")
+		require.NoError(t, err)
+	}
+}
+
+func TestMonteCarloStrategy_IsReproducible(t *testing.T) {
+	view := &View{PlayerID: "p1", State: game.PlayerView(startedGame(t, 7), "p1")}
+
+	first, err := (&MonteCarloStrategy{Rollouts: 8, Seed: 3}).PlanTurn(context.Background(), view)
+	require.NoError(t, err)
+	second, err := (&MonteCarloStrategy{Rollouts: 8, Seed: 3}).PlanTurn(context.Background(), view)
+	require.NoError(t, err)
+	require.Equal(t, first, second)
+}
+
+func TestMonteCarloStrategy_StopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	view := &View{PlayerID: "p1", State: game.PlayerView(startedGame(t, 7), "p1")}
+
+	_, err := NewMonteCarloStrategy().PlanTurn(ctx, view)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestDeterminizer_DealsTheUnseenCards(t *testing.T) {
+	state := game.NewSeededGame("bot-game", "p1", 5)
+	state, err := game.AddPlayer(state, "p2")
+	require.NoError(t, err)
+	state, err = game.StartGame(state)
+	require.NoError(t, err)
+	view := &View{PlayerID: "p1", State: game.PlayerView(state, "p1")}
+
+	d, err := newDeterminizer(view)
+	require.NoError(t, err)
+	require.Len(t, d.unseen, len(state.Hands["p2"].Cards)+len(state.Deck))
+	require.Nil(t, view.State.Deck, "the view is left as it was")
+
+	c := d.sample(rand.New(rand.NewSource(1)))
+	require.Equal(t, cardValues(state.Hands["p1"].Cards), c.Hands[0], "the player's own hand is known")
+	require.Len(t, c.Hands[1], len(state.Hands["p2"].Cards))
+	require.Len(t, c.Deck, len(state.Deck))
+
+	// Between them, the other hand and the deck hold exactly the hidden cards.
+	dealt := append(append([]int32(nil), c.Hands[1]...), c.Deck...)
+	hidden := append(cardValues(state.Hands["p2"].Cards), cardValues(state.Deck)...)
+	sort.Slice(dealt, func(i, j int) bool { return dealt[i] < dealt[j] })
+	sort.Slice(hidden, func(i, j int) bool { return hidden[i] < hidden[j] })
+	require.Equal(t, hidden, dealt)
+}
+
+func cardValues(cards []*pb.Card) []int32 {
+	values := make([]int32, len(cards))
+	for i, c := range cards {
+		values[i] = c.Value
+	}
+	return values
+}
@@ -7,19 +7,42 @@ import (
 	pb "the_game_card_game/proto"
 )
 
-type PhasedStrategy struct{}
+// PhasedStrategy adapts to the phase of the game, judged by the size of the
+// deck. Early on it gets rid of extreme cards, in the middle game it plays
+// cards from the middle of the range, and late in the game it plays the
+// smallest jump it can. Back-jumps are always played first.
+type PhasedStrategy struct {
+	// EarlyDeckSize and MidDeckSize are the deck sizes above which the game
+	// is in its early and middle phases.
+	EarlyDeckSize int32
+	MidDeckSize   int32
+	// Cards below ExtremeCardMin or above ExtremeCardMax are extreme.
+	ExtremeCardMin int32
+	ExtremeCardMax int32
+	// Cards from MidCardMin to MidCardMax are middle cards.
+	MidCardMin int32
+	MidCardMax int32
+}
 
+// NewPhasedStrategy creates a PhasedStrategy with the default thresholds.
 func NewPhasedStrategy() *PhasedStrategy {
-	return &PhasedStrategy{}
+	return &PhasedStrategy{
+		EarlyDeckSize:  defaultEarlyGameDeckSize,
+		MidDeckSize:    defaultMidGameDeckSize,
+		ExtremeCardMin: defaultExtremeCardMin,
+		ExtremeCardMax: defaultExtremeCardMax,
+		MidCardMin:     defaultMidCardMin,
+		MidCardMax:     defaultMidCardMax,
+	}
 }
 
 const (
-	earlyGameDeckSize = 65
-	midGameDeckSize   = 30
-	extremeCardMin    = 20
-	extremeCardMax    = 80
-	midCardMin        = 40
-	midCardMax        = 60
+	defaultEarlyGameDeckSize = 65
+	defaultMidGameDeckSize   = 30
+	defaultExtremeCardMin    = 20
+	defaultExtremeCardMax    = 80
+	defaultMidCardMin        = 40
+	defaultMidCardMax        = 60
 )
 
 type scoredMove struct {
@@ -40,7 +63,7 @@ func (s *PhasedStrategy) GetNextMove(playerID string, gameState *pb.GameState) (
 		pile := gameState.Piles[move.Pile]
 		topCard := pile.Cards[len(pile.Cards)-1].Value
 		cardValue := move.Card.Value
-		isTen := (pile.Ascending && cardValue == topCard-10) || (!pile.Ascending && cardValue == topCard+10)
+		isTen := game.IsBackJump(gameState, pile, cardValue)
 		scoredMoves = append(scoredMoves, scoredMove{
 			move:      move,
 			isTenJump: isTen,
@@ -78,15 +101,15 @@ func (s *PhasedStrategy) GetNextMove(playerID string, gameState *pb.GameState) (
 	var phaseFilteredMoves []scoredMove
 
 	switch {
-	case deckSize > earlyGameDeckSize: // Early Game
+	case deckSize > s.EarlyDeckSize: // Early Game
 		for _, sm := range forwardMoves {
-			if sm.move.Card.Value < extremeCardMin || sm.move.Card.Value > extremeCardMax {
+			if sm.move.Card.Value < s.ExtremeCardMin || sm.move.Card.Value > s.ExtremeCardMax {
 				phaseFilteredMoves = append(phaseFilteredMoves, sm)
 			}
 		}
-	case deckSize > midGameDeckSize: // Mid Game
+	case deckSize > s.MidDeckSize: // Mid Game
 		for _, sm := range forwardMoves {
-			if sm.move.Card.Value >= midCardMin && sm.move.Card.Value <= midCardMax {
+			if sm.move.Card.Value >= s.MidCardMin && sm.move.Card.Value <= s.MidCardMax {
 				phaseFilteredMoves = append(phaseFilteredMoves, sm)
 			}
 		}
@@ -95,7 +118,7 @@ func (s *PhasedStrategy) GetNextMove(playerID string, gameState *pb.GameState) (
 	movesToConsider := forwardMoves
 	if len(phaseFilteredMoves) > 0 {
 		movesToConsider = phaseFilteredMoves
-	} else if deckSize <= midGameDeckSize { // Late game, consider all forward moves
+	} else if deckSize <= s.MidDeckSize { // Late game, consider all forward moves
 		movesToConsider = forwardMoves
 	}
 
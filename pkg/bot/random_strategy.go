@@ -15,8 +15,14 @@ type RandomStrategy struct {
 
 // NewRandomStrategy creates a new RandomStrategy.
 func NewRandomStrategy() *RandomStrategy {
+	return NewSeededRandomStrategy(time.Now().UnixNano())
+}
+
+// NewSeededRandomStrategy creates a RandomStrategy whose choices are
+// reproducible from seed.
+func NewSeededRandomStrategy(seed int64) *RandomStrategy {
 	return &RandomStrategy{
-		r: rand.New(rand.NewSource(time.Now().UnixNano())),
+		r: rand.New(rand.NewSource(seed)),
 	}
 }
 
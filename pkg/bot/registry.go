@@ -0,0 +1,284 @@
+package bot
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ParamType is the type of a strategy parameter.
+type ParamType int
+
+const (
+	ParamInt ParamType = iota
+	ParamFloat
+	ParamBool
+	ParamString
+)
+
+func (t ParamType) String() string {
+	switch t {
+	case ParamInt:
+		return "int"
+	case ParamFloat:
+		return "float"
+	case ParamBool:
+		return "bool"
+	case ParamString:
+		return "string"
+	}
+	return fmt.Sprintf("ParamType(%d)", int(t))
+}
+
+// parse converts s to a value of type t.
+func (t ParamType) parse(s string) (interface{}, error) {
+	switch t {
+	case ParamInt:
+		return strconv.Atoi(s)
+	case ParamFloat:
+		return strconv.ParseFloat(s, 64)
+	case ParamBool:
+		return strconv.ParseBool(s)
+	case ParamString:
+		return s, nil
+	}
+	return nil, fmt.Errorf("unknown parameter type %s", t)
+}
+
+// accepts reports whether v holds a value of type t.
+func (t ParamType) accepts(v interface{}) bool {
+	var ok bool
+	switch t {
+	case ParamInt:
+		_, ok = v.(int)
+	case ParamFloat:
+		_, ok = v.(float64)
+	case ParamBool:
+		_, ok = v.(bool)
+	case ParamString:
+		_, ok = v.(string)
+	}
+	return ok
+}
+
+// Param describes a parameter a strategy can be built with.
+type Param struct {
+	Name        string
+	Type        ParamType
+	Default     interface{} // Must be an int, float64, bool or string to match Type.
+	Description string
+}
+
+// Params holds the value of every parameter of a strategy, defaults included.
+// The accessors panic if the strategy did not declare the parameter with
+// that type, which is a mistake in the strategy's definition.
+type Params struct {
+	values map[string]interface{}
+}
+
+func (p Params) Int(name string) int           { return p.values[name].(int) }
+func (p Params) Float(name string) float64     { return p.values[name].(float64) }
+func (p Params) Bool(name string) bool         { return p.values[name].(bool) }
+func (p Params) String(name string) string     { return p.values[name].(string) }
+func (p Params) value(name string) interface{} { return p.values[name] }
+
+// Definition describes a strategy that can be built by name.
+type Definition struct {
+	Name        string
+	Description string
+	Params      []Param
+	// New builds a strategy. Every declared parameter has a value in params.
+	New func(params Params) (TurnStrategy, error)
+}
+
+// Spec is a strategy definition together with the values of its parameters.
+// Its string form, such as "phased:early=60,mid=25", is how strategies are
+// chosen on command lines and in requests.
+type Spec struct {
+	Definition Definition
+	Params     Params
+}
+
+// String returns the spec with the value of every parameter, in the order the
+// definition declares them.
+func (s Spec) String() string {
+	if len(s.Definition.Params) == 0 {
+		return s.Definition.Name
+	}
+	args := make([]string, len(s.Definition.Params))
+	for i, param := range s.Definition.Params {
+		v := s.Params.value(param.Name)
+		if str, ok := v.(string); ok && strings.ContainsAny(str, `,"`) {
+			v = strconv.Quote(str)
+		}
+		args[i] = fmt.Sprintf("%s=%v", param.Name, v)
+	}
+	return s.Definition.Name + ":" + strings.Join(args, ",")
+}
+
+// New builds the strategy the spec describes, wrapped by Checked.
+func (s Spec) New() (TurnStrategy, error) {
+	strategy, err := s.Definition.New(s.Params)
+	if err != nil {
+		return nil, err
+	}
+	return Checked(strategy), nil
+}
+
+// Registry maps strategy names to their definitions. It is safe for
+// concurrent use.
+type Registry struct {
+	mu          sync.RWMutex
+	definitions map[string]Definition
+}
+
+// NewRegistry returns an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{definitions: make(map[string]Definition)}
+}
+
+// DefaultRegistry holds the strategies built into this package. Commands and
+// the server resolve strategy specs through it.
+var DefaultRegistry = NewRegistry()
+
+// Register adds def to the registry. Names must be unique, and parameter
+// defaults must match their declared types.
+func (r *Registry) Register(def Definition) error {
+	if def.Name == "" || strings.ContainsAny(def.Name, ":,=") {
+		return fmt.Errorf("invalid strategy name %q", def.Name)
+	}
+	if def.New == nil {
+		return fmt.Errorf("strategy %s has no constructor", def.Name)
+	}
+	seen := make(map[string]bool, len(def.Params))
+	for _, param := range def.Params {
+		if seen[param.Name] {
+			return fmt.Errorf("strategy %s declares parameter %s twice", def.Name, param.Name)
+		}
+		seen[param.Name] = true
+		if !param.Type.accepts(param.Default) {
+			return fmt.Errorf("strategy %s: default of %s is not a valid %s", def.Name, param.Name, param.Type)
+		}
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.definitions[def.Name]; ok {
+		return fmt.Errorf("strategy %s is already registered", def.Name)
+	}
+	r.definitions[def.Name] = def
+	return nil
+}
+
+// MustRegister is like Register but panics if def cannot be registered.
+func (r *Registry) MustRegister(def Definition) {
+	if err := r.Register(def); err != nil {
+		panic(err)
+	}
+}
+
+// Definitions returns every registered definition, sorted by name.
+func (r *Registry) Definitions() []Definition {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	defs := make([]Definition, 0, len(r.definitions))
+	for _, def := range r.definitions {
+		defs = append(defs, def)
+	}
+	sort.Slice(defs, func(i, j int) bool { return defs[i].Name < defs[j].Name })
+	return defs
+}
+
+// Parse resolves a spec of the form "name" or "name:key=value,key=value".
+// Parameters that are not given take their defaults. A string value that
+// holds a comma is written in double quotes, as a Go string literal, such as
+// file="runs/a,b.json".
+func (r *Registry) Parse(spec string) (Spec, error) {
+	name, args, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	r.mu.RLock()
+	def, ok := r.definitions[name]
+	r.mu.RUnlock()
+	if !ok {
+		return Spec{}, fmt.Errorf("unknown strategy %q", name)
+	}
+
+	values := make(map[string]interface{}, len(def.Params))
+	types := make(map[string]ParamType, len(def.Params))
+	for _, param := range def.Params {
+		values[param.Name] = param.Default
+		types[param.Name] = param.Type
+	}
+	if args != "" {
+		for _, arg := range splitArgs(args) {
+			key, value, ok := strings.Cut(arg, "=")
+			key = strings.TrimSpace(key)
+			if !ok || key == "" {
+				return Spec{}, fmt.Errorf("strategy %s: parameter %q is not of the form key=value", name, arg)
+			}
+			t, ok := types[key]
+			if !ok {
+				return Spec{}, fmt.Errorf("strategy %s has no parameter %q", name, key)
+			}
+			value = strings.TrimSpace(value)
+			if t == ParamString && strings.HasPrefix(value, `"`) {
+				unquoted, err := strconv.Unquote(value)
+				if err != nil {
+					return Spec{}, fmt.Errorf("strategy %s: parameter %s is not a valid quoted string: %w", name, key, err)
+				}
+				value = unquoted
+			}
+			v, err := t.parse(value)
+			if err != nil {
+				return Spec{}, fmt.Errorf("strategy %s: parameter %s must be a %s: %w", name, key, t, err)
+			}
+			values[key] = v
+		}
+	}
+	return Spec{Definition: def, Params: Params{values: values}}, nil
+}
+
+// splitArgs splits the parameters of a spec at the commas that are not within
+// a quoted value.
+func splitArgs(args string) []string {
+	var parts []string
+	quoted, start := false, 0
+	for i := 0; i < len(args); i++ {
+		switch args[i] {
+		case '\\':
+			if quoted {
+				i++ // Skip the escaped character.
+			}
+		case '"':
+			quoted = !quoted
+		case ',':
+			if !quoted {
+				parts = append(parts, args[start:i])
+				start = i + 1
+			}
+		}
+	}
+	return append(parts, args[start:])
+}
+
+// New builds the strategy described by spec.
+func (r *Registry) New(spec string) (TurnStrategy, error) {
+	s, err := r.Parse(spec)
+	if err != nil {
+		return nil, err
+	}
+	return s.New()
+}
+
+// Describe writes every registered strategy and its parameters to w, for use
+// in command-line help.
+func (r *Registry) Describe(w io.Writer) {
+	for _, def := range r.Definitions() {
+		fmt.Fprintf(w, "%s\n    %s\n", def.Name, def.Description)
+		for _, param := range def.Params {
+			fmt.Fprintf(w, "    %s (%s, default %v): %s\n", param.Name, param.Type, param.Default, param.Description)
+		}
+	}
+}
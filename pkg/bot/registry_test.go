@@ -0,0 +1,94 @@
+package bot
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegistry_ParsesSpecs(t *testing.T) {
+	spec, err := DefaultRegistry.Parse("phased:early=60, mid=25")
+	require.NoError(t, err)
+	require.Equal(t, "phased:early=60,mid=25,extreme_min=20,extreme_max=80,mid_min=40,mid_max=60", spec.String())
+
+	strategy, err := spec.New()
+	require.NoError(t, err)
+	phased := strategy.(*checkedStrategy).s.(*strategyAdapter).s.(*PhasedStrategy)
+	require.Equal(t, int32(60), phased.EarlyDeckSize)
+	require.Equal(t, int32(25), phased.MidDeckSize)
+	require.Equal(t, int32(defaultExtremeCardMin), phased.ExtremeCardMin)
+
+	// A spec is its own canonical form.
+	again, err := DefaultRegistry.Parse(spec.String())
+	require.NoError(t, err)
+	require.Equal(t, spec.String(), again.String())
+
+	smart, err := DefaultRegistry.Parse("smart")
+	require.NoError(t, err)
+	require.Equal(t, "smart", smart.String())
+}
+
+func TestRegistry_ParsesQuotedValues(t *testing.T) {
+	spec, err := DefaultRegistry.Parse(`weighted:file="runs/a,b \"best\".json", jump=2`)
+	require.NoError(t, err)
+	require.Equal(t, `runs/a,b "best".json`, spec.Params.String("file"))
+	require.Equal(t, 2.0, spec.Params.Float("jump"))
+
+	// The canonical form quotes the value again.
+	again, err := DefaultRegistry.Parse(spec.String())
+	require.NoError(t, err)
+	require.Equal(t, spec.String(), again.String())
+	require.Equal(t, `runs/a,b "best".json`, again.Params.String("file"))
+}
+
+func TestRegistry_RejectsBadSpecs(t *testing.T) {
+	for _, spec := range []string{
+		"unknown",
+		"phased:late=10",
+		"phased:early=sixty",
+		"phased:early",
+		"smart:depth=2",
+		`weighted:file="unterminated`,
+	} {
+		_, err := DefaultRegistry.Parse(spec)
+		require.Error(t, err, spec)
+	}
+}
+
+func TestRegistry_Register(t *testing.T) {
+	r := NewRegistry()
+	def := Definition{
+		Name:   "custom",
+		Params: []Param{{Name: "weight", Type: ParamFloat, Default: 0.5}},
+		New: func(p Params) (TurnStrategy, error) {
+			return AdaptStrategy(NewSmartStrategy()), nil
+		},
+	}
+	require.NoError(t, r.Register(def))
+	require.Error(t, r.Register(def), "names must be unique")
+
+	badDefault := def
+	badDefault.Name = "bad-default"
+	badDefault.Params = []Param{{Name: "weight", Type: ParamFloat, Default: 1}}
+	require.Error(t, r.Register(badDefault), "an int default is not a float")
+
+	spec, err := r.Parse("custom:weight=2.5")
+	require.NoError(t, err)
+	require.Equal(t, 2.5, spec.Params.Float("weight"))
+
+	var help bytes.Buffer
+	r.Describe(&help)
+	require.Contains(t, help.String(), "weight (float, default 0.5)")
+}
+
+func TestDefaultRegistry_BuildsEveryStrategy(t *testing.T) {
+	for _, def := range DefaultRegistry.Definitions() {
+		if def.Name == "external" {
+			continue // Needs a program to run; see TestExternalStrategy_Registered.
+		}
+		strategy, err := DefaultRegistry.New(def.Name)
+		require.NoError(t, err, def.Name)
+		require.NotNil(t, strategy, def.Name)
+	}
+}
@@ -27,10 +27,9 @@ func (s *SafeTenStrategy) GetNextMove(playerID string, gameState *pb.GameState)
 	// Prioritize "10-back" moves.
 	for _, move := range possibleMoves {
 		pile := gameState.Piles[move.Pile]
-		topCard := pile.Cards[len(pile.Cards)-1]
 		cardValue := move.Card.Value
 
-		if (pile.Ascending && cardValue == topCard.Value-10) || (!pile.Ascending && cardValue == topCard.Value+10) {
+		if game.IsBackJump(gameState, pile, cardValue) {
 			return &pb.PlayCardRequest{
 				GameId:   gameState.GameId,
 				PlayerId: playerID,
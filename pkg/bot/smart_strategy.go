@@ -31,10 +31,9 @@ func (s *SmartStrategy) GetNextMove(playerID string, gameState *pb.GameState) (*
 
 	for _, move := range possibleMoves {
 		pile := gameState.Piles[move.Pile]
-		topCard := pile.Cards[len(pile.Cards)-1]
 		cardValue := move.Card.Value
 
-		isTenRule := (pile.Ascending && cardValue == topCard.Value-10) || (!pile.Ascending && cardValue == topCard.Value+10)
+		isTenRule := game.IsBackJump(gameState, pile, cardValue)
 
 		if isTenRule {
 			tenMoves = append(tenMoves, move)
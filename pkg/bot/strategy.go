@@ -4,7 +4,9 @@ import (
 	pb "the_game_card_game/proto"
 )
 
-// Strategy defines the interface for a game-playing bot.
+// Strategy defines the interface for a game-playing bot that decides one move
+// at a time. Bots are driven through TurnStrategy; AdaptStrategy wraps a
+// Strategy to plan whole turns.
 type Strategy interface {
 	// GetNextMove determines the next move for the bot to make.
 	GetNextMove(playerID string, gameState *pb.GameState) (*pb.PlayCardRequest, *pb.EndTurnRequest, error)
@@ -0,0 +1,140 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"the_game_card_game/pkg/game"
+	pb "the_game_card_game/proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// Turn is a completed turn: the cards a player played, in the order they were
+// played, before ending it.
+type Turn struct {
+	Number   int32
+	PlayerID string
+	Moves    []game.Move
+}
+
+// View is what a strategy knows when it plans a turn.
+type View struct {
+	// PlayerID is the player the strategy plays for.
+	PlayerID string
+	// State is the player's view of the game: the deck and the other
+	// players' hands are hidden, as they are from a player at the table.
+	State *pb.GameState
+	// History holds the turns completed so far that the strategy's driver has
+	// seen, oldest first. It is shared and must not be modified.
+	History []Turn
+}
+
+// TurnStrategy plans a player's turns a whole turn at a time.
+type TurnStrategy interface {
+	// PlanTurn returns the cards to play this turn, in order; the turn ends
+	// once they have been played. It should give up when ctx is done.
+	PlanTurn(ctx context.Context, view *View) ([]game.Move, error)
+}
+
+// GameObserver is implemented by turn strategies that want to know when a game
+// starts and ends, for example to set up or discard what they have learnt
+// about it. A strategy may be used for many games, one after another.
+type GameObserver interface {
+	// GameStarted is called before the player's first turn. An error stops
+	// the strategy from playing the game.
+	GameStarted(ctx context.Context, view *View) error
+	// GameOver is called once the game has finished, whatever the outcome.
+	GameOver(ctx context.Context, view *View)
+}
+
+// AdaptStrategy turns a Strategy into a TurnStrategy. The turn is planned by
+// asking s for one move at a time, applying each move to a copy of the
+// player's view, until s ends the turn.
+func AdaptStrategy(s Strategy) TurnStrategy {
+	return &strategyAdapter{s: s}
+}
+
+type strategyAdapter struct {
+	s Strategy
+}
+
+func (a *strategyAdapter) PlanTurn(ctx context.Context, view *View) ([]game.Move, error) {
+	state := proto.Clone(view.State).(*pb.GameState)
+	var moves []game.Move
+	for !game.IsOver(state) {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		playReq, _, err := a.s.GetNextMove(view.PlayerID, state)
+		if err != nil {
+			return nil, err
+		}
+		if playReq == nil {
+			break
+		}
+		next, err := game.PlayCard(state, view.PlayerID, playReq.GetCard().GetValue(), playReq.GetPileId())
+		if err != nil {
+			return nil, fmt.Errorf("strategy chose an illegal move: %w", err)
+		}
+		moves = append(moves, game.Move{Card: playReq.GetCard(), Pile: playReq.GetPileId()})
+		state = next
+	}
+	return moves, nil
+}
+
+// Checked wraps s so that it is never asked to plan a turn the player cannot
+// take: one in a game that is over or not started, out of turn, or in a state
+// that cannot arise in play, such as a pile without its starting card. Such a
+// turn is refused with the error from game.ValidateTurn, so strategies need
+// not check for themselves. Strategies built by a Registry are checked.
+func Checked(s TurnStrategy) TurnStrategy {
+	if c, ok := s.(*checkedStrategy); ok {
+		return c
+	}
+	return &checkedStrategy{s: s}
+}
+
+// Unchecked returns the strategy s wraps if it was wrapped by Checked, and s
+// otherwise, so that tests can reach the strategy itself.
+func Unchecked(s TurnStrategy) TurnStrategy {
+	if c, ok := s.(*checkedStrategy); ok {
+		return c.s
+	}
+	return s
+}
+
+type checkedStrategy struct {
+	s TurnStrategy
+}
+
+func (c *checkedStrategy) PlanTurn(ctx context.Context, view *View) ([]game.Move, error) {
+	if err := game.ValidateTurn(view.State, view.PlayerID); err != nil {
+		return nil, err
+	}
+	return c.s.PlanTurn(ctx, view)
+}
+
+// GameStarted implements GameObserver for strategies that observe games.
+func (c *checkedStrategy) GameStarted(ctx context.Context, view *View) error {
+	if o, ok := c.s.(GameObserver); ok {
+		return o.GameStarted(ctx, view)
+	}
+	return nil
+}
+
+// GameOver implements GameObserver for strategies that observe games.
+func (c *checkedStrategy) GameOver(ctx context.Context, view *View) {
+	if o, ok := c.s.(GameObserver); ok {
+		o.GameOver(ctx, view)
+	}
+}
+
+// Close stops strategies that hold resources, such as ExternalStrategy.
+func (c *checkedStrategy) Close() error {
+	if closer, ok := c.s.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+package bot
+
+import (
+	"context"
+	"testing"
+
+	"the_game_card_game/pkg/game"
+	pb "the_game_card_game/proto"
+
+	"github.com/stretchr/testify/require"
+)
+
+// startedGame returns a started solo game for player "p1".
+func startedGame(t *testing.T, seed int64) *pb.GameState {
+	t.Helper()
+	state, err := game.StartGame(game.NewSeededGame("bot-game", "p1", seed))
+	require.NoError(t, err)
+	return state
+}
+
+func TestAdaptStrategy_PlansTheMovesOfATurn(t *testing.T) {
+	state := startedGame(t, 3)
+	view := &View{PlayerID: "p1", State: game.PlayerView(state, "p1")}
+
+	moves, err := AdaptStrategy(NewTwoCardGreedyStrategy()).PlanTurn(context.Background(), view)
+	require.NoError(t, err)
+	require.Len(t, moves, 2, "the greedy strategy plays the minimum")
+
+	// The plan is made on a copy; the view is left as it was.
+	require.Zero(t, view.State.CardsPlayedThisTurn)
+
+	// The same moves, asked for one at a time, are legal in the real game.
+	for _, move := range moves {
+		state, err = game.PlayCard(state, "p1", move.Card.Value, move.Pile)
+		require.NoError(t, err)
+	}
+	_, err = game.EndTurn(state, "p1")
+	require.NoError(t, err)
+}
+
+func TestAdaptStrategy_StopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	view := &View{PlayerID: "p1", State: game.PlayerView(startedGame(t, 3), "p1")}
+
+	_, err := AdaptStrategy(NewSmartStrategy()).PlanTurn(ctx, view)
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+// illegalStrategy always plays a card that is not in the player's hand.
+type illegalStrategy struct{}
+
+func (illegalStrategy) GetNextMove(playerID string, gameState *pb.GameState) (*pb.PlayCardRequest, *pb.EndTurnRequest, error) {
+	return &pb.PlayCardRequest{PlayerId: playerID, Card: &pb.Card{Value: 1}, PileId: "up1"}, nil, nil
+}
+
+func TestAdaptStrategy_RejectsIllegalMoves(t *testing.T) {
+	view := &View{PlayerID: "p1", State: game.PlayerView(startedGame(t, 3), "p1")}
+
+	_, err := AdaptStrategy(illegalStrategy{}).PlanTurn(context.Background(), view)
+	require.Error(t, err)
+}
+
+func TestChecked_RefusesTurnsThePlayerCannotTake(t *testing.T) {
+	state := startedGame(t, 3)
+	strategy := Checked(AdaptStrategy(NewTwoCardGreedyStrategy()))
+	require.Same(t, strategy, Checked(strategy), "a checked strategy is not checked twice")
+
+	_, err := strategy.PlanTurn(context.Background(), &View{PlayerID: "p2", State: game.PlayerView(state, "p2")})
+	require.ErrorContains(t, err, "not p2's turn")
+
+	state.Piles["up1"].Cards = nil
+	_, err = strategy.PlanTurn(context.Background(), &View{PlayerID: "p1", State: game.PlayerView(state, "p1")})
+	require.ErrorContains(t, err, "pile up1 has no cards")
+}
@@ -17,10 +17,10 @@ func NewTwoCardGreedyStrategy() *TwoCardGreedyStrategy {
 }
 
 func (s *TwoCardGreedyStrategy) GetNextMove(playerID string, gameState *pb.GameState) (*pb.PlayCardRequest, *pb.EndTurnRequest, error) {
-	// If we've already played 2 or more cards, end the turn.
-	// The server requires a minimum of 2 cards to be played (or 1 if the deck is empty),
-	// so this strategy will always satisfy the minimum requirement before ending the turn.
-	if gameState.GetCardsPlayedThisTurn() >= 2 {
+	// Once the rules' minimum has been played, end the turn.
+	// The server requires a minimum of 2 cards to be played (or 1 if the deck is empty)
+	// under the standard rules, so this strategy always satisfies the requirement.
+	if gameState.GetCardsPlayedThisTurn() >= game.MinPlaysToEndTurn(gameState) {
 		return nil, &pb.EndTurnRequest{GameId: gameState.GameId, PlayerId: playerID}, nil
 	}
 
@@ -0,0 +1,175 @@
+package bot
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"the_game_card_game/pkg/game"
+	pb "the_game_card_game/proto"
+)
+
+// Weights are the weights of the features WeightedStrategy scores a play by.
+// The cost of a play is the sum of each feature times its weight, and the
+// cheapest play is made.
+type Weights struct {
+	// Jump weighs the distance the play moves the pile, negative for a
+	// back-jump.
+	Jump float64 `json:"jump"`
+	// LateJump weighs the same distance, scaled from 0 when the deck is full
+	// to 1 when it is empty.
+	LateJump float64 `json:"late_jump"`
+	// Skipped and SkippedHeld weigh the cards still to be played that the
+	// play puts out of the pile's reach: those in other hands or the deck,
+	// and those in the player's hand. A back-jump counts the cards it brings
+	// back within reach as negative.
+	Skipped     float64 `json:"skipped"`
+	SkippedHeld float64 `json:"skipped_held"`
+	// BackJump is added for a back-jump.
+	BackJump float64 `json:"back_jump"`
+	// BackJumpChance weighs the chance of a later back-jump on the pile that
+	// the play gives up, as estimated by Knowledge.ChanceAvailable.
+	BackJumpChance float64 `json:"back_jump_chance"`
+	// ExtraPlay is the highest cost of a play beyond the turn's minimum.
+	ExtraPlay float64 `json:"extra_play"`
+}
+
+// DefaultWeights returns weights that play like CountingStrategy.
+func DefaultWeights() Weights {
+	return Weights{
+		Skipped:        1,
+		SkippedHeld:    defaultCountingHeldWeight,
+		BackJumpChance: defaultCountingBackJumpValue,
+		ExtraPlay:      defaultCountingMaxExtraCost,
+	}
+}
+
+// WeightNames lists the weights in the order of Vector.
+var WeightNames = []string{"jump", "late_jump", "skipped", "skipped_held", "back_jump", "back_jump_chance", "extra_play"}
+
+// Vector returns the weights as a vector, for numerical search.
+func (w Weights) Vector() []float64 {
+	return []float64{w.Jump, w.LateJump, w.Skipped, w.SkippedHeld, w.BackJump, w.BackJumpChance, w.ExtraPlay}
+}
+
+// WeightsFromVector is the inverse of Weights.Vector.
+func WeightsFromVector(v []float64) Weights {
+	return Weights{
+		Jump:           v[0],
+		LateJump:       v[1],
+		Skipped:        v[2],
+		SkippedHeld:    v[3],
+		BackJump:       v[4],
+		BackJumpChance: v[5],
+		ExtraPlay:      v[6],
+	}
+}
+
+// WeightsFile is a parameter file for WeightedStrategy, as written by
+// cmd/tune. Besides the weights it records how they were measured.
+type WeightsFile struct {
+	Weights Weights `json:"weights"`
+
+	Players            int     `json:"players,omitempty"`
+	Games              int     `json:"games,omitempty"`
+	Seed               int64   `json:"seed,omitempty"`
+	MeanCardsRemaining float64 `json:"mean_cards_remaining,omitempty"`
+	WinRate            float64 `json:"win_rate,omitempty"`
+}
+
+// LoadWeightsFile reads a parameter file.
+func LoadWeightsFile(path string) (*WeightsFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &WeightsFile{}
+	if err := json.Unmarshal(data, f); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	return f, nil
+}
+
+// Save writes f to path.
+func (f *WeightsFile) Save(path string) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// WeightedStrategy plays the play whose features cost least under its
+// weights. Unlike the hand-tuned strategies, all of its judgement is in the
+// weights, so they can be tuned by search.
+type WeightedStrategy struct {
+	Weights Weights
+	// Draws is how many cards ahead the player looks for back-jump cards.
+	Draws int
+}
+
+// NewWeightedStrategy creates a WeightedStrategy with the given weights.
+func NewWeightedStrategy(w Weights) *WeightedStrategy {
+	return &WeightedStrategy{Weights: w, Draws: defaultCountingDraws}
+}
+
+// GetNextMove implements the Strategy interface for WeightedStrategy.
+func (s *WeightedStrategy) GetNextMove(playerID string, gameState *pb.GameState) (*pb.PlayCardRequest, *pb.EndTurnRequest, error) {
+	endTurn := &pb.EndTurnRequest{GameId: gameState.GameId, PlayerId: playerID}
+	possibleMoves := game.GetPossibleMoves(playerID, gameState)
+	if len(possibleMoves) == 0 {
+		return nil, endTurn, nil
+	}
+
+	rules := game.RulesOf(gameState)
+	k := NewKnowledge(gameState, playerID)
+	late := 1 - float64(gameState.DeckSize)/float64(rules.MaxCard-rules.MinCard+1)
+	best, bestCost := possibleMoves[0], 0.0
+	for i, move := range possibleMoves {
+		cost := s.cost(k, gameState.Piles[move.Pile], move.Card.Value, rules.GetBackJump(), late)
+		if i == 0 || cost < bestCost {
+			best, bestCost = move, cost
+		}
+	}
+
+	if gameState.CardsPlayedThisTurn >= game.MinPlaysToEndTurn(gameState) && bestCost > s.Weights.ExtraPlay {
+		return nil, endTurn, nil
+	}
+	return &pb.PlayCardRequest{
+		GameId:   gameState.GameId,
+		PlayerId: playerID,
+		Card:     best.Card,
+		PileId:   best.Pile,
+	}, nil, nil
+}
+
+// cost returns the cost of playing card on pile under the strategy's weights.
+func (s *WeightedStrategy) cost(k *Knowledge, pile *pb.Pile, card, backJump int32, late float64) float64 {
+	w := s.Weights
+	top := pile.Cards[len(pile.Cards)-1].Value
+	jump := float64(card - top)
+	if !pile.Ascending {
+		jump = -jump
+	}
+	others, held := k.Between(top, card)
+	skipped := w.Skipped*float64(others) + w.SkippedHeld*float64(held)
+	cost := w.Jump*jump + w.LateJump*late*jump
+	if jump < 0 {
+		cost += w.BackJump - skipped
+	} else {
+		cost += skipped
+	}
+
+	target := func(v int32) int32 {
+		if pile.Ascending {
+			return v - backJump
+		}
+		return v + backJump
+	}
+	before := k.ChanceAvailable(target(top), s.Draws)
+	if target(top) == card {
+		before = 0 // The back-jump is this play.
+	}
+	after := k.ChanceAvailable(target(card), s.Draws)
+	return cost + w.BackJumpChance*(before-after)
+}
@@ -0,0 +1,50 @@
+package bot
+
+import (
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWeightedStrategy_DefaultsPlayLikeCounting(t *testing.T) {
+	for _, hand := range [][]int32{{94, 25}, {94, 50}, {11, 91, 95}} {
+		for _, played := range []int32{0, 2} {
+			state := countingState(t, hand...)
+			state.CardsPlayedThisTurn = played
+
+			want, wantEnd, err := NewCountingStrategy().GetNextMove("p1", state)
+			require.NoError(t, err)
+			got, gotEnd, err := NewWeightedStrategy(DefaultWeights()).GetNextMove("p1", state)
+			require.NoError(t, err)
+			require.Equal(t, wantEnd == nil, gotEnd == nil, "hand %v", hand)
+			if want != nil {
+				require.Equal(t, want.Card.Value, got.Card.Value, "hand %v", hand)
+				require.Equal(t, want.PileId, got.PileId, "hand %v", hand)
+			}
+		}
+	}
+}
+
+func TestWeights_VectorRoundTrip(t *testing.T) {
+	w := Weights{Jump: 1, LateJump: 2, Skipped: 3, SkippedHeld: 4, BackJump: 5, BackJumpChance: 6, ExtraPlay: 7}
+	require.Len(t, WeightNames, len(w.Vector()))
+	require.Equal(t, w, WeightsFromVector(w.Vector()))
+}
+
+func TestWeightsFile_LoadsFromRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "tuned.json")
+	want := &WeightsFile{Weights: Weights{Jump: 0.5, Skipped: 1.5}, Games: 200, MeanCardsRemaining: 19.5}
+	require.NoError(t, want.Save(path))
+
+	got, err := LoadWeightsFile(path)
+	require.NoError(t, err)
+	require.Equal(t, want, got)
+
+	strategy, err := DefaultRegistry.New("weighted:file=" + path)
+	require.NoError(t, err)
+	require.Equal(t, want.Weights, strategy.(*checkedStrategy).s.(*strategyAdapter).s.(*WeightedStrategy).Weights)
+
+	_, err = DefaultRegistry.New("weighted:file=" + filepath.Join(t.TempDir(), "missing.json"))
+	require.Error(t, err)
+}
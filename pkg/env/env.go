@@ -0,0 +1,475 @@
+// Package env wraps the game rules as a reinforcement learning environment in
+// the style of OpenAI Gym: Reset deals a game, and Step applies one action of
+// the agent's and returns a reward. Observations and actions are vectors of a
+// fixed size, so that they can be fed straight to a learning agent.
+//
+// The agent plays as "p1". At a table of more than one, the other players are
+// played by bot strategies between the agent's turns.
+package env
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+
+	"the_game_card_game/pkg/bot"
+	"the_game_card_game/pkg/game"
+	pb "the_game_card_game/proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// AgentID is the player the agent plays as.
+const AgentID = "p1"
+
+var (
+	// ErrIllegalAction is returned by Step for an action the mask rules out.
+	ErrIllegalAction = errors.New("illegal action")
+	// ErrNotRunning is returned by Step before Reset, or once the game is over.
+	ErrNotRunning = errors.New("no game in progress; call Reset")
+)
+
+// Reward weighs what happens during a step. Each weight is multiplied by what
+// it counts and the products are added up, so penalties take negative
+// weights.
+type Reward struct {
+	// CardPlayed counts every card played at the table during the step: the
+	// agent's, and the other players' in the turns that follow the agent's.
+	CardPlayed float64 `json:"card_played"`
+	// BackJump counts the back-jumps the agent plays.
+	BackJump float64 `json:"back_jump"`
+	// Win and Loss are earned once, when the game is won or lost.
+	Win  float64 `json:"win"`
+	Loss float64 `json:"loss"`
+	// CardsRemaining counts the cards not played when the game ends.
+	CardsRemaining float64 `json:"cards_remaining"`
+}
+
+// DefaultReward returns a reward of one for every card played and a bonus
+// for winning.
+func DefaultReward() Reward {
+	return Reward{CardPlayed: 1, Win: 10}
+}
+
+// Config describes an environment.
+type Config struct {
+	// Rules are the rules every game is played with; nil means the standard
+	// rules.
+	Rules *game.RuleSet
+	// Players is the number of players at the table; zero means one.
+	Players int
+	// Opponent builds the strategy of each player other than the agent. It
+	// is needed when there is more than one player.
+	Opponent func() (bot.TurnStrategy, error)
+	// Reward weighs the reward of each step; the zero Reward means
+	// DefaultReward.
+	Reward Reward
+}
+
+// TimeStep is what the agent learns from Reset or Step.
+type TimeStep struct {
+	// Observation encodes the agent's view of the game, as described by
+	// Env.Observe.
+	Observation []float32 `json:"observation"`
+	// Mask holds, for each action, whether it is legal.
+	Mask []bool `json:"mask"`
+	// Reward is the reward earned by the step; zero after Reset.
+	Reward float64 `json:"reward"`
+	// Done is set once the game is over. Reset must be called to play on.
+	Done bool `json:"done"`
+	Info Info `json:"info"`
+}
+
+// Info describes the game, for logging rather than learning.
+type Info struct {
+	Status         string `json:"status"`
+	TurnNumber     int32  `json:"turn_number"`
+	CardsRemaining int    `json:"cards_remaining"`
+	// Move is the card the step played, or nil if it ended the turn.
+	Move *Move `json:"move,omitempty"`
+}
+
+// Move is a card played on a pile.
+type Move struct {
+	Card int32  `json:"card"`
+	Pile string `json:"pile"`
+}
+
+// Env is an environment. It is not safe for concurrent use; run one Env per
+// worker, and Close it when done.
+type Env struct {
+	rules     *game.RuleSet
+	players   int
+	reward    Reward
+	opponents []bot.TurnStrategy
+
+	pileIDs   []string
+	handSlots int
+
+	state   *pb.GameState
+	history []bot.Turn
+	turn    bot.Turn
+}
+
+// New creates an environment. Reset must be called before the first Step.
+func New(cfg Config) (*Env, error) {
+	if err := game.ValidateRuleSet(cfg.Rules); err != nil {
+		return nil, fmt.Errorf("invalid rules: %w", err)
+	}
+	rules := game.ResolveRuleSet(cfg.Rules)
+	players := cfg.Players
+	if players == 0 {
+		players = 1
+	}
+	if players < 1 || int32(players) > rules.MaxPlayers {
+		return nil, fmt.Errorf("between 1 and %d players can play, got %d", rules.MaxPlayers, players)
+	}
+	e := &Env{
+		rules:     rules,
+		players:   players,
+		reward:    cfg.Reward,
+		handSlots: game.HandSizeFor(rules, players),
+	}
+	if e.reward == (Reward{}) {
+		e.reward = DefaultReward()
+	}
+	for _, p := range rules.Piles {
+		e.pileIDs = append(e.pileIDs, p.Id)
+	}
+	if players > 1 && cfg.Opponent == nil {
+		return nil, fmt.Errorf("%d players need an opponent strategy", players)
+	}
+	for i := 1; i < players; i++ {
+		s, err := cfg.Opponent()
+		if err != nil {
+			return nil, fmt.Errorf("opponent: %w", err)
+		}
+		e.opponents = append(e.opponents, s)
+	}
+	return e, nil
+}
+
+// Close releases what the other players' strategies hold, such as the
+// programs external strategies run. The Env cannot be used afterwards.
+func (e *Env) Close() error {
+	var errs []error
+	for _, s := range e.opponents {
+		if c, ok := s.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// PileIDs returns the piles in the order observations and actions use.
+func (e *Env) PileIDs() []string {
+	return append([]string(nil), e.pileIDs...)
+}
+
+// HandSlots returns how many cards of the agent's hand an observation holds:
+// the most the agent can hold.
+func (e *Env) HandSlots() int {
+	return e.handSlots
+}
+
+// ObservationSize returns the length of every observation.
+func (e *Env) ObservationSize() int {
+	return e.handSlots + len(e.pileIDs) + 3
+}
+
+// ActionSize returns the number of actions. Action slot*len(PileIDs())+pile
+// plays the slot-th card of the agent's hand, in increasing order, on the
+// pile-th pile; the last action, EndTurnAction, ends the turn.
+func (e *Env) ActionSize() int {
+	return e.handSlots*len(e.pileIDs) + 1
+}
+
+// EndTurnAction returns the action that ends the agent's turn.
+func (e *Env) EndTurnAction() int {
+	return e.ActionSize() - 1
+}
+
+// Reset deals a new game from seed and plays the other players up to the
+// agent's first turn.
+func (e *Env) Reset(seed int64) (*TimeStep, error) {
+	if e.state != nil && !game.IsOver(e.state) {
+		e.gameOver()
+	}
+	state := game.NewGameWithRules("env", AgentID, seed, e.rules)
+	for i := 2; i <= e.players; i++ {
+		var err error
+		if state, err = game.AddPlayer(state, fmt.Sprintf("p%d", i)); err != nil {
+			return nil, err
+		}
+	}
+	state, err := game.StartGame(state)
+	if err != nil {
+		return nil, err
+	}
+	e.state = state
+	e.history = nil
+	e.turn = bot.Turn{Number: state.GetTurnNumber(), PlayerID: AgentID}
+	for i, s := range e.opponents {
+		if o, ok := s.(bot.GameObserver); ok {
+			if err := o.GameStarted(context.Background(), e.viewFor(e.opponentID(i))); err != nil {
+				return nil, fmt.Errorf("opponent could not start the game: %w", err)
+			}
+		}
+	}
+	if err := e.playOpponents(); err != nil {
+		// The game cannot go on without the other players.
+		e.state = nil
+		return nil, err
+	}
+	return e.timeStep(0, nil), nil
+}
+
+// Step applies action for the agent. An action the mask rules out returns
+// ErrIllegalAction, and an error of the other players' strategies in the turns
+// that follow is returned as it is; either way the game is left as it was.
+func (e *Env) Step(action int) (*TimeStep, error) {
+	if e.state == nil || game.IsOver(e.state) {
+		return nil, ErrNotRunning
+	}
+	if action < 0 || action >= e.ActionSize() || !e.Mask()[action] {
+		return nil, fmt.Errorf("%w: %d", ErrIllegalAction, action)
+	}
+	before := game.CardsPlayed(e.state)
+	reward := 0.0
+
+	var move *Move
+	if action == e.EndTurnAction() {
+		if err := e.endTurn(); err != nil {
+			return nil, err
+		}
+	} else {
+		m, _ := e.Move(action)
+		move = &m
+		pile := e.state.Piles[m.Pile]
+		if top := pile.Cards[len(pile.Cards)-1].Value; (m.Card < top) == pile.Ascending {
+			reward += e.reward.BackJump
+		}
+		next, err := game.PlayCard(e.state, AgentID, m.Card, m.Pile)
+		if err != nil {
+			return nil, err
+		}
+		e.state = next
+		e.turn.Moves = append(e.turn.Moves, game.Move{Card: &pb.Card{Value: m.Card}, Pile: m.Pile})
+	}
+
+	reward += e.reward.CardPlayed * float64(game.CardsPlayed(e.state)-before)
+	if game.IsOver(e.state) {
+		switch e.state.GetStatus() {
+		case pb.GameStatus_WON:
+			reward += e.reward.Win
+		case pb.GameStatus_LOST:
+			reward += e.reward.Loss
+		}
+		reward += e.reward.CardsRemaining * float64(game.CardsRemaining(e.state))
+		e.gameOver()
+	}
+	return e.timeStep(reward, move), nil
+}
+
+// endTurn ends the agent's turn and plays the other players' turns up to the
+// agent's next one. If any of them fails, the game is put back as it was
+// before the agent's turn ended.
+func (e *Env) endTurn() (err error) {
+	state, history := e.state, e.history
+	defer func() {
+		if err != nil {
+			e.state, e.history = state, history
+		}
+	}()
+
+	// EndTurn changes the state it is given, so it ends the turn on a copy.
+	next, err := game.EndTurn(proto.Clone(e.state).(*pb.GameState), AgentID)
+	if err != nil {
+		return err
+	}
+	e.state = next
+	e.history = append(e.history, e.turn)
+	if err := e.playOpponents(); err != nil {
+		return err
+	}
+	e.turn = bot.Turn{Number: e.state.GetTurnNumber(), PlayerID: AgentID}
+	return nil
+}
+
+// playOpponents plays the turns of the other players until it is the agent's
+// turn or the game is over.
+func (e *Env) playOpponents() error {
+	for !game.IsOver(e.state) && e.state.CurrentTurnPlayerId != AgentID {
+		playerID := e.state.CurrentTurnPlayerId
+		turn := bot.Turn{Number: e.state.GetTurnNumber(), PlayerID: playerID}
+		moves, err := e.opponentFor(playerID).PlanTurn(context.Background(), e.viewFor(playerID))
+		if err != nil {
+			return fmt.Errorf("turn %d: strategy error for %s: %w", turn.Number, playerID, err)
+		}
+		for _, m := range moves {
+			next, err := game.PlayCard(e.state, playerID, m.Card.GetValue(), m.Pile)
+			if err != nil {
+				return fmt.Errorf("turn %d: %s played %d on %s: %w", turn.Number, playerID, m.Card.GetValue(), m.Pile, err)
+			}
+			e.state = next
+			turn.Moves = append(turn.Moves, m)
+			if game.IsOver(e.state) {
+				return nil
+			}
+		}
+		next, err := game.EndTurn(e.state, playerID)
+		if err != nil {
+			return fmt.Errorf("turn %d: %s could not end their turn: %w", turn.Number, playerID, err)
+		}
+		e.state = next
+		e.history = append(e.history, turn)
+	}
+	return nil
+}
+
+func (e *Env) opponentID(i int) string {
+	return fmt.Sprintf("p%d", i+2)
+}
+
+func (e *Env) opponentFor(playerID string) bot.TurnStrategy {
+	for i, s := range e.opponents {
+		if e.opponentID(i) == playerID {
+			return s
+		}
+	}
+	return nil
+}
+
+func (e *Env) viewFor(playerID string) *bot.View {
+	return &bot.View{PlayerID: playerID, State: game.PlayerView(e.state, playerID), History: e.history}
+}
+
+func (e *Env) gameOver() {
+	for i, s := range e.opponents {
+		if o, ok := s.(bot.GameObserver); ok {
+			o.GameOver(context.Background(), e.viewFor(e.opponentID(i)))
+		}
+	}
+}
+
+// View returns the agent's view of the game, or nil before Reset.
+func (e *Env) View() *pb.GameState {
+	if e.state == nil {
+		return nil
+	}
+	return game.PlayerView(e.state, AgentID)
+}
+
+// hand returns the agent's cards in increasing order.
+func (e *Env) hand() []int32 {
+	var cards []int32
+	for _, c := range e.state.Hands[AgentID].GetCards() {
+		cards = append(cards, c.GetValue())
+	}
+	sort.Slice(cards, func(i, j int) bool { return cards[i] < cards[j] })
+	return cards
+}
+
+// Move returns the card and pile of a play action in the current game. It
+// reports false for the end turn action and for slots the hand does not fill.
+func (e *Env) Move(action int) (Move, bool) {
+	if e.state == nil || action < 0 || action >= e.EndTurnAction() {
+		return Move{}, false
+	}
+	slot, pile := action/len(e.pileIDs), action%len(e.pileIDs)
+	hand := e.hand()
+	if slot >= len(hand) {
+		return Move{}, false
+	}
+	return Move{Card: hand[slot], Pile: e.pileIDs[pile]}, true
+}
+
+// Action returns the action that plays card on pileID, or -1 if the agent
+// does not hold the card.
+func (e *Env) Action(card int32, pileID string) int {
+	pile := -1
+	for i, id := range e.pileIDs {
+		if id == pileID {
+			pile = i
+		}
+	}
+	for slot, c := range e.hand() {
+		if c == card && pile >= 0 {
+			return slot*len(e.pileIDs) + pile
+		}
+	}
+	return -1
+}
+
+// Mask returns, for each action, whether it is legal now. Ending the turn is
+// legal once the agent has played the cards the rules require, or when it
+// cannot play at all.
+func (e *Env) Mask() []bool {
+	mask := make([]bool, e.ActionSize())
+	if e.state == nil || game.IsOver(e.state) {
+		return mask
+	}
+	moves := game.GetPossibleMoves(AgentID, e.state)
+	for _, m := range moves {
+		if a := e.Action(m.Card.GetValue(), m.Pile); a >= 0 {
+			mask[a] = true
+		}
+	}
+	mask[e.EndTurnAction()] = len(moves) == 0 || e.state.CardsPlayedThisTurn >= game.MinPlaysToEndTurn(e.state)
+	return mask
+}
+
+// Observe encodes the agent's view of the game, every value scaled to lie
+// between 0 and 1:
+//
+//   - HandSlots values: the agent's cards in increasing order, card c as
+//     (c-MinCard+1)/(MaxCard-MinCard+1), and 0 for an empty slot;
+//   - one value per pile, in the order of PileIDs: the top card t as
+//     (t-MinCard+1)/(MaxCard-MinCard+2), so that an ascending pile starts at
+//     0 and a descending one at 1;
+//   - the fraction of the cards left in the deck;
+//   - the cards the agent has played this turn, as a fraction of HandSlots;
+//   - the cards it must still play before it can end the turn, as a fraction
+//     of the rules' minimum plays per turn.
+func (e *Env) Observe() []float32 {
+	obs := make([]float32, e.ObservationSize())
+	if e.state == nil {
+		return obs
+	}
+	span := float32(e.rules.MaxCard - e.rules.MinCard + 1)
+	for i, c := range e.hand() {
+		if i < e.handSlots {
+			obs[i] = float32(c-e.rules.MinCard+1) / span
+		}
+	}
+	i := e.handSlots
+	for _, id := range e.pileIDs {
+		if pile := e.state.Piles[id]; len(pile.GetCards()) > 0 {
+			obs[i] = float32(pile.Cards[len(pile.Cards)-1].Value-e.rules.MinCard+1) / (span + 1)
+		}
+		i++
+	}
+	obs[i] = float32(e.state.DeckSize) / span
+	obs[i+1] = float32(e.state.CardsPlayedThisTurn) / float32(e.handSlots)
+	if owed := game.MinPlaysToEndTurn(e.state) - e.state.CardsPlayedThisTurn; owed > 0 && e.state.CurrentTurnPlayerId == AgentID {
+		obs[i+2] = float32(owed) / float32(e.rules.MinPlaysPerTurn)
+	}
+	return obs
+}
+
+func (e *Env) timeStep(reward float64, move *Move) *TimeStep {
+	return &TimeStep{
+		Observation: e.Observe(),
+		Mask:        e.Mask(),
+		Reward:      reward,
+		Done:        game.IsOver(e.state),
+		Info: Info{
+			Status:         e.state.GetStatus().String(),
+			TurnNumber:     e.state.GetTurnNumber(),
+			CardsRemaining: game.CardsRemaining(e.state),
+			Move:           move,
+		},
+	}
+}
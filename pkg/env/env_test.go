@@ -0,0 +1,195 @@
+package env
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"math/rand"
+	"strings"
+	"testing"
+
+	"the_game_card_game/pkg/bot"
+	"the_game_card_game/pkg/game"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+// playRandomly plays a game to the end, taking a random legal action at each
+// step, and returns the total reward.
+func playRandomly(t *testing.T, e *Env, seed int64) (float64, *TimeStep) {
+	t.Helper()
+	rng := rand.New(rand.NewSource(seed))
+	step, err := e.Reset(seed)
+	require.NoError(t, err)
+	total := 0.0
+	for !step.Done {
+		require.Len(t, step.Observation, e.ObservationSize())
+		require.Len(t, step.Mask, e.ActionSize())
+		var legal []int
+		for a, ok := range step.Mask {
+			if ok {
+				legal = append(legal, a)
+			}
+		}
+		require.NotEmpty(t, legal, "a game in progress has a legal action")
+		step, err = e.Step(legal[rng.Intn(len(legal))])
+		require.NoError(t, err)
+		total += step.Reward
+	}
+	return total, step
+}
+
+func TestEnv_RewardsCardsPlayedAndWins(t *testing.T) {
+	e, err := New(Config{Reward: Reward{CardPlayed: 1, Win: 100, CardsRemaining: -0.5}})
+	require.NoError(t, err)
+	require.Equal(t, 8+4+3, e.ObservationSize())
+	require.Equal(t, 8*4+1, e.ActionSize())
+
+	for seed := int64(1); seed <= 5; seed++ {
+		total, last := playRandomly(t, e, seed)
+		played := 98 - last.Info.CardsRemaining
+		want := float64(played) - 0.5*float64(last.Info.CardsRemaining)
+		if last.Info.Status == "WON" {
+			want += 100
+		}
+		require.Equal(t, want, total, "seed %d", seed)
+	}
+
+	_, err = e.Step(0)
+	require.ErrorIs(t, err, ErrNotRunning)
+}
+
+func TestEnv_MaskFollowsPossibleMoves(t *testing.T) {
+	e, err := New(Config{})
+	require.NoError(t, err)
+	step, err := e.Reset(3)
+	require.NoError(t, err)
+
+	moves := game.GetPossibleMoves(AgentID, e.View())
+	legal := 0
+	for a, ok := range step.Mask {
+		if !ok || a == e.EndTurnAction() {
+			continue
+		}
+		legal++
+		m, ok := e.Move(a)
+		require.True(t, ok)
+		require.Equal(t, a, e.Action(m.Card, m.Pile))
+	}
+	require.Equal(t, len(moves), legal)
+	require.False(t, step.Mask[e.EndTurnAction()], "two cards must be played first")
+
+	_, err = e.Step(e.EndTurnAction())
+	require.ErrorIs(t, err, ErrIllegalAction)
+	require.Zero(t, e.View().CardsPlayedThisTurn, "an illegal action changes nothing")
+
+	// The hand is observed in increasing order.
+	for i := 1; i < e.HandSlots(); i++ {
+		require.Less(t, step.Observation[i-1], step.Observation[i])
+	}
+}
+
+func TestEnv_PlaysOpponentsBetweenTurns(t *testing.T) {
+	e, err := New(Config{Players: 3, Opponent: func() (bot.TurnStrategy, error) {
+		return bot.DefaultRegistry.New("smart")
+	}})
+	require.NoError(t, err)
+	require.Equal(t, 6*4+1, e.ActionSize())
+
+	for seed := int64(1); seed <= 3; seed++ {
+		_, last := playRandomly(t, e, seed)
+		require.NotEqual(t, "IN_PROGRESS", last.Info.Status)
+	}
+
+	_, err = New(Config{Players: 2})
+	require.Error(t, err, "other players need a strategy")
+}
+
+// failingStrategy plays like the smart strategy until fail is set, and then
+// fails every turn.
+type failingStrategy struct {
+	bot.TurnStrategy
+	fail   bool
+	closed bool
+}
+
+func (s *failingStrategy) PlanTurn(ctx context.Context, view *bot.View) ([]game.Move, error) {
+	if s.fail {
+		return nil, errors.New("strategy crashed")
+	}
+	return s.TurnStrategy.PlanTurn(ctx, view)
+}
+
+func (s *failingStrategy) Close() error {
+	s.closed = true
+	return nil
+}
+
+func TestEnv_OpponentErrorLeavesGameAsItWas(t *testing.T) {
+	opponent := &failingStrategy{TurnStrategy: bot.AdaptStrategy(bot.NewSmartStrategy())}
+	e, err := New(Config{Players: 2, Opponent: func() (bot.TurnStrategy, error) { return opponent, nil }})
+	require.NoError(t, err)
+
+	step, err := e.Reset(1)
+	require.NoError(t, err)
+	for !step.Mask[e.EndTurnAction()] {
+		step, err = e.Step(firstLegal(step.Mask))
+		require.NoError(t, err)
+	}
+
+	before := e.View()
+	opponent.fail = true
+	_, err = e.Step(e.EndTurnAction())
+	require.ErrorContains(t, err, "strategy crashed")
+	require.True(t, proto.Equal(before, e.View()), "the agent's turn should not have ended")
+	require.Equal(t, step.Mask, e.Mask())
+
+	opponent.fail = false
+	step, err = e.Step(e.EndTurnAction())
+	require.NoError(t, err)
+	require.Greater(t, step.Info.TurnNumber, before.TurnNumber)
+
+	require.NoError(t, e.Close())
+	require.True(t, opponent.closed, "Close should stop the opponents' strategies")
+}
+
+// firstLegal returns the first legal action in mask.
+func firstLegal(mask []bool) int {
+	for a, ok := range mask {
+		if ok {
+			return a
+		}
+	}
+	return -1
+}
+
+func TestServe(t *testing.T) {
+	e, err := New(Config{})
+	require.NoError(t, err)
+	requests := strings.Join([]string{
+		`{"type":"spec"}`,
+		`{"type":"step","action":0}`,
+		`{"type":"reset","seed":1}`,
+		`not json`,
+	}, "\n")
+	var out bytes.Buffer
+	require.NoError(t, Serve(e, strings.NewReader(requests), &out))
+
+	var replies []map[string]interface{}
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var reply map[string]interface{}
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &reply))
+		replies = append(replies, reply)
+	}
+	require.Len(t, replies, 4)
+	require.Equal(t, "spec", replies[0]["type"])
+	require.Equal(t, float64(e.ActionSize()), replies[0]["action_size"])
+	require.Equal(t, "error", replies[1]["type"], "no game has been dealt")
+	require.Equal(t, "time_step", replies[2]["type"])
+	require.Len(t, replies[2]["observation"], e.ObservationSize())
+	require.Equal(t, "error", replies[3]["type"])
+}
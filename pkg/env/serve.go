@@ -0,0 +1,103 @@
+package env
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// maxRequestLine bounds a request line read by Serve.
+const maxRequestLine = 1 << 16
+
+// request is a line read by Serve.
+type request struct {
+	Type   string `json:"type"`
+	Seed   int64  `json:"seed"`
+	Action int    `json:"action"`
+}
+
+// Spec describes the shape of an environment's observations and actions.
+type Spec struct {
+	Type            string   `json:"type"`
+	ObservationSize int      `json:"observation_size"`
+	ActionSize      int      `json:"action_size"`
+	EndTurnAction   int      `json:"end_turn_action"`
+	HandSlots       int      `json:"hand_slots"`
+	PileIDs         []string `json:"pile_ids"`
+	Reward          Reward   `json:"reward"`
+}
+
+// Spec returns the shape of e's observations and actions.
+func (e *Env) Spec() Spec {
+	return Spec{
+		Type:            "spec",
+		ObservationSize: e.ObservationSize(),
+		ActionSize:      e.ActionSize(),
+		EndTurnAction:   e.EndTurnAction(),
+		HandSlots:       e.HandSlots(),
+		PileIDs:         e.PileIDs(),
+		Reward:          e.reward,
+	}
+}
+
+type timeStepReply struct {
+	Type string `json:"type"`
+	*TimeStep
+}
+
+type errorReply struct {
+	Type  string `json:"type"`
+	Error string `json:"error"`
+}
+
+// Serve runs e for a client in another process, reading requests from r and
+// writing replies to w, one JSON object per line, until r is exhausted:
+//
+//	-> {"type":"spec"}
+//	<- {"type":"spec","observation_size":19,"action_size":33,"end_turn_action":32,...}
+//	-> {"type":"reset","seed":1}
+//	<- {"type":"time_step","observation":[...],"mask":[...],"reward":0,"done":false,"info":{...}}
+//	-> {"type":"step","action":3}
+//	<- {"type":"time_step",...}
+//
+// A request that fails, such as an illegal action, is answered with
+// {"type":"error","error":"..."}. A failed step leaves the game as it was; a
+// failed reset leaves no game, so the client must reset again.
+func Serve(e *Env, r io.Reader, w io.Writer) error {
+	in := bufio.NewScanner(r)
+	in.Buffer(make([]byte, 0, 4096), maxRequestLine)
+	out := json.NewEncoder(w)
+	for in.Scan() {
+		var reply interface{}
+		var req request
+		if err := json.Unmarshal(in.Bytes(), &req); err != nil {
+			reply = errorReply{Type: "error", Error: fmt.Sprintf("unreadable request: %v", err)}
+		} else {
+			reply = e.handle(req)
+		}
+		if err := out.Encode(reply); err != nil {
+			return err
+		}
+	}
+	return in.Err()
+}
+
+func (e *Env) handle(req request) interface{} {
+	var step *TimeStep
+	var err error
+	switch req.Type {
+	case "spec":
+		return e.Spec()
+	case "reset":
+		step, err = e.Reset(req.Seed)
+	case "step":
+		step, err = e.Step(req.Action)
+	default:
+		err = fmt.Errorf("unknown request type %q", req.Type)
+	}
+	if err != nil {
+		return errorReply{Type: "error", Error: err.Error()}
+	}
+	return timeStepReply{Type: "time_step", TimeStep: step}
+}
@@ -0,0 +1,175 @@
+package game
+
+import (
+	"fmt"
+
+	pb "the_game_card_game/proto"
+)
+
+// Compact is a game reduced to card values, for searches and simulations that
+// play many moves and cannot afford to copy a GameState for each one. It
+// follows the same rules as PlayCard and EndTurn.
+//
+// Piles are indexed in PileIDs order and hands in seating order. A Compact
+// holds every card, so it is built from a full state, or from a player's view
+// once the hidden cards have been filled in.
+type Compact struct {
+	PileIDs   []string
+	Ascending []bool
+	Tops      []int32
+	Hands     [][]int32
+	// Deck holds the cards still to be drawn, the next one first.
+	Deck []int32
+
+	Current        int // Index of the player whose turn it is.
+	PlayedThisTurn int32
+	TurnNumber     int32
+	Status         pb.GameStatus
+
+	BackJump          int32
+	MinPlaysPerTurn   int32
+	MinPlaysDeckEmpty int32
+}
+
+// NewCompact returns the compact form of a full game state.
+func NewCompact(state *pb.GameState) (*Compact, error) {
+	if int(state.GetDeckSize()) != len(state.GetDeck()) {
+		return nil, fmt.Errorf("the deck of game %s is hidden", state.GetGameId())
+	}
+	rules := RulesOf(state)
+	c := &Compact{
+		PileIDs:           PileIDs(state),
+		Deck:              cardValues(state.GetDeck()),
+		PlayedThisTurn:    state.GetCardsPlayedThisTurn(),
+		TurnNumber:        state.GetTurnNumber(),
+		Status:            state.GetStatus(),
+		BackJump:          rules.GetBackJump(),
+		MinPlaysPerTurn:   rules.MinPlaysPerTurn,
+		MinPlaysDeckEmpty: rules.MinPlaysDeckEmpty,
+	}
+	for _, id := range c.PileIDs {
+		pile := state.Piles[id]
+		cards := pile.GetCards()
+		if len(cards) == 0 {
+			return nil, fmt.Errorf("pile %s has no cards", id)
+		}
+		c.Ascending = append(c.Ascending, pile.GetAscending())
+		c.Tops = append(c.Tops, cards[len(cards)-1].GetValue())
+	}
+	for i, id := range state.GetPlayerIds() {
+		hand, ok := state.Hands[id]
+		if !ok {
+			return nil, fmt.Errorf("the hand of player %s is hidden", id)
+		}
+		c.Hands = append(c.Hands, cardValues(hand.GetCards()))
+		if id == state.GetCurrentTurnPlayerId() {
+			c.Current = i
+		}
+	}
+	return c, nil
+}
+
+func cardValues(cards []*pb.Card) []int32 {
+	values := make([]int32, len(cards))
+	for i, card := range cards {
+		values[i] = card.GetValue()
+	}
+	return values
+}
+
+// Clone returns a deep copy of c. The pile layout and rules are shared, as
+// they never change.
+func (c *Compact) Clone() *Compact {
+	clone := *c
+	clone.Tops = append([]int32(nil), c.Tops...)
+	clone.Deck = append([]int32(nil), c.Deck...)
+	clone.Hands = make([][]int32, len(c.Hands))
+	for i, hand := range c.Hands {
+		clone.Hands[i] = append([]int32(nil), hand...)
+	}
+	return &clone
+}
+
+// IsOver reports whether the game has finished.
+func (c *Compact) IsOver() bool {
+	return c.Status == pb.GameStatus_WON || c.Status == pb.GameStatus_LOST || c.Status == pb.GameStatus_ABANDONED
+}
+
+// CanPlay reports whether card may be played on the pile at index pile.
+func (c *Compact) CanPlay(card int32, pile int) bool {
+	return CanPlayOnTop(c.Ascending[pile], c.Tops[pile], card, c.BackJump)
+}
+
+// HasMove reports whether any card in the hand of the given player can be
+// played.
+func (c *Compact) HasMove(player int) bool {
+	for _, card := range c.Hands[player] {
+		for p := range c.Tops {
+			if c.CanPlay(card, p) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Play plays the card at index card of the current player's hand on the pile
+// at index pile. The move must be legal.
+func (c *Compact) Play(card, pile int) {
+	hand := c.Hands[c.Current]
+	c.Tops[pile] = hand[card]
+	c.Hands[c.Current] = append(hand[:card], hand[card+1:]...)
+	c.PlayedThisTurn++
+
+	// As in PlayCard, a player who cannot make the turn's plays loses.
+	if c.PlayedThisTurn < c.MinPlaysToEndTurn() && len(c.Hands[c.Current]) > 0 && !c.HasMove(c.Current) {
+		c.Status = pb.GameStatus_LOST
+	}
+}
+
+// MinPlaysToEndTurn is the compact form of MinPlaysToEndTurn.
+func (c *Compact) MinPlaysToEndTurn() int32 {
+	required := c.MinPlaysPerTurn
+	if len(c.Deck) == 0 {
+		required = c.MinPlaysDeckEmpty
+	}
+	if held := c.PlayedThisTurn + int32(len(c.Hands[c.Current])); held < required {
+		return held
+	}
+	return required
+}
+
+// EndTurn ends the current player's turn as EndTurn does: the hand is
+// replenished and play passes on, unless the minimum has not been played.
+func (c *Compact) EndTurn() error {
+	if min := c.MinPlaysToEndTurn(); c.PlayedThisTurn < min {
+		return fmt.Errorf("must play at least %d card(s) to end turn (played %d)", min, c.PlayedThisTurn)
+	}
+	draw := int(c.PlayedThisTurn)
+	if draw > len(c.Deck) {
+		draw = len(c.Deck)
+	}
+	c.Hands[c.Current] = append(c.Hands[c.Current], c.Deck[:draw]...)
+	c.Deck = c.Deck[draw:]
+	c.PlayedThisTurn = 0
+	c.TurnNumber++
+	c.Current = (c.Current + 1) % len(c.Hands)
+
+	if len(c.Deck) == 0 && c.CardsRemaining() == 0 {
+		c.Status = pb.GameStatus_WON
+		return nil
+	}
+	if len(c.Hands[c.Current]) > 0 && !c.HasMove(c.Current) {
+		c.Status = pb.GameStatus_LOST
+	}
+	return nil
+}
+
+// CardsRemaining returns the number of cards not yet played.
+func (c *Compact) CardsRemaining() int {
+	n := len(c.Deck)
+	for _, hand := range c.Hands {
+		n += len(hand)
+	}
+	return n
+}
@@ -0,0 +1,98 @@
+package game
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	pb "the_game_card_game/proto"
+
+	"github.com/stretchr/testify/require"
+)
+
+// requireSameGame checks that c is the compact form of state.
+func requireSameGame(t *testing.T, state *pb.GameState, c *Compact) {
+	t.Helper()
+	want, err := NewCompact(state)
+	require.NoError(t, err)
+	require.Equal(t, want.Tops, c.Tops)
+	require.Equal(t, want.Hands, c.Hands)
+	require.Equal(t, want.Deck, c.Deck)
+	require.Equal(t, want.Current, c.Current)
+	require.Equal(t, want.PlayedThisTurn, c.PlayedThisTurn)
+	require.Equal(t, want.TurnNumber, c.TurnNumber)
+	require.Equal(t, want.Status, c.Status)
+}
+
+func TestCompact_FollowsTheRules(t *testing.T) {
+	for seed := int64(1); seed <= 20; seed++ {
+		state := NewSeededGame("compact", "p1", seed)
+		for i := 2; i <= int(seed%3)+1; i++ {
+			var err error
+			state, err = AddPlayer(state, fmt.Sprintf("p%d", i))
+			require.NoError(t, err)
+		}
+		state, err := StartGame(state)
+		require.NoError(t, err)
+		c, err := NewCompact(state)
+		require.NoError(t, err)
+
+		// Both forms play the same random game.
+		rng := rand.New(rand.NewSource(seed))
+		for !IsOver(state) {
+			playerID := state.CurrentTurnPlayerId
+			moves := GetPossibleMoves(playerID, state)
+			if len(moves) == 0 || (state.CardsPlayedThisTurn >= MinPlaysToEndTurn(state) && rng.Intn(2) == 0) {
+				state, err = EndTurn(state, playerID)
+				require.NoError(t, err)
+				require.NoError(t, c.EndTurn())
+			} else {
+				move := moves[rng.Intn(len(moves))]
+				state, err = PlayCard(state, playerID, move.Card.Value, move.Pile)
+				require.NoError(t, err)
+				card := -1
+				for i, v := range c.Hands[c.Current] {
+					if v == move.Card.Value {
+						card = i
+					}
+				}
+				pile := -1
+				for i, id := range c.PileIDs {
+					if id == move.Pile {
+						pile = i
+					}
+				}
+				require.True(t, c.CanPlay(move.Card.Value, pile))
+				c.Play(card, pile)
+			}
+			requireSameGame(t, state, c)
+			require.Equal(t, CardsRemaining(state), c.CardsRemaining())
+			require.Equal(t, IsOver(state), c.IsOver())
+		}
+	}
+}
+
+func TestCompact_CloneIsIndependent(t *testing.T) {
+	state, err := StartGame(NewSeededGame("compact", "p1", 5))
+	require.NoError(t, err)
+	c, err := NewCompact(state)
+	require.NoError(t, err)
+
+	clone := c.Clone()
+	clone.Tops[0] = 50
+	clone.Hands[0][0] = 0
+	clone.Deck[0] = 0
+	requireSameGame(t, state, c)
+}
+
+func TestNewCompact_RejectsHiddenCards(t *testing.T) {
+	state, err := StartGame(NewSeededGame("compact", "p1", 5))
+	require.NoError(t, err)
+
+	_, err = NewCompact(PlayerView(state, "p1"))
+	require.Error(t, err)
+
+	state.Piles["up1"].Cards = nil
+	_, err = NewCompact(state)
+	require.ErrorContains(t, err, "pile up1 has no cards")
+}
@@ -0,0 +1,89 @@
+package game
+
+import (
+	"fmt"
+
+	pb "the_game_card_game/proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// ApplyEvent returns the player view that results from applying event to view,
+// so that a client can follow a game from its event stream. A snapshot
+// replaces the view; other events must follow on from it in sequence.
+func ApplyEvent(view *pb.GameState, event *pb.GameEvent) (*pb.GameState, error) {
+	if snapshot := event.GetSnapshot(); snapshot != nil {
+		return proto.Clone(snapshot).(*pb.GameState), nil
+	}
+	if view == nil {
+		return nil, fmt.Errorf("event %d arrived before a snapshot", event.GetSequence())
+	}
+	if event.GetSequence() != view.GetEventSequence()+1 {
+		return nil, fmt.Errorf("event %d does not follow event %d", event.GetSequence(), view.GetEventSequence())
+	}
+
+	next := proto.Clone(view).(*pb.GameState)
+	if next.HandSizes == nil {
+		next.HandSizes = make(map[string]int32)
+	}
+
+	switch e := event.GetEvent().(type) {
+	case *pb.GameEvent_CardPlayed:
+		played := e.CardPlayed
+		pile, ok := next.Piles[played.GetPileId()]
+		if !ok {
+			return nil, fmt.Errorf("pile '%s' not found", played.GetPileId())
+		}
+		pile.Cards = append(pile.Cards, proto.Clone(played.GetCard()).(*pb.Card))
+		// Only the player's own hand is in the view.
+		if hand, ok := next.Hands[played.GetPlayerId()]; ok {
+			for i, c := range hand.Cards {
+				if c.GetValue() == played.GetCard().GetValue() {
+					hand.Cards = append(hand.Cards[:i], hand.Cards[i+1:]...)
+					break
+				}
+			}
+		}
+		next.HandSizes[played.GetPlayerId()]--
+		next.CardsPlayedThisTurn++
+
+	case *pb.GameEvent_CardsDrawn:
+		drawn := e.CardsDrawn
+		if hand, ok := next.Hands[drawn.GetPlayerId()]; ok {
+			for _, c := range drawn.GetCards() {
+				hand.Cards = append(hand.Cards, proto.Clone(c).(*pb.Card))
+			}
+		}
+		// A full, unredacted state also carries the deck.
+		if n := int(drawn.GetCount()); len(next.Deck) >= n {
+			next.Deck = next.Deck[n:]
+		}
+		next.HandSizes[drawn.GetPlayerId()] += drawn.GetCount()
+		next.DeckSize = drawn.GetDeckSize()
+
+	case *pb.GameEvent_TurnEnded:
+		next.CurrentTurnPlayerId = e.TurnEnded.GetNextPlayerId()
+		next.TurnNumber = e.TurnEnded.GetTurnNumber()
+		next.CardsPlayedThisTurn = 0
+
+	case *pb.GameEvent_PlayerJoined:
+		// The hands are redealt; the snapshot that follows has them.
+		next.PlayerIds = append(next.PlayerIds, e.PlayerJoined.GetPlayerId())
+
+	case *pb.GameEvent_GameStarted:
+		// The hands are redealt; the snapshot that follows has them.
+		next.Status = pb.GameStatus_IN_PROGRESS
+
+	case *pb.GameEvent_GameOver:
+		next.Status = e.GameOver.GetStatus()
+		next.Winner = e.GameOver.GetWinner()
+		next.Message = e.GameOver.GetMessage()
+
+	default:
+		return nil, fmt.Errorf("event %d has no known content", event.GetSequence())
+	}
+
+	next.EventSequence = event.GetSequence()
+	next.Version = event.GetVersion()
+	return next, nil
+}
@@ -0,0 +1,67 @@
+package game
+
+import (
+	"testing"
+
+	pb "the_game_card_game/proto"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+func TestApplyEvent_FollowsPlayerView(t *testing.T) {
+	state, err := AddPlayer(NewSeededGame("events-game", "alice", 11), "bob")
+	require.NoError(t, err)
+	state, err = StartGame(state)
+	require.NoError(t, err)
+
+	views := map[string]*pb.GameState{}
+	for _, id := range state.PlayerIds {
+		views[id], err = ApplyEvent(nil, &pb.GameEvent{Event: &pb.GameEvent_Snapshot{Snapshot: PlayerView(state, id)}})
+		require.NoError(t, err)
+	}
+
+	// apply folds events into every view and checks that each then matches
+	// the player's view of state. Only alice sees the cards she draws.
+	sequence := int64(0)
+	apply := func(events ...*pb.GameEvent) {
+		t.Helper()
+		for _, event := range events {
+			sequence++
+			event.Sequence = sequence
+			for id, view := range views {
+				sent := proto.Clone(event).(*pb.GameEvent)
+				if drawn := sent.GetCardsDrawn(); drawn != nil && id != drawn.PlayerId {
+					drawn.Cards = nil
+				}
+				views[id], err = ApplyEvent(view, sent)
+				require.NoError(t, err)
+			}
+		}
+		state.EventSequence = sequence
+		for id, view := range views {
+			require.True(t, proto.Equal(PlayerView(state, id), view), "view of %s diverged", id)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		move := GetPossibleMoves("alice", state)[0]
+		state, err = PlayCard(state, "alice", move.Card.Value, move.Pile)
+		require.NoError(t, err)
+		apply(&pb.GameEvent{Event: &pb.GameEvent_CardPlayed{CardPlayed: &pb.CardPlayed{PlayerId: "alice", Card: move.Card, PileId: move.Pile}}})
+	}
+
+	drawn := proto.Clone(state).(*pb.GameState).Deck[:2]
+	state, err = EndTurn(state, "alice")
+	require.NoError(t, err)
+	apply(
+		&pb.GameEvent{Event: &pb.GameEvent_CardsDrawn{CardsDrawn: &pb.CardsDrawn{PlayerId: "alice", Count: 2, Cards: drawn, DeckSize: state.DeckSize}}},
+		&pb.GameEvent{Event: &pb.GameEvent_TurnEnded{TurnEnded: &pb.TurnEnded{PlayerId: "alice", NextPlayerId: "bob", TurnNumber: 2}}},
+	)
+
+	// Events must arrive in sequence.
+	_, err = ApplyEvent(views["bob"], &pb.GameEvent{Sequence: sequence + 2, Event: &pb.GameEvent_GameOver{GameOver: &pb.GameOver{}}})
+	require.Error(t, err)
+	_, err = ApplyEvent(nil, &pb.GameEvent{Sequence: 1, Event: &pb.GameEvent_GameStarted{GameStarted: &pb.GameStarted{}}})
+	require.Error(t, err)
+}
@@ -16,14 +16,38 @@ type Move struct {
 	Pile string
 }
 
-// NewGame initializes a new game state.
+// NewGame initializes a new game state with a randomly seeded deck.
 func NewGame(gameID string, playerID string) *pb.GameState {
-	const handSize = 8
-	deck := createShuffledDeck()
+	return NewSeededGame(gameID, playerID, time.Now().UnixNano())
+}
+
+// NewSeededGame initializes a new game state under the standard rules whose
+// deck is shuffled from seed. Games created with the same seed are dealt
+// identically, so a deal can be replayed from the seed recorded in the state.
+func NewSeededGame(gameID string, playerID string, seed int64) *pb.GameState {
+	return NewGameWithRules(gameID, playerID, seed, DefaultRuleSet())
+}
 
-	hand := deck[:handSize]
+// NewGameWithRules initializes a new game state played under rules, with the
+// deck shuffled from seed. Unset fields of rules take the standard values.
+func NewGameWithRules(gameID string, playerID string, seed int64, rules *RuleSet) *pb.GameState {
+	rules = ResolveRuleSet(rules)
+	handSize := HandSizeFor(rules, 1)
+	deck := createShuffledDeck(seed, rules)
+
+	hand := deck[:handSize:handSize]
 	remainingDeck := deck[handSize:]
 
+	// Ascending piles start just below the lowest card, descending piles just above the highest.
+	piles := make(map[string]*pb.Pile, len(rules.Piles))
+	for _, p := range rules.Piles {
+		start := rules.MinCard - 1
+		if !p.Ascending {
+			start = rules.MaxCard + 1
+		}
+		piles[p.Id] = &pb.Pile{Ascending: p.Ascending, Cards: []*pb.Card{{Value: start}}}
+	}
+
 	return &pb.GameState{
 		GameId:              gameID,
 		PlayerIds:           []string{playerID},
@@ -31,57 +55,231 @@ func NewGame(gameID string, playerID string) *pb.GameState {
 		Deck:                remainingDeck,
 		CurrentTurnPlayerId: playerID, // First player starts
 		CardsPlayedThisTurn: 0,
-		Piles: map[string]*pb.Pile{
-			"up1":   {Ascending: true, Cards: []*pb.Card{{Value: 1}}},
-			"up2":   {Ascending: true, Cards: []*pb.Card{{Value: 1}}},
-			"down1": {Ascending: false, Cards: []*pb.Card{{Value: 100}}},
-			"down2": {Ascending: false, Cards: []*pb.Card{{Value: 100}}},
-		},
+		Piles:               piles,
 		Hands: map[string]*pb.Hand{
 			playerID: {Cards: hand},
 		},
+		Seed:   seed,
+		Rules:  rules,
+		Status: pb.GameStatus_WAITING,
 	}
 }
 
-func createShuffledDeck() []*pb.Card {
-	deck := make([]*pb.Card, 98)
-	for i := 0; i < 98; i++ {
-		deck[i] = &pb.Card{Value: int32(i + 2)}
+func createShuffledDeck(seed int64, rules *RuleSet) []*pb.Card {
+	deck := make([]*pb.Card, 0, rules.MaxCard-rules.MinCard+1)
+	for v := rules.MinCard; v <= rules.MaxCard; v++ {
+		deck = append(deck, &pb.Card{Value: v})
 	}
-	r := rand.New(rand.NewSource(time.Now().UnixNano()))
+	r := rand.New(rand.NewSource(seed))
 	r.Shuffle(len(deck), func(i, j int) {
 		deck[i], deck[j] = deck[j], deck[i]
 	})
 	return deck
 }
 
-// AddPlayer adds a new player to the game state and deals them a hand.
-func AddPlayer(state *pb.GameState, playerID string, handSize int) (*pb.GameState, error) {
-	if len(state.Deck) < handSize {
-		return nil, fmt.Errorf("not enough cards in deck to deal a new hand")
+// AddPlayer seats a new player at the table and redeals every hand for the new
+// player count. Players can only join while the game is WAITING, and no more
+// than the rules' MaxPlayers may be seated.
+func AddPlayer(state *pb.GameState, playerID string) (*pb.GameState, error) {
+	rules := RulesOf(state)
+	if state.Status != pb.GameStatus_WAITING {
+		return nil, fmt.Errorf("cannot join game %s: it is %s", state.GameId, state.Status)
+	}
+	if _, ok := state.Hands[playerID]; ok {
+		return nil, fmt.Errorf("player '%s' has already joined", playerID)
+	}
+	if len(state.PlayerIds) >= int(rules.MaxPlayers) {
+		return nil, fmt.Errorf("game is full (%d players)", rules.MaxPlayers)
 	}
 
 	state.PlayerIds = append(state.PlayerIds, playerID)
-	hand := state.Deck[:handSize]
-	state.Deck = state.Deck[handSize:]
-	state.DeckSize = int32(len(state.Deck))
-	state.Hands[playerID] = &pb.Hand{Cards: hand}
+	if err := DealHands(state); err != nil {
+		state.PlayerIds = state.PlayerIds[:len(state.PlayerIds)-1]
+		return nil, err
+	}
+	return state, nil
+}
+
+// StartGame closes the table and starts play. Hands are redealt for the final
+// player count and the first seated player takes the first turn.
+func StartGame(state *pb.GameState) (*pb.GameState, error) {
+	if state.Status != pb.GameStatus_WAITING {
+		return nil, fmt.Errorf("cannot start game %s: it is %s", state.GameId, state.Status)
+	}
+	if len(state.PlayerIds) == 0 {
+		return nil, fmt.Errorf("cannot start game %s: no players have joined", state.GameId)
+	}
+	if err := DealHands(state); err != nil {
+		return nil, err
+	}
+	state.CurrentTurnPlayerId = state.PlayerIds[0]
+	state.CardsPlayedThisTurn = 0
+	state.TurnNumber = 1
+	state.Status = pb.GameStatus_IN_PROGRESS
+	return state, nil
+}
+
+// Abandon ends the game because playerID has left it. Every player is needed
+// to play the deck out, so the game is over for the whole table.
+func Abandon(state *pb.GameState, playerID string) (*pb.GameState, error) {
+	if IsOver(state) {
+		return nil, fmt.Errorf("game %s is over (%s)", state.GameId, state.Status)
+	}
+	if _, ok := state.Hands[playerID]; !ok {
+		return nil, fmt.Errorf("player '%s' has not joined game %s", playerID, state.GameId)
+	}
+	state.Status = pb.GameStatus_ABANDONED
+	state.Message = fmt.Sprintf("Player %s left: the game is abandoned.", playerID)
 	return state, nil
 }
 
+// IsOver reports whether the game has finished, whatever the outcome.
+func IsOver(state *pb.GameState) bool {
+	switch state.GetStatus() {
+	case pb.GameStatus_WON, pb.GameStatus_LOST, pb.GameStatus_ABANDONED:
+		return true
+	}
+	return false
+}
+
+// StatusOf returns the game's status. States built without a status, such as
+// those saved before games had one, are in progress.
+func StatusOf(state *pb.GameState) pb.GameStatus {
+	if state.GetStatus() == pb.GameStatus_GAME_STATUS_UNSPECIFIED {
+		return pb.GameStatus_IN_PROGRESS
+	}
+	return state.GetStatus()
+}
+
+// checkPlayable returns an error unless moves may be made in the game.
+func checkPlayable(state *pb.GameState) error {
+	switch StatusOf(state) {
+	case pb.GameStatus_IN_PROGRESS:
+		return nil
+	case pb.GameStatus_WAITING:
+		return fmt.Errorf("game %s has not started", state.GameId)
+	default:
+		return fmt.Errorf("game %s is over (%s)", state.GameId, state.Status)
+	}
+}
+
+// ValidateTurn returns an error unless playerID may take a turn in state: the
+// game is under way, it is the player's turn and the player holds a hand,
+// every pile holds at least its starting card, and every card in the hand is
+// in the rules' range. Bot strategies are checked with it before they plan,
+// so that a state that cannot arise in play is refused rather than misread.
+func ValidateTurn(state *pb.GameState, playerID string) error {
+	if err := checkPlayable(state); err != nil {
+		return err
+	}
+	if state.CurrentTurnPlayerId != playerID {
+		return fmt.Errorf("it is not %s's turn", playerID)
+	}
+	hand, ok := state.Hands[playerID]
+	if !ok || hand == nil {
+		return fmt.Errorf("player '%s' not found", playerID)
+	}
+	if len(state.Piles) == 0 {
+		return fmt.Errorf("game %s has no piles", state.GameId)
+	}
+	for id, pile := range state.Piles {
+		if len(pile.GetCards()) == 0 {
+			return fmt.Errorf("pile %s has no cards", id)
+		}
+	}
+	rules := RulesOf(state)
+	for _, c := range hand.Cards {
+		if v := c.GetValue(); v < rules.MinCard || v > rules.MaxCard {
+			return fmt.Errorf("card %d is outside the range %d-%d", v, rules.MinCard, rules.MaxCard)
+		}
+	}
+	return nil
+}
+
+// DealHands reshuffles the deck from the game's seed and deals every seated
+// player a hand sized for the current player count, in seat order. It is used
+// to redeal the table before the first card is played.
+func DealHands(state *pb.GameState) error {
+	rules := RulesOf(state)
+	handSize := HandSizeFor(rules, len(state.PlayerIds))
+	deck := createShuffledDeck(state.Seed, rules)
+	if len(deck) < handSize*len(state.PlayerIds) {
+		return fmt.Errorf("not enough cards in deck to deal %d hands of %d", len(state.PlayerIds), handSize)
+	}
+
+	state.Hands = make(map[string]*pb.Hand, len(state.PlayerIds))
+	for _, id := range state.PlayerIds {
+		state.Hands[id] = &pb.Hand{Cards: deck[:handSize:handSize]}
+		deck = deck[handSize:]
+	}
+	state.Deck = deck
+	state.DeckSize = int32(len(deck))
+	return nil
+}
+
+// CardsPlayed returns how many cards have been played onto the piles, not
+// counting the cards each pile starts with.
+func CardsPlayed(state *pb.GameState) int {
+	played := 0
+	for _, pile := range state.Piles {
+		if len(pile.Cards) > 1 {
+			played += len(pile.Cards) - 1
+		}
+	}
+	return played
+}
+
+// CardsRemaining returns how many cards have not been played: those left in the
+// deck and in every player's hand.
+func CardsRemaining(state *pb.GameState) int {
+	remaining := int(state.GetDeckSize())
+	for _, hand := range state.Hands {
+		remaining += len(hand.Cards)
+	}
+	return remaining
+}
+
+// PlayerView returns a copy of the state as seen by playerID. The deck order and
+// seed are removed and other players' hands are dropped; every player's hand size
+// is kept in HandSizes. An unknown playerID gets a spectator view with no hands.
+//
+// While the game is WAITING no hand is shown, not even the player's own: every
+// join redeals the same seeded deck, so a lobby hand holds cards that the final
+// deal gives to other players.
+func PlayerView(state *pb.GameState, playerID string) *pb.GameState {
+	view := proto.Clone(state).(*pb.GameState)
+	view.Deck = nil
+	view.Seed = 0
+
+	view.HandSizes = make(map[string]int32, len(state.Hands))
+	for id, hand := range state.Hands {
+		view.HandSizes[id] = int32(len(hand.GetCards()))
+		if id != playerID || state.Status == pb.GameStatus_WAITING {
+			delete(view.Hands, id)
+		}
+	}
+	return view
+}
+
 // PlayCard validates a move, updates the game state, and increments the turn's card counter.
 func PlayCard(state *pb.GameState, playerID string, cardValue int32, pileID string) (*pb.GameState, error) {
+	if err := checkPlayable(state); err != nil {
+		return nil, err
+	}
 	newState := proto.Clone(state).(*pb.GameState)
 
+	rules := RulesOf(newState)
+
 	pile, ok := newState.Piles[pileID]
 	if !ok {
 		return nil, fmt.Errorf("pile '%s' not found", pileID)
 	}
 
-	topCard := pile.Cards[len(pile.Cards)-1]
-	isTenBack := (pile.Ascending && cardValue == topCard.Value-10) || (!pile.Ascending && cardValue == topCard.Value+10)
-	isValid := (pile.Ascending && cardValue > topCard.Value) || (!pile.Ascending && cardValue < topCard.Value) || isTenBack
-	if !isValid {
+	if len(pile.GetCards()) == 0 {
+		return nil, fmt.Errorf("pile '%s' has no cards", pileID)
+	}
+	if !canPlay(pile, cardValue, rules.GetBackJump()) {
+		topCard := pile.Cards[len(pile.Cards)-1]
 		return nil, fmt.Errorf("invalid move: card %d on pile %s (top: %d)", cardValue, pileID, topCard.Value)
 	}
 
@@ -105,10 +303,11 @@ func PlayCard(state *pb.GameState, playerID string, cardValue int32, pileID stri
 	pile.Cards = append(pile.Cards, playedCard)
 	newState.CardsPlayedThisTurn++
 
-	// After playing, check if the game is lost
-	if newState.CardsPlayedThisTurn < 2 && len(playerHand.Cards) > 0 {
-		if !isMovePossible(playerHand, newState.Piles) {
-			newState.GameOver = true
+	// After playing, check if the game is lost: the player still owes plays,
+	// by the deck's rule, and none of their cards can be played.
+	if newState.CardsPlayedThisTurn < MinPlaysToEndTurn(newState) && len(playerHand.Cards) > 0 {
+		if !isMovePossible(playerHand, newState.Piles, rules.GetBackJump()) {
+			newState.Status = pb.GameStatus_LOST
 			newState.Message = fmt.Sprintf("Player %s lost: No more valid moves.", playerID)
 		}
 	}
@@ -117,24 +316,10 @@ func PlayCard(state *pb.GameState, playerID string, cardValue int32, pileID stri
 }
 
 // isMovePossible checks if any card in the hand can be legally played on any pile.
-func isMovePossible(hand *pb.Hand, piles map[string]*pb.Pile) bool {
+func isMovePossible(hand *pb.Hand, piles map[string]*pb.Pile, backJump int32) bool {
 	for _, card := range hand.Cards {
 		for _, pile := range piles {
-			topCard := pile.Cards[len(pile.Cards)-1]
-
-			// Check normal play rule
-			if pile.Ascending && card.Value > topCard.Value {
-				return true
-			}
-			if !pile.Ascending && card.Value < topCard.Value {
-				return true
-			}
-
-			// Check "10-rule"
-			if pile.Ascending && card.Value == topCard.Value-10 {
-				return true
-			}
-			if !pile.Ascending && card.Value == topCard.Value+10 {
+			if canPlay(pile, card.Value, backJump) {
 				return true
 			}
 		}
@@ -143,6 +328,7 @@ func isMovePossible(hand *pb.Hand, piles map[string]*pb.Pile) bool {
 }
 
 // GetPossibleMoves returns a list of all valid moves for a given player.
+// Moves are ordered by hand position, then by pile in rule order.
 func GetPossibleMoves(playerID string, state *pb.GameState) []Move {
 	var moves []Move
 	hand, ok := state.Hands[playerID]
@@ -150,12 +336,11 @@ func GetPossibleMoves(playerID string, state *pb.GameState) []Move {
 		return moves
 	}
 
+	backJump := RulesOf(state).GetBackJump()
+	pileIDs := PileIDs(state)
 	for _, card := range hand.Cards {
-		for pileID, pile := range state.Piles {
-			topCard := pile.Cards[len(pile.Cards)-1]
-			isTenBack := (pile.Ascending && card.Value == topCard.Value-10) || (!pile.Ascending && card.Value == topCard.Value+10)
-			isValid := (pile.Ascending && card.Value > topCard.Value) || (!pile.Ascending && card.Value < topCard.Value) || isTenBack
-			if isValid {
+		for _, pileID := range pileIDs {
+			if canPlay(state.Piles[pileID], card.Value, backJump) {
 				moves = append(moves, Move{Card: card, Pile: pileID})
 			}
 		}
@@ -165,14 +350,15 @@ func GetPossibleMoves(playerID string, state *pb.GameState) []Move {
 
 // EndTurn replenishes the player's hand, resets the turn counter, and advances to the next player.
 func EndTurn(state *pb.GameState, playerID string) (*pb.GameState, error) {
-	// Validate that the player has played enough cards.
-	// The minimum is 2, unless the deck is empty, in which case it's 1.
-	minCards := 2
-	if state.DeckSize == 0 {
-		minCards = 1
+	if err := checkPlayable(state); err != nil {
+		return nil, err
 	}
 
-	if state.CardsPlayedThisTurn < int32(minCards) {
+	// Validate that the player has played enough cards.
+	// The rules set a lower minimum once the deck is empty.
+	minCards := MinPlaysToEndTurn(state)
+
+	if state.CardsPlayedThisTurn < minCards {
 		return nil, fmt.Errorf("must play at least %d card(s) to end turn (played %d)", minCards, state.CardsPlayedThisTurn)
 	}
 
@@ -200,6 +386,7 @@ func EndTurn(state *pb.GameState, playerID string) (*pb.GameState, error) {
 
 	// Reset counter
 	state.CardsPlayedThisTurn = 0
+	state.TurnNumber++
 
 	// Advance to next player
 	currentPlayerIndex := -1
@@ -225,7 +412,7 @@ func EndTurn(state *pb.GameState, playerID string) (*pb.GameState, error) {
 	}
 
 	if state.DeckSize == 0 && allHandsEmpty {
-		state.GameOver = true
+		state.Status = pb.GameStatus_WON
 		state.Message = "You won! All cards have been played."
 		return state, nil
 	}
@@ -233,8 +420,8 @@ func EndTurn(state *pb.GameState, playerID string) (*pb.GameState, error) {
 	// Check if the new player is stuck (losing condition)
 	nextPlayerHand, ok := state.Hands[state.CurrentTurnPlayerId]
 	if ok && len(nextPlayerHand.Cards) > 0 {
-		if !isMovePossible(nextPlayerHand, state.Piles) {
-			state.GameOver = true
+		if !isMovePossible(nextPlayerHand, state.Piles, RulesOf(state).GetBackJump()) {
+			state.Status = pb.GameStatus_LOST
 			state.Message = fmt.Sprintf("Player %s lost: No more valid moves.", state.CurrentTurnPlayerId)
 		}
 	}
@@ -1,12 +1,289 @@
 package game
 
 import (
+	"fmt"
 	"testing"
 	pb "the_game_card_game/proto"
 
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
 )
 
+func TestNewSeededGame_Reproducible(t *testing.T) {
+	first := NewSeededGame("game-a", "player", 1234)
+	second := NewSeededGame("game-b", "player", 1234)
+	other := NewSeededGame("game-c", "player", 4321)
+
+	require.Equal(t, int64(1234), first.Seed)
+	require.Equal(t, first.Hands["player"].Cards, second.Hands["player"].Cards)
+	require.Equal(t, first.Deck, second.Deck)
+	require.NotEqual(t, first.Deck, other.Deck, "different seeds should deal different decks")
+}
+
+func TestNewGameWithRules_CustomDeckAndPiles(t *testing.T) {
+	rules := &RuleSet{
+		MinCard:  2,
+		MaxCard:  49,
+		HandSize: 6,
+		Piles: []*pb.PileRule{
+			{Id: "up1", Ascending: true},
+			{Id: "down1", Ascending: false},
+			{Id: "down2", Ascending: false},
+		},
+	}
+
+	state := NewGameWithRules("short-game", "player", 1, rules)
+
+	require.Len(t, state.Hands["player"].Cards, 6)
+	require.Equal(t, int32(42), state.DeckSize)
+	require.Equal(t, int32(5), state.Rules.MaxPlayers)
+	require.Len(t, state.Piles, 3)
+	require.Equal(t, int32(1), state.Piles["up1"].Cards[0].Value)
+	require.Equal(t, int32(50), state.Piles["down2"].Cards[0].Value)
+	require.Equal(t, []string{"up1", "down1", "down2"}, PileIDs(state))
+	require.Equal(t, int32(10), state.Rules.GetBackJump(), "unset fields take the standard rules")
+}
+
+func TestAddPlayer_RedealsOfficialHandSizes(t *testing.T) {
+	state := NewSeededGame("table", "p1", 11)
+	require.Len(t, state.Hands["p1"].Cards, 8)
+
+	for i, want := range []int{7, 6, 6, 6} {
+		var err error
+		state, err = AddPlayer(state, fmt.Sprintf("p%d", i+2))
+		require.NoError(t, err)
+		for _, id := range state.PlayerIds {
+			require.Len(t, state.Hands[id].Cards, want, "player %s at a table of %d", id, len(state.PlayerIds))
+		}
+		require.Equal(t, int32(98-want*len(state.PlayerIds)), state.DeckSize)
+	}
+
+	_, err := AddPlayer(state, "p6")
+	require.ErrorContains(t, err, "game is full")
+}
+
+func TestAddPlayer_IsReproducible(t *testing.T) {
+	first, err := AddPlayer(NewSeededGame("a", "p1", 5), "p2")
+	require.NoError(t, err)
+	second, err := AddPlayer(NewSeededGame("b", "p1", 5), "p2")
+	require.NoError(t, err)
+
+	require.Equal(t, first.Hands["p2"].Cards, second.Hands["p2"].Cards)
+	require.Equal(t, first.Deck, second.Deck)
+}
+
+func TestAddPlayer_RejectsAfterStart(t *testing.T) {
+	state, err := StartGame(NewSeededGame("started", "p1", 9))
+	require.NoError(t, err)
+
+	_, err = AddPlayer(state, "p2")
+	require.ErrorContains(t, err, "cannot join")
+}
+
+func TestStartGame(t *testing.T) {
+	state := NewSeededGame("lobby", "p1", 13)
+	require.Equal(t, pb.GameStatus_WAITING, state.Status)
+
+	_, err := PlayCard(state, "p1", state.Hands["p1"].Cards[0].Value, "up1")
+	require.ErrorContains(t, err, "has not started")
+
+	state, err = AddPlayer(state, "p2")
+	require.NoError(t, err)
+	state, err = StartGame(state)
+	require.NoError(t, err)
+
+	require.Equal(t, pb.GameStatus_IN_PROGRESS, state.Status)
+	require.Equal(t, "p1", state.CurrentTurnPlayerId)
+	require.Equal(t, int32(1), state.TurnNumber)
+	require.Len(t, state.Hands["p2"].Cards, 7)
+	require.Equal(t, 98, CardsRemaining(state), "no card has been played yet")
+
+	_, err = StartGame(state)
+	require.ErrorContains(t, err, "cannot start")
+}
+
+func TestPlayCard_RejectsFinishedGame(t *testing.T) {
+	playerID := "player"
+	state := &pb.GameState{
+		GameId:              "lost-game",
+		PlayerIds:           []string{playerID},
+		CurrentTurnPlayerId: playerID,
+		Status:              pb.GameStatus_LOST,
+		Piles: map[string]*pb.Pile{
+			"up1": {Ascending: true, Cards: []*pb.Card{{Value: 1}}},
+		},
+		Hands: map[string]*pb.Hand{
+			playerID: {Cards: []*pb.Card{{Value: 20}}},
+		},
+	}
+
+	_, err := PlayCard(state, playerID, 20, "up1")
+	require.ErrorContains(t, err, "is over")
+
+	_, err = EndTurn(state, playerID)
+	require.ErrorContains(t, err, "is over")
+}
+
+func TestAbandon(t *testing.T) {
+	state := NewSeededGame("game1", "p1", 1)
+	_, err := AddPlayer(state, "p2")
+	require.NoError(t, err)
+
+	_, err = Abandon(state, "p3")
+	require.ErrorContains(t, err, "has not joined")
+
+	state, err = Abandon(state, "p2")
+	require.NoError(t, err)
+	require.Equal(t, pb.GameStatus_ABANDONED, state.Status)
+	require.True(t, IsOver(state))
+	require.Contains(t, state.Message, "p2")
+
+	_, err = Abandon(state, "p1")
+	require.ErrorContains(t, err, "is over")
+}
+
+func TestStatusOf(t *testing.T) {
+	require.Equal(t, pb.GameStatus_IN_PROGRESS, StatusOf(&pb.GameState{}), "states saved without a status are in progress")
+	require.Equal(t, pb.GameStatus_WAITING, StatusOf(&pb.GameState{Status: pb.GameStatus_WAITING}))
+	require.Equal(t, pb.GameStatus_LOST, StatusOf(&pb.GameState{Status: pb.GameStatus_LOST}))
+}
+
+func TestValidateTurn(t *testing.T) {
+	state, err := StartGame(NewSeededGame("game", "p1", 1))
+	require.NoError(t, err)
+	require.NoError(t, ValidateTurn(state, "p1"))
+	require.ErrorContains(t, ValidateTurn(state, "p2"), "not p2's turn")
+
+	state.Piles["up1"].Cards = nil
+	require.ErrorContains(t, ValidateTurn(state, "p1"), "pile up1 has no cards")
+	for _, m := range GetPossibleMoves("p1", state) {
+		require.NotEqual(t, "up1", m.Pile, "nothing may be played on a pile without its starting card")
+	}
+	_, err = PlayCard(state, "p1", state.Hands["p1"].Cards[0].Value, "up1")
+	require.ErrorContains(t, err, "has no cards")
+}
+
+func TestRulesOf(t *testing.T) {
+	require.True(t, proto.Equal(DefaultRuleSet(), RulesOf(&pb.GameState{})), "states without rules get the standard rules")
+	require.Equal(t, int32(7), RulesOf(&pb.GameState{Rules: &RuleSet{MinPlaysPerTurn: 7}}).MinPlaysPerTurn)
+	require.Equal(t, int32(10), RulesOf(&pb.GameState{Rules: &RuleSet{MinPlaysPerTurn: 7}}).GetBackJump())
+
+	// Rules are looked up for every move, so resolved rules are not copied.
+	state := NewSeededGame("game1", "p1", 1)
+	require.Same(t, state.Rules, RulesOf(state))
+	require.Zero(t, testing.AllocsPerRun(10, func() { RulesOf(state) }))
+	require.Zero(t, testing.AllocsPerRun(10, func() { RulesOf(&pb.GameState{}) }))
+}
+
+func TestValidateRuleSet(t *testing.T) {
+	require.NoError(t, ValidateRuleSet(nil))
+	require.NoError(t, ValidateRuleSet(DefaultRuleSet()))
+	require.Error(t, ValidateRuleSet(&RuleSet{MinCard: 50, MaxCard: 40}))
+	require.Error(t, ValidateRuleSet(&RuleSet{MinCard: 2, MaxCard: 5, HandSize: 8}))
+	require.Error(t, ValidateRuleSet(&RuleSet{MinCard: 2, MaxCard: 30, MaxPlayers: 5}), "five hands of six do not fit in 29 cards")
+	require.Error(t, ValidateRuleSet(&RuleSet{MaxPlayers: -1}))
+	require.Error(t, ValidateRuleSet(&RuleSet{MinPlaysPerTurn: 9}))
+	require.Error(t, ValidateRuleSet(&RuleSet{Piles: []*pb.PileRule{{Id: "up1"}, {Id: "up1"}}}))
+	require.Error(t, ValidateRuleSet(&RuleSet{BackJump: proto.Int32(-1)}))
+	require.NoError(t, ValidateRuleSet(&RuleSet{BackJump: proto.Int32(0)}))
+}
+
+func TestPlayCard_CustomBackJump(t *testing.T) {
+	playerID := "player"
+	state := &pb.GameState{
+		PlayerIds:           []string{playerID},
+		CurrentTurnPlayerId: playerID,
+		Rules:               &RuleSet{BackJump: proto.Int32(5)},
+		Piles: map[string]*pb.Pile{
+			"up1": {Ascending: true, Cards: []*pb.Card{{Value: 30}}},
+		},
+		Hands: map[string]*pb.Hand{
+			playerID: {Cards: []*pb.Card{{Value: 25}, {Value: 20}}},
+		},
+	}
+
+	_, err := PlayCard(state, playerID, 20, "up1")
+	require.Error(t, err, "a 10-back move is not legal when the back jump is 5")
+
+	newState, err := PlayCard(state, playerID, 25, "up1")
+	require.NoError(t, err)
+	require.Equal(t, int32(25), newState.Piles["up1"].Cards[1].Value)
+}
+
+func TestPlayCard_NoBackJump(t *testing.T) {
+	playerID := "player"
+	state := &pb.GameState{
+		PlayerIds:           []string{playerID},
+		CurrentTurnPlayerId: playerID,
+		Rules:               ResolveRuleSet(&RuleSet{BackJump: proto.Int32(0)}),
+		Piles: map[string]*pb.Pile{
+			"up1": {Ascending: true, Cards: []*pb.Card{{Value: 30}}},
+		},
+		Hands: map[string]*pb.Hand{
+			playerID: {Cards: []*pb.Card{{Value: 20}, {Value: 40}}},
+		},
+	}
+	require.Equal(t, int32(0), state.Rules.GetBackJump(), "a back jump of 0 is kept, not replaced by the standard 10")
+
+	_, err := PlayCard(state, playerID, 20, "up1")
+	require.Error(t, err, "no move goes backwards when back-jumps are disabled")
+	require.Len(t, GetPossibleMoves(playerID, state), 1)
+}
+
+func TestEndTurn_CustomMinimumPlays(t *testing.T) {
+	playerID := "player"
+	state := &pb.GameState{
+		PlayerIds:           []string{playerID},
+		CurrentTurnPlayerId: playerID,
+		Rules:               &RuleSet{MinPlaysPerTurn: 3},
+		Deck:                []*pb.Card{{Value: 40}, {Value: 41}, {Value: 42}},
+		DeckSize:            3,
+		Hands: map[string]*pb.Hand{
+			playerID: {Cards: []*pb.Card{{Value: 20}}},
+		},
+		CardsPlayedThisTurn: 2,
+	}
+
+	_, err := EndTurn(state, playerID)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "must play at least 3 card(s)")
+}
+
+func TestPlayerView_RedactsHiddenInformation(t *testing.T) {
+	state := NewSeededGame("view-game", "alice", 7)
+	state, err := AddPlayer(state, "bob")
+	require.NoError(t, err)
+	state, err = StartGame(state)
+	require.NoError(t, err)
+
+	view := PlayerView(state, "alice")
+
+	require.Empty(t, view.Deck, "deck order must not be visible")
+	require.Zero(t, view.Seed, "seed would reveal the deck order")
+	require.Equal(t, state.DeckSize, view.DeckSize)
+	require.Equal(t, state.Hands["alice"].Cards, view.Hands["alice"].Cards)
+	require.NotContains(t, view.Hands, "bob")
+	require.Equal(t, map[string]int32{"alice": 7, "bob": 7}, view.HandSizes)
+
+	// The original state must be left untouched.
+	require.NotEmpty(t, state.Deck)
+	require.Contains(t, state.Hands, "bob")
+}
+
+func TestPlayerView_HidesLobbyHands(t *testing.T) {
+	// Every join redeals the same seeded deck, so alice's solo hand holds the
+	// card that the two-player deal gives to bob.
+	state := NewSeededGame("lobby", "alice", 7)
+	lobby := PlayerView(state, "alice")
+	require.Empty(t, lobby.Hands)
+	require.Equal(t, map[string]int32{"alice": 8}, lobby.HandSizes)
+
+	state, err := AddPlayer(state, "bob")
+	require.NoError(t, err)
+	require.Empty(t, PlayerView(state, "alice").Hands)
+	require.Empty(t, PlayerView(state, "bob").Hands)
+}
+
 func TestPlayCard_LosingCondition(t *testing.T) {
 	// 1. Setup
 	playerID := "stuck-player"
@@ -14,6 +291,9 @@ func TestPlayCard_LosingCondition(t *testing.T) {
 		GameId:              "game-with-no-moves",
 		PlayerIds:           []string{playerID},
 		CurrentTurnPlayerId: playerID,
+		// Cards are still to be drawn, so two plays are owed.
+		Deck:     []*pb.Card{{Value: 30}},
+		DeckSize: 1,
 		Piles: map[string]*pb.Pile{
 			// After playing 50, the only remaining card '4' will have no valid pile.
 			"up1":   {Ascending: true, Cards: []*pb.Card{{Value: 48}}},
@@ -36,7 +316,7 @@ func TestPlayCard_LosingCondition(t *testing.T) {
 	require.NotNil(t, newState)
 
 	// The key assertion: The game should now be over.
-	require.True(t, newState.GameOver, "Game should be over because no second move is possible")
+	require.Equal(t, pb.GameStatus_LOST, newState.Status, "Game should be over because no second move is possible")
 	require.Contains(t, newState.Message, "lost: No more valid moves")
 
 	// The hand should have one card left.
@@ -68,7 +348,7 @@ func TestEndTurn_WinCondition(t *testing.T) {
 	// 3. Assert
 	require.NoError(t, err)
 	require.NotNil(t, newState)
-	require.True(t, newState.GameOver, "Game should be over because all cards are played")
+	require.Equal(t, pb.GameStatus_WON, newState.Status, "Game should be over because all cards are played")
 	require.Equal(t, "You won! All cards have been played.", newState.Message)
 }
 
@@ -95,6 +375,37 @@ func TestEndTurn_DeckEmptyRule(t *testing.T) {
 	require.NoError(t, err, "Ending turn with 1 card should be allowed when deck is empty")
 }
 
+func TestPlayCard_DeckEmptyOnePlayIsEnough(t *testing.T) {
+	state := &pb.GameState{
+		GameId:              "empty-deck-game",
+		PlayerIds:           []string{"p1", "p2"},
+		CurrentTurnPlayerId: "p1",
+		Status:              pb.GameStatus_IN_PROGRESS,
+		Piles: map[string]*pb.Pile{
+			"up1":   {Ascending: true, Cards: []*pb.Card{{Value: 1}, {Value: 98}}},
+			"down1": {Ascending: false, Cards: []*pb.Card{{Value: 100}, {Value: 4}}},
+		},
+		Hands: map[string]*pb.Hand{
+			"p1": {Cards: []*pb.Card{{Value: 99}, {Value: 50}}},
+			"p2": {Cards: []*pb.Card{{Value: 3}}},
+		},
+	}
+	c, err := NewCompact(state)
+	require.NoError(t, err)
+
+	// With the deck empty one play ends the turn, so p1 being unable to play
+	// 50 does not lose the game.
+	newState, err := PlayCard(state, "p1", 99, "up1")
+	require.NoError(t, err)
+	require.Equal(t, pb.GameStatus_IN_PROGRESS, newState.Status)
+	newState, err = EndTurn(newState, "p1")
+	require.NoError(t, err)
+	require.Equal(t, "p2", newState.CurrentTurnPlayerId)
+
+	c.Play(0, 0)
+	require.False(t, c.IsOver(), "the compact form follows the same rule")
+}
+
 func TestEndTurn_NextPlayerHasNoMoves(t *testing.T) {
 	// 1. Setup
 	playerA := "player-a"
@@ -115,6 +426,7 @@ func TestEndTurn_NextPlayerHasNoMoves(t *testing.T) {
 			playerB: {Cards: []*pb.Card{{Value: 50}}}, // Player B has a card but no valid move
 		},
 		CardsPlayedThisTurn: 2, // Player A has met the minimum
+		TurnNumber:          7,
 	}
 
 	// 2. Execute
@@ -123,6 +435,30 @@ func TestEndTurn_NextPlayerHasNoMoves(t *testing.T) {
 
 	// 3. Assert
 	require.NoError(t, err)
-	require.True(t, newState.GameOver, "Game should be over because the next player has no valid moves")
+	require.Equal(t, int32(8), newState.TurnNumber)
+	require.Equal(t, pb.GameStatus_LOST, newState.Status, "Game should be over because the next player has no valid moves")
 	require.Contains(t, newState.Message, "lost: No more valid moves")
 }
+
+func TestEndTurn_EmptyHandPasses(t *testing.T) {
+	initialState := &pb.GameState{
+		GameId:              "passing-game",
+		PlayerIds:           []string{"player-a", "player-b"},
+		CurrentTurnPlayerId: "player-a",
+		DeckSize:            0,
+		Piles: map[string]*pb.Pile{
+			"up1": {Ascending: true, Cards: []*pb.Card{{Value: 40}}},
+		},
+		Hands: map[string]*pb.Hand{
+			"player-a": {Cards: []*pb.Card{}},
+			"player-b": {Cards: []*pb.Card{{Value: 50}}},
+		},
+	}
+
+	// Player A has no cards left to play, so the turn passes to player B.
+	newState, err := EndTurn(initialState, "player-a")
+
+	require.NoError(t, err)
+	require.Equal(t, "player-b", newState.CurrentTurnPlayerId)
+	require.Equal(t, pb.GameStatus_GAME_STATUS_UNSPECIFIED, newState.Status)
+}
@@ -0,0 +1,241 @@
+package game
+
+import (
+	"fmt"
+	"sort"
+
+	pb "the_game_card_game/proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// RuleSet configures the variable rules of a game. It is stored on the
+// GameState so that every move in a game is checked against the same rules.
+type RuleSet = pb.RuleSet
+
+// DefaultRuleSet returns the standard rules of The Game. Its HandSize is left
+// at zero so that hands follow the official sizes for the player count.
+func DefaultRuleSet() *RuleSet {
+	return &RuleSet{
+		MinCard:           2,
+		MaxCard:           99,
+		MaxPlayers:        5,
+		MinPlaysPerTurn:   2,
+		MinPlaysDeckEmpty: 1,
+		BackJump:          proto.Int32(10),
+		Piles: []*pb.PileRule{
+			{Id: "up1", Ascending: true},
+			{Id: "up2", Ascending: true},
+			{Id: "down1", Ascending: false},
+			{Id: "down2", Ascending: false},
+		},
+	}
+}
+
+// ResolveRuleSet returns a copy of rules with every unset field taken from the
+// standard rules. A nil rules resolves to DefaultRuleSet. BackJump is the one
+// field whose zero is a rule of its own: set to 0, it disables back-jumps.
+func ResolveRuleSet(rules *RuleSet) *RuleSet {
+	resolved := DefaultRuleSet()
+	if rules == nil {
+		return resolved
+	}
+	if rules.MinCard != 0 {
+		resolved.MinCard = rules.MinCard
+	}
+	if rules.MaxCard != 0 {
+		resolved.MaxCard = rules.MaxCard
+	}
+	if rules.HandSize != 0 {
+		resolved.HandSize = rules.HandSize
+	}
+	if rules.MaxPlayers != 0 {
+		resolved.MaxPlayers = rules.MaxPlayers
+	}
+	if rules.MinPlaysPerTurn != 0 {
+		resolved.MinPlaysPerTurn = rules.MinPlaysPerTurn
+	}
+	if rules.MinPlaysDeckEmpty != 0 {
+		resolved.MinPlaysDeckEmpty = rules.MinPlaysDeckEmpty
+	}
+	if rules.BackJump != nil {
+		resolved.BackJump = proto.Int32(rules.GetBackJump())
+	}
+	if len(rules.Piles) > 0 {
+		resolved.Piles = make([]*pb.PileRule, len(rules.Piles))
+		for i, p := range rules.Piles {
+			resolved.Piles[i] = proto.Clone(p).(*pb.PileRule)
+		}
+	}
+	return resolved
+}
+
+// ValidateRuleSet checks that rules, once resolved, describe a playable game.
+func ValidateRuleSet(rules *RuleSet) error {
+	r := ResolveRuleSet(rules)
+	if r.MinCard < 1 || r.MinCard >= r.MaxCard {
+		return fmt.Errorf("invalid card range %d-%d", r.MinCard, r.MaxCard)
+	}
+	if r.HandSize < 0 {
+		return fmt.Errorf("hand size must not be negative (got %d)", r.HandSize)
+	}
+	if r.MaxPlayers < 1 {
+		return fmt.Errorf("max players must be positive (got %d)", r.MaxPlayers)
+	}
+	// A full table must be dealable from the deck.
+	fullTableHand := int32(HandSizeFor(r, int(r.MaxPlayers)))
+	if deckSize := r.MaxCard - r.MinCard + 1; fullTableHand*r.MaxPlayers > deckSize {
+		return fmt.Errorf("%d hands of %d cards do not fit in the deck (%d cards)", r.MaxPlayers, fullTableHand, deckSize)
+	}
+	if r.MinPlaysPerTurn < 1 || r.MinPlaysDeckEmpty < 1 {
+		return fmt.Errorf("minimum plays per turn must be positive")
+	}
+	if r.MinPlaysPerTurn > fullTableHand {
+		return fmt.Errorf("minimum plays per turn (%d) exceeds the hand size (%d)", r.MinPlaysPerTurn, fullTableHand)
+	}
+	if r.GetBackJump() < 0 {
+		return fmt.Errorf("back jump must not be negative (got %d)", r.GetBackJump())
+	}
+	seen := make(map[string]bool, len(r.Piles))
+	for _, p := range r.Piles {
+		if p.GetId() == "" {
+			return fmt.Errorf("pile IDs must not be empty")
+		}
+		if seen[p.GetId()] {
+			return fmt.Errorf("duplicate pile '%s'", p.GetId())
+		}
+		seen[p.GetId()] = true
+	}
+	return nil
+}
+
+// standardRules are the rules of games that do not set their own. RulesOf
+// returns them to every such game, so they are never modified.
+var standardRules = DefaultRuleSet()
+
+// RulesOf returns the resolved rules of a game. States saved before rule sets
+// existed, and states that leave fields unset, get the standard rules.
+//
+// RulesOf is called for every move, so it only resolves the rules when it has
+// to: games created by NewGameWithRules store their rules resolved, and those
+// are returned as they are. The result may be shared and must not be modified.
+func RulesOf(state *pb.GameState) *RuleSet {
+	rules := state.GetRules()
+	switch {
+	case rules == nil:
+		return standardRules
+	case isResolved(rules):
+		return rules
+	}
+	return ResolveRuleSet(rules)
+}
+
+// isResolved reports whether rules set every field that ResolveRuleSet fills
+// in. HandSize is left out: at zero, it follows the player count.
+func isResolved(rules *RuleSet) bool {
+	return rules.MinCard != 0 && rules.MaxCard != 0 && rules.MaxPlayers != 0 &&
+		rules.MinPlaysPerTurn != 0 && rules.MinPlaysDeckEmpty != 0 &&
+		rules.BackJump != nil && len(rules.Piles) > 0
+}
+
+// HandSizeFor returns how many cards each player is dealt at a table of
+// numPlayers. An explicit HandSize in the rules applies to every table size;
+// otherwise the official sizes are used: 8 cards for one player, 7 for two and
+// 6 for three or more.
+func HandSizeFor(rules *RuleSet, numPlayers int) int {
+	if rules.GetHandSize() > 0 {
+		return int(rules.GetHandSize())
+	}
+	switch {
+	case numPlayers <= 1:
+		return 8
+	case numPlayers == 2:
+		return 7
+	default:
+		return 6
+	}
+}
+
+// MinPlaysToEndTurn returns how many cards the current player must play before
+// ending their turn. No player has to play more cards than they held when the
+// turn began, so a player whose hand has run out simply passes.
+func MinPlaysToEndTurn(state *pb.GameState) int32 {
+	rules := RulesOf(state)
+	required := rules.MinPlaysPerTurn
+	if state.DeckSize == 0 {
+		required = rules.MinPlaysDeckEmpty
+	}
+	// A player view only carries the size of other players' hands.
+	hand, ok := state.Hands[state.CurrentTurnPlayerId]
+	handSize := int32(len(hand.GetCards()))
+	if !ok {
+		handSize = state.HandSizes[state.CurrentTurnPlayerId]
+	}
+	held := state.CardsPlayedThisTurn + handSize
+	if held < required {
+		return held
+	}
+	return required
+}
+
+// IsBackJump reports whether cardValue moves pile in the "wrong" direction by
+// exactly the back-jump distance of the game's rules.
+func IsBackJump(state *pb.GameState, pile *pb.Pile, cardValue int32) bool {
+	return isBackJump(pile, cardValue, RulesOf(state).GetBackJump())
+}
+
+// PileIDs returns the IDs of the game's piles in rule order. Piles that are not
+// named by the rules follow in lexical order.
+func PileIDs(state *pb.GameState) []string {
+	ids := make([]string, 0, len(state.Piles))
+	named := make(map[string]bool)
+	for _, p := range RulesOf(state).Piles {
+		named[p.Id] = true
+		if _, ok := state.Piles[p.Id]; ok {
+			ids = append(ids, p.Id)
+		}
+	}
+	var extra []string
+	for id := range state.Piles {
+		if !named[id] {
+			extra = append(extra, id)
+		}
+	}
+	sort.Strings(extra)
+	return append(ids, extra...)
+}
+
+func isBackJump(pile *pb.Pile, cardValue int32, backJump int32) bool {
+	cards := pile.GetCards()
+	if len(cards) == 0 {
+		return false
+	}
+	return IsBackJumpOnTop(pile.GetAscending(), cards[len(cards)-1].GetValue(), cardValue, backJump)
+}
+
+// canPlay reports whether cardValue may legally be played on pile. Nothing
+// may be played on a pile without its starting card.
+func canPlay(pile *pb.Pile, cardValue int32, backJump int32) bool {
+	cards := pile.GetCards()
+	if len(cards) == 0 {
+		return false
+	}
+	return CanPlayOnTop(pile.GetAscending(), cards[len(cards)-1].GetValue(), cardValue, backJump)
+}
+
+// IsBackJumpOnTop reports whether cardValue moves a pile with the given
+// direction and top card the "wrong" way by exactly backJump. Searches that
+// track only the top of each pile use it instead of IsBackJump. A backJump of
+// 0 means the rules have no back-jumps.
+func IsBackJumpOnTop(ascending bool, top, cardValue, backJump int32) bool {
+	if backJump <= 0 {
+		return false
+	}
+	return (ascending && cardValue == top-backJump) || (!ascending && cardValue == top+backJump)
+}
+
+// CanPlayOnTop reports whether cardValue may legally be played on a pile with
+// the given direction and top card.
+func CanPlayOnTop(ascending bool, top, cardValue, backJump int32) bool {
+	return (ascending && cardValue > top) || (!ascending && cardValue < top) || IsBackJumpOnTop(ascending, top, cardValue, backJump)
+}
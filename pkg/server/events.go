@@ -0,0 +1,175 @@
+package server
+
+import (
+	"log"
+
+	"the_game_card_game/pkg/game"
+	"the_game_card_game/pkg/storage"
+	pb "the_game_card_game/proto"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+// stampEvents numbers events in the order they happened and marks them with
+// the version newState is about to be saved at. The game's event sequence is
+// part of its state, so it is only advanced if the state is saved.
+func stampEvents(version int64, newState *pb.GameState, events []*pb.GameEvent) {
+	for _, event := range events {
+		newState.EventSequence++
+		event.Sequence = newState.EventSequence
+		event.Version = version + 1
+	}
+}
+
+// gameOverEvents returns a GameOver event if newState has ended the game.
+func gameOverEvents(newState *pb.GameState) []*pb.GameEvent {
+	if !game.IsOver(newState) {
+		return nil
+	}
+	return []*pb.GameEvent{{Event: &pb.GameEvent_GameOver{GameOver: &pb.GameOver{
+		Status:  newState.GetStatus(),
+		Winner:  newState.GetWinner(),
+		Message: newState.GetMessage(),
+	}}}}
+}
+
+// endTurnEvents returns the events of playerID ending their turn: the cards
+// they drew beyond their hand of handSize cards, the turn passing on and, if
+// it is over, the end of the game.
+func endTurnEvents(newState *pb.GameState, playerID string, handSize int) []*pb.GameEvent {
+	var events []*pb.GameEvent
+	if drawn := newState.Hands[playerID].GetCards()[handSize:]; len(drawn) > 0 {
+		events = append(events, &pb.GameEvent{Event: &pb.GameEvent_CardsDrawn{CardsDrawn: &pb.CardsDrawn{
+			PlayerId: playerID,
+			Count:    int32(len(drawn)),
+			Cards:    drawn,
+			DeckSize: newState.GetDeckSize(),
+		}}})
+	}
+	events = append(events, &pb.GameEvent{Event: &pb.GameEvent_TurnEnded{TurnEnded: &pb.TurnEnded{
+		PlayerId:     playerID,
+		NextPlayerId: newState.GetCurrentTurnPlayerId(),
+		TurnNumber:   newState.GetTurnNumber(),
+	}}})
+	return append(events, gameOverEvents(newState)...)
+}
+
+// eventView returns the event as it may be sent to playerID: only the player
+// who drew cards, or a trusted caller, sees which cards they were.
+func eventView(event *pb.GameEvent, playerID string, trusted bool) *pb.GameEvent {
+	drawn := event.GetCardsDrawn()
+	if drawn == nil || trusted || drawn.GetPlayerId() == playerID {
+		return event
+	}
+	view := proto.Clone(event).(*pb.GameEvent)
+	view.GetCardsDrawn().Cards = nil
+	return view
+}
+
+// StreamGameEvents streams what happens in a game as typed events instead of
+// whole states. The stream starts with a snapshot of the player's view unless
+// the client resumes after an event sequence it has already seen; snapshots
+// are also sent whenever the hands are redealt or the client has fallen
+// behind what the event log still holds.
+func (s *Server) StreamGameEvents(req *pb.StreamGameEventsRequest, stream pb.GameService_StreamGameEventsServer) error {
+	log.Printf("StreamGameEvents request received for game %s by player %s", req.GetGameId(), req.GetPlayerId())
+	ctx := stream.Context()
+	trusted := s.isTrusted(ctx)
+
+	// The stream is bound to one player's view unless the caller is trusted.
+	if req.GetPlayerId() == "" && !trusted {
+		return status.Error(codes.InvalidArgument, "player_id is required to stream game events")
+	}
+
+	state, err := s.store.GetGameState(ctx, req.GetGameId())
+	if err != nil {
+		return err
+	}
+
+	// sendSnapshot sends the player's view of state in place of the events
+	// that led to it.
+	lastSequence := int64(-1)
+	sendSnapshot := func(state *pb.GameState) error {
+		lastSequence = state.GetEventSequence()
+		return stream.Send(&pb.GameEvent{
+			Sequence: state.GetEventSequence(),
+			Version:  state.GetVersion(),
+			Event:    &pb.GameEvent_Snapshot{Snapshot: s.viewFor(ctx, state, req.GetPlayerId())},
+		})
+	}
+
+	// A client that is behind replays the events it missed from the log; the
+	// log is keyed by version, so it is read from the start and the events the
+	// client has seen are skipped.
+	afterVersion := state.GetVersion()
+	switch {
+	case req.AfterSequence == nil || req.GetAfterSequence() > state.GetEventSequence():
+		if err := sendSnapshot(state); err != nil {
+			return err
+		}
+	case req.GetAfterSequence() < state.GetEventSequence():
+		lastSequence = req.GetAfterSequence()
+		afterVersion = 0
+	default:
+		lastSequence = req.GetAfterSequence()
+	}
+
+	ch, closeSub, err := s.store.SubscribeToGameUpdates(ctx, req.GetGameId(), afterVersion)
+	if err != nil {
+		log.Printf("failed to subscribe to game updates: %v", err)
+		return err
+	}
+	defer closeSub()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Printf("Client for game %s disconnected", req.GetGameId())
+			return nil
+		case update, ok := <-ch:
+			if !ok {
+				log.Printf("Update subscription for game %s closed", req.GetGameId())
+				return nil
+			}
+			sent := false
+			for _, event := range update.Events {
+				if event.GetSequence() <= lastSequence {
+					continue
+				}
+				// Events the log no longer holds are covered by a fresh snapshot.
+				if event.GetSequence() > lastSequence+1 {
+					log.Printf("Events for game %s skipped from %d to %d, resending state", req.GetGameId(), lastSequence, event.GetSequence())
+					state, err := s.store.GetGameState(ctx, req.GetGameId())
+					if err != nil {
+						return err
+					}
+					if err := sendSnapshot(state); err != nil {
+						return err
+					}
+					sent = false
+					break
+				}
+				if err := stream.Send(eventView(event, req.GetPlayerId(), trusted)); err != nil {
+					log.Printf("Error sending event for game %s: %v", req.GetGameId(), err)
+					return err // Client likely disconnected
+				}
+				lastSequence = event.GetSequence()
+				sent = true
+			}
+
+			// Joining and starting redeal every hand, which no event describes.
+			redealt := update.Kind == storage.UpdatePlayerJoined || update.Kind == storage.UpdateGameStarted
+			if sent && redealt {
+				state, err := s.store.GetGameState(ctx, req.GetGameId())
+				if err != nil {
+					return err
+				}
+				if err := sendSnapshot(state); err != nil {
+					return err
+				}
+			}
+		}
+	}
+}
@@ -2,26 +2,158 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"time"
 
+	"the_game_card_game/pkg/bot"
 	"the_game_card_game/pkg/game"
 	"the_game_card_game/pkg/logger"
 	"the_game_card_game/pkg/storage"
 	pb "the_game_card_game/proto"
 
 	"github.com/google/uuid"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
+// AdminTokenHeader is the gRPC metadata key a trusted caller sets to receive
+// the full, unredacted game state.
+const AdminTokenHeader = "x-admin-token"
+
 type Server struct {
 	pb.UnimplementedGameServiceServer
-	store  storage.Storer
-	logger *logger.Logger
+	store      storage.Storer
+	logger     *logger.Logger
+	adminToken string
+	strategies *bot.Registry
+}
+
+// Option configures optional Server behaviour.
+type Option func(*Server)
+
+// WithAdminToken lets callers presenting token in the AdminTokenHeader metadata
+// see the full game state, including the deck and every player's hand.
+func WithAdminToken(token string) Option {
+	return func(s *Server) {
+		s.adminToken = token
+	}
+}
+
+// WithStrategies resolves the strategies players report when they join through
+// registry instead of bot.DefaultRegistry.
+func WithStrategies(registry *bot.Registry) Option {
+	return func(s *Server) {
+		s.strategies = registry
+	}
+}
+
+func NewServer(store storage.Storer, logger *logger.Logger, opts ...Option) *Server {
+	s := &Server{store: store, logger: logger, strategies: bot.DefaultRegistry}
+	for _, opt := range opts {
+		opt(s)
+	}
+	return s
+}
+
+// isTrusted reports whether the caller presented the admin token.
+func (s *Server) isTrusted(ctx context.Context) bool {
+	if s.adminToken == "" {
+		return false
+	}
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	for _, token := range md.Get(AdminTokenHeader) {
+		if token == s.adminToken {
+			return true
+		}
+	}
+	return false
+}
+
+// notInStatus returns a message explaining why an action on state is refused,
+// or "" when the game is in the wanted status.
+func notInStatus(state *pb.GameState, want pb.GameStatus) string {
+	if game.StatusOf(state) == want {
+		return ""
+	}
+	return fmt.Sprintf("game is %s, not %s", game.StatusOf(state), want)
+}
+
+// saveState stores newState only if the game is still at version, the version
+// it was read at, and logs an update of the given kind, with the events that
+// describe it, for subscribers. A concurrent update makes it fail with
+// codes.Aborted so that the caller can re-read the game and retry.
+func (s *Server) saveState(ctx context.Context, gameID string, version int64, newState *pb.GameState, kind storage.UpdateKind, events ...*pb.GameEvent) error {
+	stampEvents(version, newState, events)
+	err := s.store.CompareAndSetGameState(ctx, gameID, version, newState, kind, events...)
+	if errors.Is(err, storage.ErrVersionConflict) {
+		return status.Errorf(codes.Aborted, "game %s changed concurrently, retry: %v", gameID, err)
+	}
+	return err
+}
+
+// recordMoves writes moves to the game history in PostgreSQL, followed by the
+// game's outcome if newState has ended it. Like any history write it happens
+// in the background so that clients are not kept waiting; failures are logged.
+func (s *Server) recordMoves(newState *pb.GameState, moves ...storage.MoveRecord) {
+	var result *storage.GameResult
+	if game.IsOver(newState) {
+		last := moves[len(moves)-1]
+		moves = append(moves, storage.MoveRecord{
+			GameID:      last.GameID,
+			Version:     last.Version,
+			TurnNumber:  newState.GetTurnNumber(),
+			PlayerID:    last.PlayerID,
+			Kind:        storage.MoveGameOver,
+			PilesBefore: last.PilesAfter,
+			PilesAfter:  last.PilesAfter,
+		})
+		result = &storage.GameResult{
+			GameID:         newState.GetGameId(),
+			Status:         newState.GetStatus(),
+			CardsRemaining: game.CardsRemaining(newState),
+			Turns:          newState.GetTurnNumber(),
+		}
+	}
+
+	go func() {
+		ctx := context.Background()
+		for _, move := range moves {
+			if err := s.store.SaveMove(ctx, move); err != nil {
+				log.Printf("failed to save move to postgres: %v", err)
+			}
+		}
+		if result != nil {
+			if err := s.store.FinishGame(ctx, *result); err != nil {
+				log.Printf("failed to finish game in postgres: %v", err)
+			}
+		}
+	}()
 }
 
-func NewServer(store storage.Storer, logger *logger.Logger) *Server {
-	return &Server{store: store, logger: logger}
+// pileTops returns the top card of every pile.
+func pileTops(state *pb.GameState) map[string]int32 {
+	tops := make(map[string]int32, len(state.Piles))
+	for id, pile := range state.Piles {
+		if n := len(pile.GetCards()); n > 0 {
+			tops[id] = pile.Cards[n-1].GetValue()
+		}
+	}
+	return tops
+}
+
+// viewFor returns the state that may be sent to playerID: the full state for
+// trusted callers, and the player's redacted view for everyone else.
+func (s *Server) viewFor(ctx context.Context, state *pb.GameState, playerID string) *pb.GameState {
+	if s.isTrusted(ctx) {
+		return state
+	}
+	return game.PlayerView(state, playerID)
 }
 
 // GameEvent is a generic struct for all game log events.
@@ -35,6 +167,7 @@ type GameEvent struct {
 // GameStartEventPayload contains the data for a 'game_start' event.
 type GameStartEventPayload struct {
 	PlayerID string `json:"player_id"`
+	Seed     int64  `json:"seed"`
 }
 
 // PlayCardEventPayload contains the data for a 'play_card' event.
@@ -51,10 +184,17 @@ type EndTurnEventPayload struct {
 
 // GameOverEventPayload contains the data for a 'game_over' event.
 type GameOverEventPayload struct {
+	Status  string `json:"status"`
 	Winner  string `json:"winner,omitempty"`
 	Message string `json:"message"`
 }
 
+// StartGameEventPayload contains the data for a 'start_game' event.
+type StartGameEventPayload struct {
+	PlayerID  string   `json:"player_id"`
+	PlayerIDs []string `json:"player_ids"`
+}
+
 // PlayerJoinEventPayload contains the data for a 'player_join' event.
 type PlayerJoinEventPayload struct {
 	PlayerID string `json:"player_id"`
@@ -83,12 +223,23 @@ func (s *Server) CreateGame(ctx context.Context, req *pb.CreateGameRequest) (*pb
 		log.Printf("Player ID was not provided, generated a new one: %s", playerID)
 	}
 
+	if err := game.ValidateRuleSet(req.GetRules()); err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "invalid rules: %v", err)
+	}
+
+	// Honour an explicit seed so that a deal can be replayed.
+	seed := time.Now().UnixNano()
+	if req.Seed != nil {
+		seed = req.GetSeed()
+	}
+
 	s.logEvent(gameID, "game_start", GameStartEventPayload{
 		PlayerID: playerID,
+		Seed:     seed,
 	})
 
 	// Create the initial game state using the game logic package
-	initialState := game.NewGame(gameID, playerID)
+	initialState := game.NewGameWithRules(gameID, playerID, seed, req.GetRules())
 
 	// Persist to PostgreSQL
 	if err := s.store.CreateGame(ctx, gameID, playerID); err != nil {
@@ -103,13 +254,23 @@ func (s *Server) CreateGame(ctx context.Context, req *pb.CreateGameRequest) (*pb
 	}
 
 	return &pb.CreateGameResponse{
-		GameState: initialState,
+		GameState: s.viewFor(ctx, initialState, playerID),
 	}, nil
 }
 
 func (s *Server) JoinGame(ctx context.Context, req *pb.JoinGameRequest) (*pb.JoinGameResponse, error) {
 	log.Printf("JoinGame request received for game %s by player %s", req.GetGameId(), req.GetPlayerId())
 
+	// A bot reports its strategy, which is logged with every parameter value.
+	strategy := req.GetStrategy()
+	if strategy != "" {
+		spec, err := s.strategies.Parse(strategy)
+		if err != nil {
+			return &pb.JoinGameResponse{Success: false}, status.Errorf(codes.InvalidArgument, "invalid strategy: %v", err)
+		}
+		strategy = spec.String()
+	}
+
 	// Get current game state from Redis
 	state, err := s.store.GetGameState(ctx, req.GetGameId())
 	if err != nil {
@@ -117,27 +278,48 @@ func (s *Server) JoinGame(ctx context.Context, req *pb.JoinGameRequest) (*pb.Joi
 		return &pb.JoinGameResponse{Success: false}, err
 	}
 
-	// Add the new player to the game
-	// TODO: Make hand size dynamic based on number of players
-	const handSize = 7
-	newState, err := game.AddPlayer(state, req.GetPlayerId(), handSize)
+	// Joining again is harmless: it only records the player's strategy.
+	if _, ok := state.Hands[req.GetPlayerId()]; ok {
+		s.logEvent(req.GetGameId(), "player_join", PlayerJoinEventPayload{
+			PlayerID: req.GetPlayerId(),
+			Strategy: strategy,
+		})
+		return &pb.JoinGameResponse{Success: true, GameState: s.viewFor(ctx, state, req.GetPlayerId())}, nil
+	}
+
+	// Players can only take a seat while the game is in the lobby.
+	if msg := notInStatus(state, pb.GameStatus_WAITING); msg != "" {
+		log.Printf("cannot join game %s: %s", req.GetGameId(), msg)
+		return &pb.JoinGameResponse{Success: false}, status.Errorf(codes.FailedPrecondition, "cannot join: %s", msg)
+	}
+
+	// Seat the new player; every hand is redealt for the new table size.
+	version := state.GetVersion()
+	newState, err := game.AddPlayer(state, req.GetPlayerId())
 	if err != nil {
 		log.Printf("failed to add player: %v", err)
 		return &pb.JoinGameResponse{Success: false}, err
 	}
 
-	s.logEvent(req.GetGameId(), "player_join", PlayerJoinEventPayload{
-		PlayerID: req.GetPlayerId(),
-		Strategy: req.GetStrategy(),
-	})
-
 	// Update the game state in Redis
-	if err := s.store.UpdateGameState(ctx, req.GetGameId(), newState); err != nil {
+	joined := &pb.GameEvent{Event: &pb.GameEvent_PlayerJoined{PlayerJoined: &pb.PlayerJoined{PlayerId: req.GetPlayerId()}}}
+	if err := s.saveState(ctx, req.GetGameId(), version, newState, storage.UpdatePlayerJoined, joined); err != nil {
 		log.Printf("failed to update game state: %v", err)
 		return &pb.JoinGameResponse{Success: false}, err
 	}
 
-	return &pb.JoinGameResponse{Success: true, GameState: newState}, nil
+	// The seat is taken; failing to record it in the history is not critical.
+	seat := len(newState.GetPlayerIds()) - 1
+	if err := s.store.AddPlayer(ctx, req.GetGameId(), req.GetPlayerId(), seat); err != nil {
+		log.Printf("failed to add player to postgres: %v", err)
+	}
+
+	s.logEvent(req.GetGameId(), "player_join", PlayerJoinEventPayload{
+		PlayerID: req.GetPlayerId(),
+		Strategy: strategy,
+	})
+
+	return &pb.JoinGameResponse{Success: true, GameState: s.viewFor(ctx, newState, req.GetPlayerId())}, nil
 }
 
 func (s *Server) PlayCard(ctx context.Context, req *pb.PlayCardRequest) (*pb.PlayCardResponse, error) {
@@ -150,6 +332,12 @@ func (s *Server) PlayCard(ctx context.Context, req *pb.PlayCardRequest) (*pb.Pla
 		return &pb.PlayCardResponse{Success: false, Message: "Game not found"}, err
 	}
 
+	// Cards can only be played while the game is in progress.
+	if msg := notInStatus(state, pb.GameStatus_IN_PROGRESS); msg != "" {
+		log.Printf("%s", msg)
+		return &pb.PlayCardResponse{Success: false, Message: msg}, nil
+	}
+
 	// It must be the player's turn to play.
 	if state.CurrentTurnPlayerId != req.GetPlayerId() {
 		msg := fmt.Sprintf("it is not your turn (current turn: %s)", state.CurrentTurnPlayerId)
@@ -164,40 +352,81 @@ func (s *Server) PlayCard(ctx context.Context, req *pb.PlayCardRequest) (*pb.Pla
 		return &pb.PlayCardResponse{Success: false, Message: err.Error()}, nil
 	}
 
+	// Update the game state in Redis, unless another move got there first.
+	played := &pb.GameEvent{Event: &pb.GameEvent_CardPlayed{CardPlayed: &pb.CardPlayed{
+		PlayerId: req.GetPlayerId(),
+		Card:     &pb.Card{Value: req.GetCard().GetValue()},
+		PileId:   req.GetPileId(),
+	}}}
+	events := append([]*pb.GameEvent{played}, gameOverEvents(newState)...)
+	if err := s.saveState(ctx, req.GetGameId(), state.GetVersion(), newState, storage.UpdateCardPlayed, events...); err != nil {
+		log.Printf("failed to update game state: %v", err)
+		return &pb.PlayCardResponse{Success: false, Message: "Failed to save game state"}, err
+	}
+
 	s.logEvent(req.GetGameId(), "play_card", PlayCardEventPayload{
 		PlayerID:  req.GetPlayerId(),
 		CardValue: req.GetCard().GetValue(),
 		PileID:    req.GetPileId(),
 	})
 
-	if newState.GetGameOver() {
+	if game.IsOver(newState) {
 		s.logEvent(req.GetGameId(), "game_over", GameOverEventPayload{
+			Status:  newState.GetStatus().String(),
 			Message: newState.GetMessage(),
 		})
 	}
 
-	// Update the game state in Redis
-	if err := s.store.UpdateGameState(ctx, req.GetGameId(), newState); err != nil {
-		log.Printf("failed to update game state: %v", err)
-		return &pb.PlayCardResponse{Success: false, Message: "Failed to save game state"}, err
+	// Asynchronously save the move to PostgreSQL
+	s.recordMoves(newState, storage.MoveRecord{
+		GameID:      req.GetGameId(),
+		Version:     newState.GetVersion(),
+		TurnNumber:  newState.GetTurnNumber(),
+		PlayerID:    req.GetPlayerId(),
+		Kind:        storage.MovePlay,
+		Card:        req.GetCard().GetValue(),
+		PileID:      req.GetPileId(),
+		PilesBefore: pileTops(state),
+		PilesAfter:  pileTops(newState),
+	})
+
+	return &pb.PlayCardResponse{Success: true}, nil
+}
+
+func (s *Server) StartGame(ctx context.Context, req *pb.StartGameRequest) (*pb.StartGameResponse, error) {
+	log.Printf("StartGame request received for game %s by player %s", req.GetGameId(), req.GetPlayerId())
+
+	state, err := s.store.GetGameState(ctx, req.GetGameId())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get game state: %w", err)
 	}
 
-	// Notify subscribers that the game state has changed.
-	if err := s.store.PublishGameUpdate(ctx, req.GetGameId()); err != nil {
-		// This is not a critical error, so we just log it.
-		// The game state was updated, but clients won't get the real-time push.
-		log.Printf("failed to publish game update: %v", err)
+	if _, ok := state.Hands[req.GetPlayerId()]; !ok {
+		msg := fmt.Sprintf("player %s has not joined this game", req.GetPlayerId())
+		return &pb.StartGameResponse{Success: false, Message: msg}, nil
+	}
+	if msg := notInStatus(state, pb.GameStatus_WAITING); msg != "" {
+		return &pb.StartGameResponse{Success: false, Message: msg}, nil
 	}
 
-	// Asynchronously save the move to PostgreSQL
-	go func() {
-		err := s.store.SaveMove(context.Background(), req.GetGameId(), req.GetPlayerId(), int(req.GetCard().GetValue()), req.GetPileId())
-		if err != nil {
-			log.Printf("failed to save move to postgres: %v", err)
-		}
-	}()
+	version := state.GetVersion()
+	newState, err := game.StartGame(state)
+	if err != nil {
+		log.Printf("failed to start game %s: %v", req.GetGameId(), err)
+		return &pb.StartGameResponse{Success: false, Message: err.Error()}, nil
+	}
 
-	return &pb.PlayCardResponse{Success: true}, nil
+	started := &pb.GameEvent{Event: &pb.GameEvent_GameStarted{GameStarted: &pb.GameStarted{PlayerId: req.GetPlayerId()}}}
+	if err := s.saveState(ctx, req.GetGameId(), version, newState, storage.UpdateGameStarted, started); err != nil {
+		return nil, fmt.Errorf("failed to update game state: %w", err)
+	}
+
+	s.logEvent(req.GetGameId(), "start_game", StartGameEventPayload{
+		PlayerID:  req.GetPlayerId(),
+		PlayerIDs: newState.GetPlayerIds(),
+	})
+
+	return &pb.StartGameResponse{Success: true, GameState: s.viewFor(ctx, newState, req.GetPlayerId())}, nil
 }
 
 func (s *Server) EndTurn(ctx context.Context, req *pb.EndTurnRequest) (*pb.EndTurnResponse, error) {
@@ -208,81 +437,157 @@ func (s *Server) EndTurn(ctx context.Context, req *pb.EndTurnRequest) (*pb.EndTu
 		return nil, fmt.Errorf("failed to get game state: %w", err)
 	}
 
+	if msg := notInStatus(state, pb.GameStatus_IN_PROGRESS); msg != "" {
+		return &pb.EndTurnResponse{Success: false, Message: msg}, nil
+	}
+
 	// Validate that it's the correct player's turn.
 	if state.CurrentTurnPlayerId != req.GetPlayerId() {
 		msg := fmt.Sprintf("it is not your turn (current turn: %s)", state.CurrentTurnPlayerId)
 		return &pb.EndTurnResponse{Success: false, Message: msg}, nil
 	}
 
+	// EndTurn updates the state in place; keep what the history needs first.
+	version := state.GetVersion()
+	turnNumber := state.GetTurnNumber()
+	pilesBefore := pileTops(state)
+	handSize := len(state.Hands[req.GetPlayerId()].GetCards())
 	newState, err := game.EndTurn(state, req.GetPlayerId())
 	if err != nil {
 		log.Printf("invalid end turn for player %s: %v", req.GetPlayerId(), err)
 		return &pb.EndTurnResponse{Success: false, Message: err.Error()}, nil
 	}
 
+	// Update state in Redis
+	events := endTurnEvents(newState, req.GetPlayerId(), handSize)
+	if err := s.saveState(ctx, req.GetGameId(), version, newState, storage.UpdateTurnEnded, events...); err != nil {
+		return nil, fmt.Errorf("failed to update game state: %w", err)
+	}
+
 	s.logEvent(req.GetGameId(), "end_turn", EndTurnEventPayload{
 		PlayerID: req.GetPlayerId(),
 	})
 
-	if newState.GetGameOver() {
+	if game.IsOver(newState) {
 		s.logEvent(req.GetGameId(), "game_over", GameOverEventPayload{
+			Status:  newState.GetStatus().String(),
 			Winner:  newState.GetWinner(),
 			Message: newState.GetMessage(),
 		})
 	}
 
-	// Update state in Redis
-	if err := s.store.UpdateGameState(ctx, req.GetGameId(), newState); err != nil {
-		return nil, fmt.Errorf("failed to update game state: %w", err)
+	s.recordMoves(newState, storage.MoveRecord{
+		GameID:      req.GetGameId(),
+		Version:     newState.GetVersion(),
+		TurnNumber:  turnNumber,
+		PlayerID:    req.GetPlayerId(),
+		Kind:        storage.MoveEndTurn,
+		PilesBefore: pilesBefore,
+		PilesAfter:  pileTops(newState),
+	})
+
+	return &pb.EndTurnResponse{Success: true, GameState: s.viewFor(ctx, newState, req.GetPlayerId())}, nil
+}
+
+// LeaveGame takes a player away from the table. The game cannot be finished
+// without them, so it is abandoned for everyone.
+func (s *Server) LeaveGame(ctx context.Context, req *pb.LeaveGameRequest) (*pb.LeaveGameResponse, error) {
+	log.Printf("LeaveGame request received for game %s by player %s", req.GetGameId(), req.GetPlayerId())
+
+	state, err := s.store.GetGameState(ctx, req.GetGameId())
+	if err != nil {
+		return nil, fmt.Errorf("failed to get game state: %w", err)
 	}
 
-	// Notify subscribers
-	if err := s.store.PublishGameUpdate(ctx, req.GetGameId()); err != nil {
-		log.Printf("failed to publish game update: %v", err) // Non-critical
+	version := state.GetVersion()
+	turnNumber := state.GetTurnNumber()
+	newState, err := game.Abandon(state, req.GetPlayerId())
+	if err != nil {
+		return &pb.LeaveGameResponse{Success: false, Message: err.Error()}, nil
 	}
 
-	return &pb.EndTurnResponse{Success: true, GameState: newState}, nil
+	if err := s.saveState(ctx, req.GetGameId(), version, newState, storage.UpdateGameLeft, gameOverEvents(newState)...); err != nil {
+		return nil, fmt.Errorf("failed to update game state: %w", err)
+	}
+
+	s.logEvent(req.GetGameId(), "game_over", GameOverEventPayload{
+		Status:  newState.GetStatus().String(),
+		Message: newState.GetMessage(),
+	})
+
+	piles := pileTops(newState)
+	s.recordMoves(newState, storage.MoveRecord{
+		GameID:      req.GetGameId(),
+		Version:     newState.GetVersion(),
+		TurnNumber:  turnNumber,
+		PlayerID:    req.GetPlayerId(),
+		Kind:        storage.MoveLeave,
+		PilesBefore: piles,
+		PilesAfter:  piles,
+	})
+
+	return &pb.LeaveGameResponse{Success: true, GameState: s.viewFor(ctx, newState, req.GetPlayerId())}, nil
 }
 
 func (s *Server) StreamGameState(req *pb.StreamGameStateRequest, stream pb.GameService_StreamGameStateServer) error {
-	log.Printf("StreamGameState request received for game %s", req.GetGameId())
+	log.Printf("StreamGameState request received for game %s by player %s", req.GetGameId(), req.GetPlayerId())
 	ctx := stream.Context()
 
-	// Subscribe to updates first to avoid race conditions.
-	ch, closeSub, err := s.store.SubscribeToGameUpdates(ctx, req.GetGameId())
-	if err != nil {
-		log.Printf("failed to subscribe to game updates: %v", err)
-		return err
+	// The stream is bound to one player's view unless the caller is trusted.
+	if req.GetPlayerId() == "" && !s.isTrusted(ctx) {
+		return status.Error(codes.InvalidArgument, "player_id is required to stream game state")
 	}
-	defer closeSub()
 
-	// After subscribing, get and send the current state.
-	// This ensures we don't miss an update that happens between the initial get and the subscribe.
+	// Send the current state unless the client already has it.
+	lastVersion := int64(-1)
+	if req.ResumeAfter != nil {
+		lastVersion = req.GetResumeAfter()
+	}
 	initialState, err := s.store.GetGameState(ctx, req.GetGameId())
 	if err != nil {
 		return err
 	}
-	if err := stream.Send(initialState); err != nil {
+	if initialState.GetVersion() > lastVersion {
+		if err := stream.Send(s.viewFor(ctx, initialState, req.GetPlayerId())); err != nil {
+			return err
+		}
+		lastVersion = initialState.GetVersion()
+	}
+
+	// Tail the game's event log from the version the client has. Anything
+	// written since the state was read is replayed from the log.
+	ch, closeSub, err := s.store.SubscribeToGameUpdates(ctx, req.GetGameId(), lastVersion)
+	if err != nil {
+		log.Printf("failed to subscribe to game updates: %v", err)
 		return err
 	}
+	defer closeSub()
 
 	for {
 		select {
 		case <-ctx.Done():
 			log.Printf("Client for game %s disconnected", req.GetGameId())
 			return nil
-		case msg := <-ch:
-			// The message content doesn't matter, its arrival is the signal.
-			log.Printf("Received update for game %s via channel %s, sending new state", req.GetGameId(), msg.Channel)
+		case update, ok := <-ch:
+			if !ok {
+				log.Printf("Update subscription for game %s closed", req.GetGameId())
+				return nil
+			}
+			// Replayed updates that the current state already covers are skipped.
+			if update.Version <= lastVersion {
+				continue
+			}
+			log.Printf("Received %s update for game %s (version %d), sending new state", update.Kind, req.GetGameId(), update.Version)
 			newState, err := s.store.GetGameState(ctx, req.GetGameId())
 			if err != nil {
 				log.Printf("Error getting new state for game %s: %v", req.GetGameId(), err)
 				continue
 			}
-			if err := stream.Send(newState); err != nil {
+			if err := stream.Send(s.viewFor(ctx, newState, req.GetPlayerId())); err != nil {
 				log.Printf("Error sending new state for game %s: %v", req.GetGameId(), err)
 				return err // Client likely disconnected
 			}
+			lastVersion = newState.GetVersion()
 		}
 	}
 }
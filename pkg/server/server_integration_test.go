@@ -5,7 +5,9 @@ package server
 import (
 	"context"
 	"os"
+	"path/filepath"
 	"testing"
+	"the_game_card_game/pkg/logger"
 	"the_game_card_game/pkg/storage"
 	pb "the_game_card_game/proto"
 	"time"
@@ -36,7 +38,13 @@ func TestMain(m *testing.M) {
 	}
 	defer testStore.Close()
 
-	testServer = NewServer(testStore)
+	gameLogger, err := logger.New(filepath.Join(os.TempDir(), "game_logs_integration.jsonl"))
+	if err != nil {
+		panic("failed to create game logger: " + err.Error())
+	}
+	defer gameLogger.Close()
+
+	testServer = NewServer(testStore, gameLogger)
 
 	// Run tests
 	os.Exit(m.Run())
@@ -57,9 +65,16 @@ func TestGameLifecycle_Integration(t *testing.T) {
 	require.True(t, joinRes.Success)
 	require.Len(t, joinRes.GameState.PlayerIds, 2, "There should be two players")
 	require.Len(t, joinRes.GameState.Hands["player2"].Cards, 7, "Player 2 should have 7 cards")
+	require.Equal(t, int32(7), joinRes.GameState.HandSizes["player1"], "Player 1 should be redealt 7 cards")
 
-	// 3. Play Card (Valid Move)
-	hand := joinRes.GameState.Hands["player1"].Cards
+	// 3. Start Game
+	startRes, err := testServer.StartGame(ctx, &pb.StartGameRequest{GameId: gameID, PlayerId: "player1"})
+	require.NoError(t, err)
+	require.True(t, startRes.Success)
+	require.Equal(t, pb.GameStatus_IN_PROGRESS, startRes.GameState.Status)
+
+	// 4. Play Card (Valid Move)
+	hand := startRes.GameState.Hands["player1"].Cards
 	var cardToPlay *pb.Card
 	for _, c := range hand {
 		if c.Value > 1 {
@@ -87,18 +102,17 @@ func TestStreamGameState_Integration(t *testing.T) {
 	createRes, err := testServer.CreateGame(ctx, &pb.CreateGameRequest{PlayerId: "integration_stream_player"})
 	require.NoError(t, err)
 	gameID := createRes.GameState.GameId
+	startRes, err := testServer.StartGame(ctx, &pb.StartGameRequest{GameId: gameID, PlayerId: "integration_stream_player"})
+	require.NoError(t, err)
+	require.True(t, startRes.Success)
 
-	// 2. Directly subscribe to the Redis channel for this game
-	channelName := "game-updates:" + gameID
-	pubsub := testStore.Redis.Subscribe(ctx, channelName)
-
-	// Wait for the subscription to be confirmed before proceeding.
-	_, err = pubsub.Receive(ctx)
-	require.NoError(t, err, "failed to subscribe to redis channel")
-	defer pubsub.Close()
+	// 2. Subscribe to the game's updates after it started
+	updates, closeSub, err := testStore.SubscribeToGameUpdates(ctx, gameID, startRes.GameState.Version)
+	require.NoError(t, err, "failed to subscribe to game updates")
+	defer closeSub()
 
 	// 3. Play a card, which should trigger a publish
-	cardToPlay := createRes.GameState.Hands["integration_stream_player"].Cards[0]
+	cardToPlay := startRes.GameState.Hands["integration_stream_player"].Cards[0]
 	_, err = testServer.PlayCard(ctx, &pb.PlayCardRequest{
 		GameId:   gameID,
 		PlayerId: "integration_stream_player",
@@ -109,9 +123,10 @@ func TestStreamGameState_Integration(t *testing.T) {
 
 	// 4. Assert that the message was received on the channel
 	select {
-	case msg, ok := <-pubsub.Channel():
-		require.True(t, ok, "pubsub channel should be open")
-		require.Equal(t, "update", msg.Payload)
+	case update, ok := <-updates:
+		require.True(t, ok, "update channel should be open")
+		require.Equal(t, storage.UpdateCardPlayed, update.Kind)
+		require.Equal(t, int64(2), update.Version, "created at 0, started at 1, played at 2")
 	case <-time.After(2 * time.Second):
 		t.Fatal("timed out waiting for game update notification")
 	}
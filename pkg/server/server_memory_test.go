@@ -0,0 +1,267 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"the_game_card_game/pkg/bot"
+	"the_game_card_game/pkg/game"
+	"the_game_card_game/pkg/storage"
+	pb "the_game_card_game/proto"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/proto"
+)
+
+// newMemoryClient serves a Server backed by a MemoryStore over an in-process
+// gRPC connection, so that tests exercise the full stack without containers.
+func newMemoryClient(t *testing.T) (pb.GameServiceClient, *storage.MemoryStore) {
+	t.Helper()
+	store := storage.NewMemoryStore()
+	lis := bufconn.Listen(1 << 20)
+
+	grpcServer := grpc.NewServer()
+	pb.RegisterGameServiceServer(grpcServer, NewServer(store, newTestLogger(t)))
+	go grpcServer.Serve(lis)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) { return lis.DialContext(ctx) }),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		conn.Close()
+		grpcServer.Stop()
+		store.Close()
+	})
+	return pb.NewGameServiceClient(conn), store
+}
+
+// playGame plays every seat of gameID with a bot until the game ends, and
+// returns the number of cards played.
+func playGame(ctx context.Context, t *testing.T, client pb.GameServiceClient, store *storage.MemoryStore, gameID string) int {
+	t.Helper()
+	strategy := bot.NewSmartStrategy()
+	plays := 0
+	for {
+		state, err := store.GetGameState(ctx, gameID)
+		require.NoError(t, err)
+		if game.IsOver(state) {
+			return plays
+		}
+		playerID := state.CurrentTurnPlayerId
+		playReq, endTurnReq, err := strategy.GetNextMove(playerID, game.PlayerView(state, playerID))
+		require.NoError(t, err)
+		if playReq != nil {
+			res, err := client.PlayCard(ctx, playReq)
+			require.NoError(t, err)
+			require.True(t, res.Success, res.Message)
+			plays++
+			continue
+		}
+		res, err := client.EndTurn(ctx, endTurnReq)
+		require.NoError(t, err)
+		if !res.Success {
+			// Ending the turn early is not allowed; the bot is stuck.
+			return plays
+		}
+	}
+}
+
+func TestFullGame_Memory(t *testing.T) {
+	client, store := newMemoryClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	seed := int64(42)
+	createRes, err := client.CreateGame(ctx, &pb.CreateGameRequest{PlayerId: "alice", Seed: &seed})
+	require.NoError(t, err)
+	gameID := createRes.GameState.GameId
+
+	joinRes, err := client.JoinGame(ctx, &pb.JoinGameRequest{GameId: gameID, PlayerId: "bob"})
+	require.NoError(t, err)
+	require.True(t, joinRes.Success)
+
+	stream, err := client.StreamGameState(ctx, &pb.StreamGameStateRequest{GameId: gameID, PlayerId: "alice"})
+	require.NoError(t, err)
+	first, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, pb.GameStatus_WAITING, first.Status)
+
+	startRes, err := client.StartGame(ctx, &pb.StartGameRequest{GameId: gameID, PlayerId: "alice"})
+	require.NoError(t, err)
+	require.True(t, startRes.Success)
+
+	// The stream pushes the started game to alice.
+	started, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, pb.GameStatus_IN_PROGRESS, started.Status)
+
+	plays := playGame(ctx, t, client, store, gameID)
+
+	final, err := store.GetGameState(ctx, gameID)
+	require.NoError(t, err)
+	require.True(t, game.IsOver(final), "the game should have finished")
+	require.Equal(t, plays, game.CardsPlayed(final))
+
+	// The history is saved asynchronously and ends with the outcome.
+	require.Eventually(t, func() bool {
+		_, finished := store.Result(gameID)
+		return finished
+	}, time.Second, 10*time.Millisecond)
+	result, _ := store.Result(gameID)
+	require.Equal(t, final.Status, result.Status)
+	require.Equal(t, game.CardsRemaining(final), result.CardsRemaining)
+
+	moves := store.Moves(gameID)
+	played := 0
+	for _, move := range moves {
+		if move.Kind == storage.MovePlay {
+			played++
+		}
+	}
+	require.Equal(t, plays, played)
+	require.Equal(t, storage.MoveGameOver, moves[len(moves)-1].Kind)
+	require.Equal(t, final.TurnNumber, moves[len(moves)-1].TurnNumber)
+}
+
+func TestLeaveGame_Memory(t *testing.T) {
+	client, store := newMemoryClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	createRes, err := client.CreateGame(ctx, &pb.CreateGameRequest{PlayerId: "alice"})
+	require.NoError(t, err)
+	gameID := createRes.GameState.GameId
+	_, err = client.JoinGame(ctx, &pb.JoinGameRequest{GameId: gameID, PlayerId: "bob"})
+	require.NoError(t, err)
+	_, err = client.StartGame(ctx, &pb.StartGameRequest{GameId: gameID, PlayerId: "alice"})
+	require.NoError(t, err)
+
+	strangerRes, err := client.LeaveGame(ctx, &pb.LeaveGameRequest{GameId: gameID, PlayerId: "carol"})
+	require.NoError(t, err)
+	require.False(t, strangerRes.Success, "only a seated player can leave")
+
+	leaveRes, err := client.LeaveGame(ctx, &pb.LeaveGameRequest{GameId: gameID, PlayerId: "bob"})
+	require.NoError(t, err)
+	require.True(t, leaveRes.Success, leaveRes.Message)
+	require.Equal(t, pb.GameStatus_ABANDONED, leaveRes.GameState.Status)
+
+	// The game is over for alice too.
+	endRes, err := client.EndTurn(ctx, &pb.EndTurnRequest{GameId: gameID, PlayerId: "alice"})
+	require.NoError(t, err)
+	require.False(t, endRes.Success)
+	require.Contains(t, endRes.Message, "ABANDONED")
+
+	require.Eventually(t, func() bool {
+		_, finished := store.Result(gameID)
+		return finished
+	}, time.Second, 10*time.Millisecond)
+	result, _ := store.Result(gameID)
+	require.Equal(t, pb.GameStatus_ABANDONED, result.Status)
+	moves := store.Moves(gameID)
+	require.Len(t, moves, 2)
+	require.Equal(t, storage.MoveLeave, moves[0].Kind)
+	require.Equal(t, "bob", moves[0].PlayerID)
+	require.Equal(t, storage.MoveGameOver, moves[1].Kind)
+}
+
+func TestStreamGameState_Memory_Resume(t *testing.T) {
+	client, _ := newMemoryClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	seed := int64(7)
+	createRes, err := client.CreateGame(ctx, &pb.CreateGameRequest{PlayerId: "alice", Seed: &seed})
+	require.NoError(t, err)
+	gameID := createRes.GameState.GameId
+	_, err = client.JoinGame(ctx, &pb.JoinGameRequest{GameId: gameID, PlayerId: "bob"})
+	require.NoError(t, err)
+	startRes, err := client.StartGame(ctx, &pb.StartGameRequest{GameId: gameID, PlayerId: "alice"})
+	require.NoError(t, err)
+	require.Equal(t, int64(2), startRes.GameState.Version, "joined at 1, started at 2")
+
+	// A client that last saw the lobby catches up with one state.
+	resumeAfter := int64(1)
+	stream, err := client.StreamGameState(ctx, &pb.StreamGameStateRequest{GameId: gameID, PlayerId: "bob", ResumeAfter: &resumeAfter})
+	require.NoError(t, err)
+	caughtUp, err := stream.Recv()
+	require.NoError(t, err)
+	require.Equal(t, int64(2), caughtUp.Version)
+	require.Equal(t, pb.GameStatus_IN_PROGRESS, caughtUp.Status)
+
+	// A client that is up to date only gets newer states.
+	resumeAfter = 2
+	upToDate, err := client.StreamGameState(ctx, &pb.StreamGameStateRequest{GameId: gameID, PlayerId: "bob", ResumeAfter: &resumeAfter})
+	require.NoError(t, err)
+
+	card := startRes.GameState.Hands["alice"].Cards[0]
+	playRes, err := client.PlayCard(ctx, &pb.PlayCardRequest{GameId: gameID, PlayerId: "alice", Card: card, PileId: "up1"})
+	require.NoError(t, err)
+	require.True(t, playRes.Success)
+
+	for _, s := range []pb.GameService_StreamGameStateClient{stream, upToDate} {
+		next, err := s.Recv()
+		require.NoError(t, err)
+		require.Equal(t, int64(3), next.Version)
+	}
+}
+
+func TestStreamGameEvents_Memory(t *testing.T) {
+	client, store := newMemoryClient(t)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	seed := int64(42)
+	createRes, err := client.CreateGame(ctx, &pb.CreateGameRequest{PlayerId: "alice", Seed: &seed})
+	require.NoError(t, err)
+	gameID := createRes.GameState.GameId
+
+	// Bob follows the game from before he joins to after it ends.
+	stream, err := client.StreamGameEvents(ctx, &pb.StreamGameEventsRequest{GameId: gameID, PlayerId: "bob"})
+	require.NoError(t, err)
+	first, err := stream.Recv()
+	require.NoError(t, err)
+	require.NotNil(t, first.GetSnapshot(), "the stream starts with a snapshot")
+
+	_, err = client.JoinGame(ctx, &pb.JoinGameRequest{GameId: gameID, PlayerId: "bob"})
+	require.NoError(t, err)
+	_, err = client.StartGame(ctx, &pb.StartGameRequest{GameId: gameID, PlayerId: "alice"})
+	require.NoError(t, err)
+	playGame(ctx, t, client, store, gameID)
+
+	final, err := store.GetGameState(ctx, gameID)
+	require.NoError(t, err)
+	require.True(t, game.IsOver(final))
+
+	// Folding the events gives bob's view of the final state.
+	var events []*pb.GameEvent
+	view, err := game.ApplyEvent(nil, first)
+	require.NoError(t, err)
+	for view.EventSequence < final.EventSequence {
+		event, err := stream.Recv()
+		require.NoError(t, err)
+		if drawn := event.GetCardsDrawn(); drawn != nil && drawn.PlayerId != "bob" {
+			require.Empty(t, drawn.Cards, "alice's draws must be hidden from bob")
+		}
+		events = append(events, event)
+		view, err = game.ApplyEvent(view, event)
+		require.NoError(t, err)
+	}
+	require.True(t, proto.Equal(game.PlayerView(final, "bob"), view), "folded events should match the final view")
+	require.NotNil(t, events[len(events)-1].GetGameOver())
+
+	// A client resuming after an event it has seen replays only what follows.
+	resumeAfter := events[len(events)/2].Sequence
+	resumed, err := client.StreamGameEvents(ctx, &pb.StreamGameEventsRequest{GameId: gameID, PlayerId: "bob", AfterSequence: &resumeAfter})
+	require.NoError(t, err)
+	next, err := resumed.Recv()
+	require.NoError(t, err)
+	require.Nil(t, next.GetSnapshot())
+	require.Equal(t, resumeAfter+1, next.Sequence)
+}
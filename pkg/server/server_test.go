@@ -2,23 +2,30 @@ package server
 
 import (
 	"context"
+	"fmt"
+	"path/filepath"
 	"testing"
+	"the_game_card_game/pkg/game"
+	"the_game_card_game/pkg/logger"
+	"the_game_card_game/pkg/storage"
 	"the_game_card_game/pkg/storage/mocks"
 	pb "the_game_card_game/proto"
 
 	"time"
 
-	"github.com/go-redis/redis/v8"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 // Mock Stream for testing
 type mockStream struct {
 	pb.GameService_StreamGameStateServer
-	ctx     context.Context
-	recv    chan *pb.GameState
-	sent    chan struct{} // Signal that a message was sent
+	ctx  context.Context
+	recv chan *pb.GameState
+	sent chan struct{} // Signal that a message was sent
 }
 
 func (m *mockStream) Context() context.Context {
@@ -33,10 +40,18 @@ func (m *mockStream) Send(state *pb.GameState) error {
 	return nil
 }
 
+// newTestLogger returns a game event logger that writes into the test's temp dir.
+func newTestLogger(t *testing.T) *logger.Logger {
+	l, err := logger.New(filepath.Join(t.TempDir(), "game_logs.jsonl"))
+	require.NoError(t, err)
+	t.Cleanup(func() { l.Close() })
+	return l
+}
+
 func TestCreateGame_Unit(t *testing.T) {
 	// 1. Setup
 	mockStore := new(mocks.Storer)
-	server := NewServer(mockStore)
+	server := NewServer(mockStore, newTestLogger(t))
 	ctx := context.Background()
 	req := &pb.CreateGameRequest{PlayerId: "unit-tester"}
 
@@ -51,14 +66,73 @@ func TestCreateGame_Unit(t *testing.T) {
 	require.NoError(t, err)
 	require.NotNil(t, res)
 	require.Equal(t, "unit-tester", res.GameState.PlayerIds[0])
-	require.Len(t, res.GameState.Hands["unit-tester"].Cards, 8)
+	require.Empty(t, res.GameState.Hands, "hands are hidden until the game starts")
+	require.Equal(t, int32(8), res.GameState.HandSizes["unit-tester"])
+	require.Empty(t, res.GameState.Deck, "the deck must be redacted from player responses")
+	mockStore.AssertExpectations(t)
+}
+
+func TestCreateGame_Unit_AdminSeesFullState(t *testing.T) {
+	mockStore := new(mocks.Storer)
+	server := NewServer(mockStore, newTestLogger(t), WithAdminToken("s3cret"))
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(AdminTokenHeader, "s3cret"))
+
+	mockStore.On("CreateGame", mock.Anything, mock.AnythingOfType("string"), "admin").Return(nil)
+	mockStore.On("UpdateGameState", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("*proto.GameState")).Return(nil)
+
+	res, err := server.CreateGame(ctx, &pb.CreateGameRequest{PlayerId: "admin"})
+
+	require.NoError(t, err)
+	require.Len(t, res.GameState.Deck, 90)
+	mockStore.AssertExpectations(t)
+}
+
+func TestCreateGame_Unit_InvalidRules(t *testing.T) {
+	mockStore := new(mocks.Storer)
+	server := NewServer(mockStore, newTestLogger(t))
+
+	_, err := server.CreateGame(context.Background(), &pb.CreateGameRequest{
+		PlayerId: "p1",
+		Rules:    &pb.RuleSet{MinCard: 60, MaxCard: 50},
+	})
+
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	mockStore.AssertExpectations(t)
+}
+
+func TestCreateGame_Unit_Seeded(t *testing.T) {
+	mockStore := new(mocks.Storer)
+	server := NewServer(mockStore, newTestLogger(t))
+	ctx := context.Background()
+	seed := int64(42)
+
+	// The response is redacted, so the seed and deck are checked in the full
+	// state the server stores.
+	var stored []*pb.GameState
+	mockStore.On("CreateGame", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("string")).Return(nil)
+	mockStore.On("UpdateGameState", mock.Anything, mock.AnythingOfType("string"), mock.AnythingOfType("*proto.GameState")).
+		Run(func(args mock.Arguments) { stored = append(stored, args.Get(2).(*pb.GameState)) }).
+		Return(nil)
+
+	_, err := server.CreateGame(ctx, &pb.CreateGameRequest{PlayerId: "p1", Seed: &seed})
+	require.NoError(t, err)
+	_, err = server.CreateGame(ctx, &pb.CreateGameRequest{PlayerId: "p1", Seed: &seed})
+	require.NoError(t, err)
+
+	require.Len(t, stored, 2)
+	first, second := stored[0], stored[1]
+	require.Equal(t, seed, first.Seed)
+	require.Equal(t, seed, second.Seed)
+	require.Equal(t, first.Hands["p1"].Cards, second.Hands["p1"].Cards)
+	require.NotEmpty(t, first.Deck)
+	require.Equal(t, first.Deck, second.Deck)
 	mockStore.AssertExpectations(t)
 }
 
 func TestJoinGame_Unit(t *testing.T) {
 	// 1. Setup
 	mockStore := new(mocks.Storer)
-	server := NewServer(mockStore)
+	server := NewServer(mockStore, newTestLogger(t))
 	ctx := context.Background()
 	gameID := "game-to-join"
 	req := &pb.JoinGameRequest{GameId: gameID, PlayerId: "player2"}
@@ -68,12 +142,14 @@ func TestJoinGame_Unit(t *testing.T) {
 		GameId:    gameID,
 		PlayerIds: []string{"player1"},
 		Deck:      make([]*pb.Card, 20), // A deck with 20 cards
-		Hands:     make(map[string]*pb.Hand),
+		Hands:     map[string]*pb.Hand{"player1": {Cards: make([]*pb.Card, 8)}},
+		Status:    pb.GameStatus_WAITING,
 	}
 
 	// 2. Define Mock Expectations
 	mockStore.On("GetGameState", mock.Anything, gameID).Return(originalState, nil)
-	mockStore.On("UpdateGameState", mock.Anything, gameID, mock.AnythingOfType("*proto.GameState")).Return(nil)
+	mockStore.On("CompareAndSetGameState", mock.Anything, gameID, int64(0), mock.AnythingOfType("*proto.GameState"), storage.UpdatePlayerJoined, mock.Anything).Return(nil)
+	mockStore.On("AddPlayer", mock.Anything, gameID, "player2", 1).Return(nil)
 
 	// 3. Execute
 	res, err := server.JoinGame(ctx, req)
@@ -83,15 +159,44 @@ func TestJoinGame_Unit(t *testing.T) {
 	require.True(t, res.Success)
 	require.Len(t, res.GameState.PlayerIds, 2, "Should now have two players")
 	require.Equal(t, "player2", res.GameState.PlayerIds[1])
-	require.Len(t, res.GameState.Hands["player2"].Cards, 7, "Player 2 should have been dealt 7 cards")
+	require.Empty(t, res.GameState.Hands, "hands are hidden until the game starts")
+	require.Equal(t, int32(7), res.GameState.HandSizes["player2"], "Player 2 should have been dealt 7 cards")
+	require.Equal(t, int32(7), res.GameState.HandSizes["player1"], "Player 1 should have been redealt 7 cards")
 
 	mockStore.AssertExpectations(t)
 }
 
+func TestJoinGame_Unit_AlreadySeated(t *testing.T) {
+	mockStore := new(mocks.Storer)
+	server := NewServer(mockStore, newTestLogger(t))
+	gameID := "game-to-rejoin"
+	originalState := game.NewSeededGame(gameID, "player1", 3)
+
+	mockStore.On("GetGameState", mock.Anything, gameID).Return(originalState, nil)
+
+	res, err := server.JoinGame(context.Background(), &pb.JoinGameRequest{GameId: gameID, PlayerId: "player1", Strategy: "smart"})
+
+	require.NoError(t, err)
+	require.True(t, res.Success)
+	require.Len(t, res.GameState.PlayerIds, 1, "Rejoining must not take a second seat")
+	mockStore.AssertExpectations(t)
+}
+
+func TestJoinGame_Unit_InvalidStrategy(t *testing.T) {
+	mockStore := new(mocks.Storer)
+	server := NewServer(mockStore, newTestLogger(t))
+
+	// Unknown parameters are rejected before the game is read.
+	_, err := server.JoinGame(context.Background(), &pb.JoinGameRequest{GameId: "game", PlayerId: "bot", Strategy: "phased:late=10"})
+
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+	mockStore.AssertExpectations(t)
+}
+
 func TestPlayCard_Unit_Valid(t *testing.T) {
 	// 1. Setup
 	mockStore := new(mocks.Storer)
-	server := NewServer(mockStore)
+	server := NewServer(mockStore, newTestLogger(t))
 	ctx := context.Background()
 	gameID := "game-to-play-in"
 	playerID := "player1"
@@ -102,6 +207,7 @@ func TestPlayCard_Unit_Valid(t *testing.T) {
 		GameId:              gameID,
 		PlayerIds:           []string{playerID},
 		CurrentTurnPlayerId: playerID,
+		Status:              pb.GameStatus_IN_PROGRESS,
 		Piles: map[string]*pb.Pile{
 			"up1": {Ascending: true, Cards: []*pb.Card{{Value: 10}}},
 		},
@@ -114,9 +220,11 @@ func TestPlayCard_Unit_Valid(t *testing.T) {
 
 	// 2. Define Mock Expectations
 	mockStore.On("GetGameState", mock.Anything, gameID).Return(initialState, nil)
-	mockStore.On("UpdateGameState", mock.Anything, gameID, mock.AnythingOfType("*proto.GameState")).Return(nil)
-	mockStore.On("PublishGameUpdate", mock.Anything, gameID).Return(nil)
-	mockStore.On("SaveMove", mock.Anything, gameID, playerID, 15, "up1").Return(nil)
+	mockStore.On("CompareAndSetGameState", mock.Anything, gameID, int64(0), mock.AnythingOfType("*proto.GameState"), storage.UpdateCardPlayed, mock.Anything).Return(nil)
+	mockStore.On("SaveMove", mock.Anything, mock.MatchedBy(func(m storage.MoveRecord) bool {
+		return m.Kind == storage.MovePlay && m.Card == 15 && m.PileID == "up1" &&
+			m.PilesBefore["up1"] == 10 && m.PilesAfter["up1"] == 15
+	})).Return(nil)
 
 	// 3. Execute
 	res, err := server.PlayCard(ctx, req)
@@ -131,35 +239,186 @@ func TestPlayCard_Unit_Valid(t *testing.T) {
 	mockStore.AssertExpectations(t)
 }
 
+func TestPlayCard_Unit_LegacyState(t *testing.T) {
+	mockStore := new(mocks.Storer)
+	server := NewServer(mockStore, newTestLogger(t))
+	gameID := "legacy-game"
+	// Saved before games had a status, so the game is in progress.
+	legacyState := &pb.GameState{
+		GameId:              gameID,
+		PlayerIds:           []string{"player1"},
+		CurrentTurnPlayerId: "player1",
+		Piles: map[string]*pb.Pile{
+			"up1": {Ascending: true, Cards: []*pb.Card{{Value: 10}}},
+		},
+		Hands: map[string]*pb.Hand{
+			"player1": {Cards: []*pb.Card{{Value: 15}, {Value: 25}}},
+		},
+	}
+
+	mockStore.On("GetGameState", mock.Anything, gameID).Return(legacyState, nil)
+	mockStore.On("CompareAndSetGameState", mock.Anything, gameID, int64(0), mock.AnythingOfType("*proto.GameState"), storage.UpdateCardPlayed, mock.Anything).Return(nil)
+	mockStore.On("SaveMove", mock.Anything, mock.Anything).Return(nil).Maybe()
+
+	res, err := server.PlayCard(context.Background(), &pb.PlayCardRequest{GameId: gameID, PlayerId: "player1", Card: &pb.Card{Value: 15}, PileId: "up1"})
+
+	require.NoError(t, err)
+	require.True(t, res.Success, res.Message)
+
+	// A legacy game has already started, so nobody can join or start it.
+	joinRes, err := server.JoinGame(context.Background(), &pb.JoinGameRequest{GameId: gameID, PlayerId: "player2"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	require.ErrorContains(t, err, "IN_PROGRESS")
+	require.False(t, joinRes.Success)
+	time.Sleep(50 * time.Millisecond)
+	mockStore.AssertExpectations(t)
+}
+
+func TestPlayCard_Unit_VersionConflict(t *testing.T) {
+	mockStore := new(mocks.Storer)
+	server := NewServer(mockStore, newTestLogger(t))
+	gameID := "contested-game"
+	state := &pb.GameState{
+		GameId:              gameID,
+		PlayerIds:           []string{"player1"},
+		CurrentTurnPlayerId: "player1",
+		Status:              pb.GameStatus_IN_PROGRESS,
+		Version:             4,
+		Piles: map[string]*pb.Pile{
+			"up1": {Ascending: true, Cards: []*pb.Card{{Value: 10}}},
+		},
+		Hands: map[string]*pb.Hand{
+			"player1": {Cards: []*pb.Card{{Value: 15}, {Value: 25}}},
+		},
+	}
+
+	// Another move was saved after this one read the game at version 4.
+	mockStore.On("GetGameState", mock.Anything, gameID).Return(state, nil)
+	mockStore.On("CompareAndSetGameState", mock.Anything, gameID, int64(4), mock.AnythingOfType("*proto.GameState"), storage.UpdateCardPlayed, mock.Anything).
+		Return(fmt.Errorf("%w: expected version 4, found 5", storage.ErrVersionConflict))
+
+	res, err := server.PlayCard(context.Background(), &pb.PlayCardRequest{GameId: gameID, PlayerId: "player1", Card: &pb.Card{Value: 15}, PileId: "up1"})
+
+	require.Equal(t, codes.Aborted, status.Code(err))
+	require.False(t, res.Success)
+	mockStore.AssertExpectations(t)
+}
+
+func TestPlayCard_Unit_GameOver(t *testing.T) {
+	mockStore := new(mocks.Storer)
+	server := NewServer(mockStore, newTestLogger(t))
+	gameID := "finished-game"
+	lostState := &pb.GameState{
+		GameId:              gameID,
+		PlayerIds:           []string{"player1"},
+		CurrentTurnPlayerId: "player1",
+		Status:              pb.GameStatus_LOST,
+	}
+
+	mockStore.On("GetGameState", mock.Anything, gameID).Return(lostState, nil)
+
+	res, err := server.PlayCard(context.Background(), &pb.PlayCardRequest{GameId: gameID, PlayerId: "player1", Card: &pb.Card{Value: 20}, PileId: "up1"})
+
+	require.NoError(t, err)
+	require.False(t, res.Success)
+	require.Contains(t, res.Message, "LOST")
+	mockStore.AssertExpectations(t)
+}
+
+func TestStartGame_Unit(t *testing.T) {
+	mockStore := new(mocks.Storer)
+	server := NewServer(mockStore, newTestLogger(t))
+	ctx := context.Background()
+	gameID := "game-to-start"
+	lobby := game.NewSeededGame(gameID, "player1", 21)
+
+	mockStore.On("GetGameState", mock.Anything, gameID).Return(lobby, nil)
+	mockStore.On("CompareAndSetGameState", mock.Anything, gameID, int64(0), mock.AnythingOfType("*proto.GameState"), storage.UpdateGameStarted, mock.Anything).Return(nil)
+
+	res, err := server.StartGame(ctx, &pb.StartGameRequest{GameId: gameID, PlayerId: "outsider"})
+	require.NoError(t, err)
+	require.False(t, res.Success, "only seated players may start the game")
+
+	res, err = server.StartGame(ctx, &pb.StartGameRequest{GameId: gameID, PlayerId: "player1"})
+	require.NoError(t, err)
+	require.True(t, res.Success)
+	require.Equal(t, pb.GameStatus_IN_PROGRESS, res.GameState.Status)
+
+	res, err = server.StartGame(ctx, &pb.StartGameRequest{GameId: gameID, PlayerId: "player1"})
+	require.NoError(t, err)
+	require.False(t, res.Success, "a started game cannot be started again")
+
+	_, err = server.JoinGame(ctx, &pb.JoinGameRequest{GameId: gameID, PlayerId: "late-player"})
+	require.Equal(t, codes.FailedPrecondition, status.Code(err))
+	mockStore.AssertExpectations(t)
+}
+
+func TestEndTurn_Unit_GameOver(t *testing.T) {
+	mockStore := new(mocks.Storer)
+	server := NewServer(mockStore, newTestLogger(t))
+	gameID := "game-to-win"
+	lastTurn := &pb.GameState{
+		GameId:              gameID,
+		PlayerIds:           []string{"player1"},
+		CurrentTurnPlayerId: "player1",
+		Status:              pb.GameStatus_IN_PROGRESS,
+		TurnNumber:          30,
+		CardsPlayedThisTurn: 1,
+		Piles: map[string]*pb.Pile{
+			"up1": {Ascending: true, Cards: []*pb.Card{{Value: 1}, {Value: 60}}},
+		},
+		Hands: map[string]*pb.Hand{"player1": {}},
+	}
+
+	mockStore.On("GetGameState", mock.Anything, gameID).Return(lastTurn, nil)
+	mockStore.On("CompareAndSetGameState", mock.Anything, gameID, int64(0), mock.AnythingOfType("*proto.GameState"), storage.UpdateTurnEnded, mock.Anything).Return(nil)
+	mockStore.On("SaveMove", mock.Anything, mock.MatchedBy(func(m storage.MoveRecord) bool {
+		return m.Kind == storage.MoveEndTurn && m.TurnNumber == 30 && m.PilesAfter["up1"] == 60
+	})).Return(nil).Once()
+	mockStore.On("SaveMove", mock.Anything, mock.MatchedBy(func(m storage.MoveRecord) bool {
+		return m.Kind == storage.MoveGameOver
+	})).Return(nil).Once()
+	mockStore.On("FinishGame", mock.Anything, storage.GameResult{GameID: gameID, Status: pb.GameStatus_WON, Turns: 31}).Return(nil)
+
+	res, err := server.EndTurn(context.Background(), &pb.EndTurnRequest{GameId: gameID, PlayerId: "player1"})
+	require.NoError(t, err)
+	require.True(t, res.Success)
+	require.Equal(t, pb.GameStatus_WON, res.GameState.Status)
+
+	// Allow the history goroutine to execute
+	time.Sleep(50 * time.Millisecond)
+	mockStore.AssertExpectations(t)
+}
+
 func TestStreamGameState_Unit(t *testing.T) {
 	mockStore := mocks.NewStorer(t)
-	testServer := NewServer(mockStore)
+	testServer := NewServer(mockStore, newTestLogger(t))
 	gameID := "stream-test-game"
 
 	// 1. Setup mock stream
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 	mockSrv := &mockStream{
-		ctx:     ctx,
-		recv:    make(chan *pb.GameState, 1),
-		sent:    make(chan struct{}, 1),
+		ctx:  ctx,
+		recv: make(chan *pb.GameState, 1),
+		sent: make(chan struct{}, 1),
 	}
 
 	// 2. Setup mock pubsub channel
-	mockPubSubChan := make(chan *redis.Message, 1)
+	mockPubSubChan := make(chan storage.GameUpdate, 1)
 	cleanupFunc := func() {
 		close(mockPubSubChan)
 	}
 
 	// 3. Mock expectations
-	initialState := &pb.GameState{GameId: gameID}
-	mockStore.On("SubscribeToGameUpdates", mock.Anything, gameID).Return((<-chan *redis.Message)(mockPubSubChan), cleanupFunc, nil)
+	initialState := &pb.GameState{GameId: gameID, Version: 3}
+	mockStore.On("SubscribeToGameUpdates", mock.Anything, gameID, int64(3)).Return((<-chan storage.GameUpdate)(mockPubSubChan), cleanupFunc, nil)
 	mockStore.On("GetGameState", mock.Anything, gameID).Return(initialState, nil).Once() // For initial send
 
 	// 4. Run StreamGameState in a goroutine
 	streamErrChan := make(chan error, 1)
 	go func() {
-		streamErrChan <- testServer.StreamGameState(&pb.StreamGameStateRequest{GameId: gameID}, mockSrv)
+		streamErrChan <- testServer.StreamGameState(&pb.StreamGameStateRequest{GameId: gameID, PlayerId: "player1"}, mockSrv)
 	}()
 
 	// 5. Verify initial state is sent
@@ -172,9 +431,9 @@ func TestStreamGameState_Unit(t *testing.T) {
 	}
 
 	// 6. Simulate a game update
-	updatedState := &pb.GameState{GameId: gameID, DeckSize: 50}
+	updatedState := &pb.GameState{GameId: gameID, DeckSize: 50, Version: 4}
 	mockStore.On("GetGameState", mock.Anything, gameID).Return(updatedState, nil).Once() // For update send
-	mockPubSubChan <- &redis.Message{Channel: "game-updates:" + gameID, Payload: "update"}
+	mockPubSubChan <- storage.GameUpdate{GameID: gameID, Version: 4, Kind: storage.UpdateCardPlayed}
 
 	// 7. Verify the update is sent
 	select {
@@ -195,4 +454,14 @@ func TestStreamGameState_Unit(t *testing.T) {
 	}
 
 	mockStore.AssertExpectations(t)
-} 
\ No newline at end of file
+}
+
+func TestStreamGameState_Unit_RequiresPlayer(t *testing.T) {
+	mockStore := mocks.NewStorer(t)
+	testServer := NewServer(mockStore, newTestLogger(t))
+	mockSrv := &mockStream{ctx: context.Background()}
+
+	err := testServer.StreamGameState(&pb.StreamGameStateRequest{GameId: "game"}, mockSrv)
+
+	require.Equal(t, codes.InvalidArgument, status.Code(err))
+}
@@ -0,0 +1,39 @@
+package sim
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// Corpus is a fixed set of deals that strategies are measured on, so that two
+// versions of a strategy can be compared deal by deal. A corpus is versioned
+// and never changed once published; new deals go in a new version.
+type Corpus struct {
+	Version     int     `json:"version"`
+	Description string  `json:"description,omitempty"`
+	Seeds       []int64 `json:"seeds"`
+}
+
+// LoadCorpus reads a corpus file.
+func LoadCorpus(path string) (*Corpus, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &Corpus{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", path, err)
+	}
+	if len(c.Seeds) == 0 {
+		return nil, fmt.Errorf("corpus %s has no seeds", path)
+	}
+	seen := make(map[int64]bool, len(c.Seeds))
+	for _, s := range c.Seeds {
+		if seen[s] {
+			return nil, fmt.Errorf("corpus %s repeats seed %d", path, s)
+		}
+		seen[s] = true
+	}
+	return c, nil
+}
@@ -0,0 +1,178 @@
+package sim
+
+import (
+	"fmt"
+	"math"
+
+	pb "the_game_card_game/proto"
+)
+
+// Paired compares two strategies that played the same deals, deal by deal.
+// Pairing removes the luck of the deal from the comparison, so much smaller
+// differences can be told apart from noise than between separate runs.
+type Paired struct {
+	// Games is the number of deals both strategies played to the end; deals
+	// either failed on are left out and counted in Failed.
+	Games  int `json:"games"`
+	Failed int `json:"failed,omitempty"`
+	// CandidateFailed counts the failed deals that the base played to the
+	// end. A candidate that fails where the base does not is broken, however
+	// well it plays the deals it finishes.
+	CandidateFailed int `json:"candidate_failed,omitempty"`
+
+	BaseWon      int `json:"base_won"`
+	CandidateWon int `json:"candidate_won"`
+	// BaseOnly and CandidateOnly count the deals only one of the two won.
+	BaseOnly      int `json:"base_only"`
+	CandidateOnly int `json:"candidate_only"`
+	// WinRateDiff is the candidate's win rate less the base's. WinRateP is the
+	// two-sided p-value of the difference by an exact McNemar test.
+	WinRateDiff float64 `json:"win_rate_diff"`
+	WinRateP    float64 `json:"win_rate_p"`
+
+	// CardsDiff is the mean over the deals of the candidate's cards remaining
+	// less the base's, so negative is better. CardsLow and CardsHigh bound its
+	// 95% confidence interval, and CardsP is the two-sided p-value of the
+	// difference.
+	CardsDiff float64 `json:"cards_diff"`
+	CardsLow  float64 `json:"cards_low"`
+	CardsHigh float64 `json:"cards_high"`
+	CardsP    float64 `json:"cards_p"`
+	// Better, Worse and Tied count the deals on which the candidate left
+	// fewer, more or as many cards as the base.
+	Better int `json:"better"`
+	Worse  int `json:"worse"`
+	Tied   int `json:"tied"`
+}
+
+// ComparePaired compares the results of a candidate strategy with those of a
+// base strategy on the same deals, matched by seed.
+func ComparePaired(base, candidate []Result) (*Paired, error) {
+	bySeed := make(map[int64]Result, len(base))
+	for _, r := range base {
+		bySeed[r.Seed] = r
+	}
+	if len(bySeed) != len(base) {
+		return nil, fmt.Errorf("base results repeat a deal")
+	}
+	if len(candidate) != len(base) {
+		return nil, fmt.Errorf("%d base results but %d candidate results", len(base), len(candidate))
+	}
+
+	p := &Paired{}
+	var diffs []float64
+	for _, c := range candidate {
+		b, ok := bySeed[c.Seed]
+		if !ok {
+			return nil, fmt.Errorf("the base did not play seed %d", c.Seed)
+		}
+		if b.Err != nil || c.Err != nil {
+			p.Failed++
+			if b.Err == nil {
+				p.CandidateFailed++
+			}
+			continue
+		}
+		p.Games++
+		bWon, cWon := b.Status == pb.GameStatus_WON, c.Status == pb.GameStatus_WON
+		if bWon {
+			p.BaseWon++
+		}
+		if cWon {
+			p.CandidateWon++
+		}
+		switch {
+		case bWon && !cWon:
+			p.BaseOnly++
+		case cWon && !bWon:
+			p.CandidateOnly++
+		}
+		d := c.CardsRemaining - b.CardsRemaining
+		switch {
+		case d < 0:
+			p.Better++
+		case d > 0:
+			p.Worse++
+		default:
+			p.Tied++
+		}
+		diffs = append(diffs, float64(d))
+	}
+	if p.Games == 0 {
+		return p, nil
+	}
+	p.WinRateDiff = float64(p.CandidateWon-p.BaseWon) / float64(p.Games)
+	p.WinRateP = mcNemar(p.BaseOnly, p.CandidateOnly)
+	p.CardsDiff, p.CardsLow, p.CardsHigh, p.CardsP = meanDifference(diffs)
+	return p, nil
+}
+
+// Regressions describes each way in which the candidate is worse than the
+// base: failing deals the base finished, or falling behind it by more than
+// maxWinRateDrop in win rate or maxCardsIncrease in mean cards remaining with
+// a p-value below alpha. It returns nil if there is none.
+func (p *Paired) Regressions(maxWinRateDrop, maxCardsIncrease, alpha float64) []string {
+	var regressions []string
+	if p.CandidateFailed > 0 {
+		regressions = append(regressions, fmt.Sprintf("failed on %d deals the base finished", p.CandidateFailed))
+	}
+	if -p.WinRateDiff > maxWinRateDrop && p.WinRateP < alpha {
+		regressions = append(regressions, fmt.Sprintf("win rate fell by %.2f points", -100*p.WinRateDiff))
+	}
+	if p.CardsDiff > maxCardsIncrease && p.CardsP < alpha {
+		regressions = append(regressions, fmt.Sprintf("%.2f more cards remain per deal", p.CardsDiff))
+	}
+	return regressions
+}
+
+// mcNemar returns the two-sided p-value of an exact McNemar test: the chance,
+// were both strategies equally good, of the deals that only one won splitting
+// at least as unevenly as b to c.
+func mcNemar(b, c int) float64 {
+	n := b + c
+	if n == 0 {
+		return 1
+	}
+	k := b
+	if c < k {
+		k = c
+	}
+	// Twice the lower tail of Binomial(n, 1/2), summed in log space so that
+	// large n does not overflow.
+	lgN, _ := math.Lgamma(float64(n + 1))
+	tail := 0.0
+	for i := 0; i <= k; i++ {
+		lgI, _ := math.Lgamma(float64(i + 1))
+		lgNI, _ := math.Lgamma(float64(n - i + 1))
+		tail += math.Exp(lgN - lgI - lgNI - float64(n)*math.Ln2)
+	}
+	return math.Min(1, 2*tail)
+}
+
+// meanDifference returns the mean of paired differences with its 95%
+// confidence interval and the two-sided p-value of its differing from zero,
+// by the normal approximation, which is sound for the hundreds of deals of a
+// corpus.
+func meanDifference(diffs []float64) (mean, lo, hi, p float64) {
+	n := float64(len(diffs))
+	for _, d := range diffs {
+		mean += d
+	}
+	mean /= n
+	if len(diffs) < 2 {
+		return mean, mean, mean, 1
+	}
+	variance := 0.0
+	for _, d := range diffs {
+		variance += (d - mean) * (d - mean)
+	}
+	se := math.Sqrt(variance / (n - 1) / n)
+	if se == 0 {
+		if mean == 0 {
+			return mean, mean, mean, 1
+		}
+		return mean, mean, mean, 0
+	}
+	z := mean / se
+	return mean, mean - z95*se, mean + z95*se, math.Erfc(math.Abs(z) / math.Sqrt2)
+}
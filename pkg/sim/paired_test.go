@@ -0,0 +1,78 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	pb "the_game_card_game/proto"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestComparePaired(t *testing.T) {
+	won := func(seed int64) Result { return Result{Seed: seed, Status: pb.GameStatus_WON} }
+	lost := func(seed int64, cards int) Result {
+		return Result{Seed: seed, Status: pb.GameStatus_LOST, CardsRemaining: cards}
+	}
+	base := []Result{won(1), lost(2, 10), lost(3, 20), lost(4, 30), {Seed: 5, Err: errors.New("boom")}}
+	candidate := []Result{lost(3, 18), won(1), won(2), lost(4, 30), lost(5, 1)}
+
+	p, err := ComparePaired(base, candidate)
+	require.NoError(t, err)
+	require.Equal(t, 4, p.Games)
+	require.Equal(t, 1, p.Failed)
+	require.Zero(t, p.CandidateFailed, "the base failed the deal")
+	require.Empty(t, p.Regressions(0.01, 0.5, 0.05))
+	require.Equal(t, 1, p.BaseWon)
+	require.Equal(t, 2, p.CandidateWon)
+	require.Equal(t, 0, p.BaseOnly)
+	require.Equal(t, 1, p.CandidateOnly)
+	require.InDelta(t, 0.25, p.WinRateDiff, 1e-9)
+	require.Equal(t, 1.0, p.WinRateP, "one deal proves nothing")
+	require.InDelta(t, -3.0, p.CardsDiff, 1e-9, "(0 - 10 - 2 + 0) / 4")
+	require.Equal(t, [3]int{2, 0, 2}, [3]int{p.Better, p.Worse, p.Tied})
+	require.Less(t, p.CardsLow, p.CardsDiff)
+	require.Greater(t, p.CardsHigh, p.CardsDiff)
+
+	_, err = ComparePaired(base, candidate[:4])
+	require.Error(t, err)
+	_, err = ComparePaired(base, append(candidate[:4:4], won(6)))
+	require.Error(t, err, "the deals must match")
+}
+
+func TestComparePaired_CandidateAlwaysFails(t *testing.T) {
+	var base, candidate []Result
+	for seed := int64(1); seed <= 100; seed++ {
+		base = append(base, Result{Seed: seed, Status: pb.GameStatus_LOST, CardsRemaining: 10})
+		candidate = append(candidate, Result{Seed: seed, Err: errors.New("illegal move")})
+	}
+
+	p, err := ComparePaired(base, candidate)
+	require.NoError(t, err)
+	require.Zero(t, p.Games)
+	require.Equal(t, 100, p.CandidateFailed)
+	require.Equal(t, []string{"failed on 100 deals the base finished"}, p.Regressions(0.01, 0.5, 0.05))
+}
+
+func TestMcNemar(t *testing.T) {
+	require.Equal(t, 1.0, mcNemar(0, 0))
+	require.InDelta(t, 2.0/4096, mcNemar(0, 12), 1e-12)
+	require.InDelta(t, mcNemar(3, 9), mcNemar(9, 3), 1e-12)
+	require.InDelta(t, 0.146, mcNemar(3, 9), 1e-3)
+}
+
+func TestCorpus_PlaysTheCheckedInDeals(t *testing.T) {
+	corpus, err := LoadCorpus("../../corpus/seeds-v1.json")
+	require.NoError(t, err)
+	require.Equal(t, 1, corpus.Version)
+	require.Len(t, corpus.Seeds, 1000, "a published corpus must not change")
+
+	seeds := corpus.Seeds[:5]
+	results, err := Run(context.Background(), Config{Players: 1, Seats: []Seat{seat(t, "smart")}, Seeds: seeds})
+	require.NoError(t, err)
+	require.Len(t, results, len(seeds))
+	for i, r := range results {
+		require.Equal(t, seeds[i], r.Seed)
+	}
+}
@@ -0,0 +1,278 @@
+// Package sim plays games between bot strategies in-process, directly against
+// the game rules, so that strategies can be measured over many deals without
+// a server or any storage.
+package sim
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"runtime"
+	"sort"
+	"sync"
+
+	"the_game_card_game/pkg/bot"
+	"the_game_card_game/pkg/game"
+	pb "the_game_card_game/proto"
+)
+
+// maxTurns bounds the turns in one game, so that a run cannot hang on a game
+// that never ends. A standard game takes well under 100.
+const maxTurns = 1000
+
+// Seat is a strategy taking part in simulated games. New is called once per
+// worker, so strategies that keep state need not be safe for concurrent use.
+type Seat struct {
+	Name string
+	New  func() (bot.TurnStrategy, error)
+}
+
+// SeatsFor resolves strategy specs, such as "phased:early=60", through
+// registry.
+func SeatsFor(registry *bot.Registry, specs []string) ([]Seat, error) {
+	seats := make([]Seat, len(specs))
+	for i, spec := range specs {
+		parsed, err := registry.Parse(spec)
+		if err != nil {
+			return nil, err
+		}
+		seats[i] = Seat{Name: parsed.String(), New: parsed.New}
+	}
+	return seats, nil
+}
+
+// Config describes a simulation run.
+type Config struct {
+	// Players is the number of players at the table.
+	Players int
+	// Seats are the strategies in seating order. When there are fewer seats
+	// than players they are repeated round the table.
+	Seats []Seat
+	// Games is the number of games to play. Game i is dealt from Seed+i.
+	Games int
+	Seed  int64
+	// Seeds, if set, are the seeds of the games to play, such as those of a
+	// Corpus, in place of Games and Seed.
+	Seeds []int64
+	// Workers is the number of games played in parallel; zero means one per CPU.
+	Workers int
+	// Rules are the rules every game is played with; nil means the standard rules.
+	Rules *game.RuleSet
+}
+
+// Result is the outcome of one simulated game.
+type Result struct {
+	Game           int
+	Seed           int64
+	Status         pb.GameStatus
+	CardsRemaining int
+	Turns          int32
+	Plays          int
+	// Err is set if the game could not be played to the end, for example
+	// because a strategy made an illegal move.
+	Err error
+}
+
+// seed returns the seed game i is dealt from.
+func (cfg Config) seed(i int) int64 {
+	if len(cfg.Seeds) > 0 {
+		return cfg.Seeds[i]
+	}
+	return cfg.Seed + int64(i)
+}
+
+// games returns the number of games to play.
+func (cfg Config) games() int {
+	if len(cfg.Seeds) > 0 {
+		return len(cfg.Seeds)
+	}
+	return cfg.Games
+}
+
+// Run plays the games cfg describes and returns their results in game order.
+// It stops early, returning the results so far, if ctx is cancelled.
+func Run(ctx context.Context, cfg Config) ([]Result, error) {
+	if cfg.Players < 1 {
+		return nil, fmt.Errorf("need at least one player, got %d", cfg.Players)
+	}
+	if len(cfg.Seats) == 0 {
+		return nil, fmt.Errorf("need at least one strategy")
+	}
+	if err := game.ValidateRuleSet(cfg.Rules); err != nil {
+		return nil, fmt.Errorf("invalid rules: %w", err)
+	}
+	if max := game.ResolveRuleSet(cfg.Rules).MaxPlayers; int32(cfg.Players) > max {
+		return nil, fmt.Errorf("at most %d players can play, got %d", max, cfg.Players)
+	}
+	// Build every strategy once, so that a bad one fails the run up front.
+	for _, seat := range cfg.Seats {
+		s, err := seat.New()
+		if err != nil {
+			return nil, fmt.Errorf("strategy %s: %w", seat.Name, err)
+		}
+		closeStrategy(s)
+	}
+	workers := cfg.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	games := make(chan int)
+	results := make(chan Result)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			strategies, err := newStrategies(cfg)
+			defer func() {
+				for _, s := range strategies {
+					closeStrategy(s)
+				}
+			}()
+			for i := range games {
+				result := Result{Seed: cfg.seed(i), Err: err}
+				if err == nil {
+					result = PlayGame(ctx, fmt.Sprintf("sim-%d", i), cfg.seed(i), cfg.Rules, strategies)
+				}
+				result.Game = i
+				results <- result
+			}
+		}()
+	}
+
+	go func() {
+		defer close(games)
+		for i := 0; i < cfg.games(); i++ {
+			select {
+			case games <- i:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var all []Result
+	for result := range results {
+		all = append(all, result)
+	}
+	sort.Slice(all, func(i, j int) bool { return all[i].Game < all[j].Game })
+	return all, ctx.Err()
+}
+
+// newStrategies builds a strategy for every player at the table.
+func newStrategies(cfg Config) ([]bot.TurnStrategy, error) {
+	strategies := make([]bot.TurnStrategy, cfg.Players)
+	for i := range strategies {
+		seat := cfg.Seats[i%len(cfg.Seats)]
+		s, err := seat.New()
+		if err != nil {
+			return nil, fmt.Errorf("strategy %s: %w", seat.Name, err)
+		}
+		strategies[i] = s
+	}
+	return strategies, nil
+}
+
+// closeStrategy releases what s holds, such as the program an external
+// strategy runs, if it needs releasing.
+func closeStrategy(s bot.TurnStrategy) {
+	if c, ok := s.(io.Closer); ok {
+		c.Close()
+	}
+}
+
+// PlayGame plays one game dealt from seed, with one player per strategy in
+// seating order. Each strategy only sees its own player's view of the game,
+// and the turns played before.
+func PlayGame(ctx context.Context, gameID string, seed int64, rules *game.RuleSet, strategies []bot.TurnStrategy) Result {
+	result := Result{Seed: seed}
+	state, err := newGame(gameID, seed, rules, len(strategies))
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	var history []bot.Turn
+	viewFor := func(playerID string) *bot.View {
+		return &bot.View{PlayerID: playerID, State: game.PlayerView(state, playerID), History: history}
+	}
+
+	players := make(map[string]bot.TurnStrategy, len(strategies))
+	for i, id := range state.PlayerIds {
+		players[id] = strategies[i]
+		if observer, ok := strategies[i].(bot.GameObserver); ok {
+			if err := observer.GameStarted(ctx, viewFor(id)); err != nil {
+				result.Err = fmt.Errorf("%s could not start the game: %w", id, err)
+				return result
+			}
+			defer func(id string) { observer.GameOver(ctx, viewFor(id)) }(id)
+		}
+	}
+
+	for !game.IsOver(state) {
+		if state.GetTurnNumber() > maxTurns {
+			result.Err = fmt.Errorf("game did not finish after %d turns", maxTurns)
+			break
+		}
+		var turn bot.Turn
+		state, turn, err = playTurn(ctx, state, players[state.CurrentTurnPlayerId], viewFor(state.CurrentTurnPlayerId))
+		result.Plays += len(turn.Moves)
+		if err != nil {
+			result.Err = err
+			break
+		}
+		history = append(history, turn)
+	}
+
+	result.Status = state.GetStatus()
+	result.CardsRemaining = game.CardsRemaining(state)
+	result.Turns = state.GetTurnNumber()
+	return result
+}
+
+// playTurn asks strategy to plan the turn of the current player, then plays
+// it. It returns the state the turn led to, which is the last legal state if
+// the strategy made an illegal move, and the cards that were played.
+func playTurn(ctx context.Context, state *pb.GameState, strategy bot.TurnStrategy, view *bot.View) (*pb.GameState, bot.Turn, error) {
+	playerID := state.CurrentTurnPlayerId
+	turn := bot.Turn{Number: state.GetTurnNumber(), PlayerID: playerID}
+	moves, err := strategy.PlanTurn(ctx, view)
+	if err != nil {
+		return state, turn, fmt.Errorf("turn %d: strategy error for %s: %w", turn.Number, playerID, err)
+	}
+
+	for _, move := range moves {
+		next, err := game.PlayCard(state, playerID, move.Card.GetValue(), move.Pile)
+		if err != nil {
+			return state, turn, fmt.Errorf("turn %d: %s played %d on %s: %w", turn.Number, playerID, move.Card.GetValue(), move.Pile, err)
+		}
+		state = next
+		turn.Moves = append(turn.Moves, move)
+		if game.IsOver(state) {
+			return state, turn, nil
+		}
+	}
+
+	next, err := game.EndTurn(state, playerID)
+	if err != nil {
+		return state, turn, fmt.Errorf("turn %d: %s could not end their turn: %w", turn.Number, playerID, err)
+	}
+	return next, turn, nil
+}
+
+// newGame deals a game for players players named p1, p2 and so on, and starts it.
+func newGame(gameID string, seed int64, rules *game.RuleSet, players int) (*pb.GameState, error) {
+	state := game.NewGameWithRules(gameID, "p1", seed, rules)
+	for i := 2; i <= players; i++ {
+		var err error
+		if state, err = game.AddPlayer(state, fmt.Sprintf("p%d", i)); err != nil {
+			return nil, err
+		}
+	}
+	return game.StartGame(state)
+}
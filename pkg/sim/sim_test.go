@@ -0,0 +1,127 @@
+package sim
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"the_game_card_game/pkg/bot"
+	"the_game_card_game/pkg/game"
+	pb "the_game_card_game/proto"
+
+	"github.com/stretchr/testify/require"
+)
+
+// seat resolves a strategy spec through the default registry.
+func seat(t *testing.T, spec string) Seat {
+	t.Helper()
+	seats, err := SeatsFor(bot.DefaultRegistry, []string{spec})
+	require.NoError(t, err)
+	return seats[0]
+}
+
+func TestRun_ReproducibleAcrossWorkers(t *testing.T) {
+	cfg := Config{Players: 1, Seats: []Seat{seat(t, "smart")}, Games: 20, Seed: 100, Workers: 1}
+	serial, err := Run(context.Background(), cfg)
+	require.NoError(t, err)
+	require.Len(t, serial, 20)
+
+	cfg.Workers = 4
+	parallel, err := Run(context.Background(), cfg)
+	require.NoError(t, err)
+	require.Equal(t, serial, parallel)
+
+	for i, r := range serial {
+		require.Equal(t, i, r.Game)
+		require.Equal(t, int64(100+i), r.Seed)
+		require.NoError(t, r.Err)
+		require.Contains(t, []pb.GameStatus{pb.GameStatus_WON, pb.GameStatus_LOST}, r.Status)
+		require.Equal(t, 98-r.Plays, r.CardsRemaining)
+	}
+}
+
+func TestRun_MultiplePlayers(t *testing.T) {
+	// A short deck runs out early, so players empty their hands while others
+	// still hold cards.
+	rules := &game.RuleSet{MaxCard: 40, MaxPlayers: 4}
+	results, err := Run(context.Background(), Config{Players: 4, Seats: []Seat{seat(t, "smart"), seat(t, "random:seed=3")}, Games: 50, Seed: 1, Rules: rules})
+	require.NoError(t, err)
+
+	// Players who run out of cards pass, so every game is played to the end.
+	for _, r := range results {
+		require.NoError(t, r.Err, "game %d", r.Game)
+		require.True(t, r.Status == pb.GameStatus_WON || r.Status == pb.GameStatus_LOST)
+	}
+}
+
+func TestRun_InvalidConfig(t *testing.T) {
+	_, err := Run(context.Background(), Config{Players: 0, Seats: []Seat{seat(t, "smart")}})
+	require.Error(t, err)
+	_, err = Run(context.Background(), Config{Players: 1})
+	require.Error(t, err)
+	_, err = SeatsFor(bot.DefaultRegistry, []string{"phased:late=3"})
+	require.Error(t, err)
+	_, err = Run(context.Background(), Config{Players: 6, Seats: []Seat{seat(t, "smart")}})
+	require.Error(t, err)
+}
+
+func TestSummarize(t *testing.T) {
+	summary := Summarize([]Result{
+		{Status: pb.GameStatus_WON, CardsRemaining: 0, Turns: 40},
+		{Status: pb.GameStatus_LOST, CardsRemaining: 10, Turns: 30},
+		{Status: pb.GameStatus_LOST, CardsRemaining: 20, Turns: 20},
+		{Err: errors.New("illegal move")},
+	})
+
+	require.Equal(t, Summary{
+		Games:                4,
+		Won:                  1,
+		Lost:                 2,
+		Failed:               1,
+		WinRate:              1.0 / 3,
+		MeanCardsRemaining:   10,
+		MedianCardsRemaining: 10,
+		MeanTurns:            30,
+	}, summary)
+}
+
+// recordingStrategy plays like the smart bot and records what its driver
+// tells it.
+type recordingStrategy struct {
+	bot.TurnStrategy
+	started, over int
+	histories     []int
+}
+
+func (s *recordingStrategy) GameStarted(ctx context.Context, view *bot.View) error {
+	s.started++
+	return nil
+}
+
+func (s *recordingStrategy) GameOver(ctx context.Context, view *bot.View) {
+	s.over++
+}
+
+func (s *recordingStrategy) PlanTurn(ctx context.Context, view *bot.View) ([]game.Move, error) {
+	s.histories = append(s.histories, len(view.History))
+	return s.TurnStrategy.PlanTurn(ctx, view)
+}
+
+func TestPlayGame_HooksAndHistory(t *testing.T) {
+	first := &recordingStrategy{TurnStrategy: bot.AdaptStrategy(bot.NewSmartStrategy())}
+	second := &recordingStrategy{TurnStrategy: bot.AdaptStrategy(bot.NewSmartStrategy())}
+
+	result := PlayGame(context.Background(), "hooks", 9, nil, []bot.TurnStrategy{first, second})
+	require.NoError(t, result.Err)
+
+	for _, s := range []*recordingStrategy{first, second} {
+		require.Equal(t, 1, s.started)
+		require.Equal(t, 1, s.over)
+	}
+	// Players take turns, and each sees every turn completed before theirs.
+	require.Equal(t, 0, first.histories[0])
+	require.Equal(t, 1, second.histories[0])
+	for i := 1; i < len(first.histories); i++ {
+		require.Equal(t, first.histories[i-1]+2, first.histories[i])
+	}
+}
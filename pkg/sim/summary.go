@@ -0,0 +1,70 @@
+package sim
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	pb "the_game_card_game/proto"
+)
+
+// Summary aggregates the results of a simulation run.
+type Summary struct {
+	Games  int
+	Won    int
+	Lost   int
+	Failed int // Games that could not be played to the end.
+
+	WinRate              float64
+	MeanCardsRemaining   float64
+	MedianCardsRemaining int
+	MeanTurns            float64
+}
+
+// Summarize aggregates results. Failed games count towards Games but not
+// towards the win rate or the averages.
+func Summarize(results []Result) Summary {
+	s := Summary{Games: len(results)}
+	var remaining []int
+	totalTurns := 0
+	for _, r := range results {
+		if r.Err != nil {
+			s.Failed++
+			continue
+		}
+		switch r.Status {
+		case pb.GameStatus_WON:
+			s.Won++
+		case pb.GameStatus_LOST:
+			s.Lost++
+		}
+		remaining = append(remaining, r.CardsRemaining)
+		totalTurns += int(r.Turns)
+	}
+	if played := s.Games - s.Failed; played > 0 {
+		s.WinRate = float64(s.Won) / float64(played)
+	}
+	if n := len(remaining); n > 0 {
+		total := 0
+		for _, c := range remaining {
+			total += c
+		}
+		sort.Ints(remaining)
+		s.MeanCardsRemaining = float64(total) / float64(n)
+		s.MedianCardsRemaining = remaining[n/2]
+		s.MeanTurns = float64(totalTurns) / float64(n)
+	}
+	return s
+}
+
+// Print writes the summary in a human-readable form.
+func (s Summary) Print(w io.Writer) {
+	fmt.Fprintf(w, "games:           %d\n", s.Games)
+	fmt.Fprintf(w, "won:             %d (%.2f%%)\n", s.Won, 100*s.WinRate)
+	fmt.Fprintf(w, "lost:            %d\n", s.Lost)
+	if s.Failed > 0 {
+		fmt.Fprintf(w, "failed:          %d\n", s.Failed)
+	}
+	fmt.Fprintf(w, "cards remaining: mean %.2f, median %d\n", s.MeanCardsRemaining, s.MedianCardsRemaining)
+	fmt.Fprintf(w, "turns:           mean %.2f\n", s.MeanTurns)
+}
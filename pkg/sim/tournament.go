@@ -0,0 +1,319 @@
+package sim
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strings"
+
+	"the_game_card_game/pkg/game"
+	pb "the_game_card_game/proto"
+)
+
+// z95 is the normal quantile of a 95% confidence interval.
+const z95 = 1.959964
+
+// Wilson returns the Wilson score interval of a win rate of wins out of n at
+// z standard deviations; z95 gives a 95% interval. Unlike the normal
+// approximation it stays within [0, 1] and is sound for the low win rates of
+// this game.
+func Wilson(wins, n int, z float64) (lo, hi float64) {
+	if n == 0 {
+		return 0, 1
+	}
+	p := float64(wins) / float64(n)
+	nf := float64(n)
+	denom := 1 + z*z/nf
+	centre := (p + z*z/(2*nf)) / denom
+	margin := z * math.Sqrt(p*(1-p)/nf+z*z/(4*nf*nf)) / denom
+	lo, hi = math.Max(0, centre-margin), math.Min(1, centre+margin)
+	// Rounding can leave the bounds a hair off the edges they should touch.
+	if wins == 0 {
+		lo = 0
+	}
+	if wins == n {
+		hi = 1
+	}
+	return lo, hi
+}
+
+// TournamentConfig describes a round robin between strategies.
+type TournamentConfig struct {
+	// Seats are the strategies taking part.
+	Seats []Seat
+	// Players is the size of every table. Every lineup of Players strategies
+	// is played, a strategy taking any number of seats, on the same deals.
+	Players int
+	// Games is the number of deals each lineup plays. Deal i is dealt from
+	// Seed+i, and is played once in every distinct rotation of the lineup's
+	// seats, so that no strategy always moves first.
+	Games   int
+	Seed    int64
+	Workers int
+	Rules   *game.RuleSet
+	// Baseline is the index in Seats of the strategy that stands in for an
+	// absent player when seat values are measured.
+	Baseline int
+}
+
+// TableResult is how one lineup did.
+type TableResult struct {
+	Lineup             []string `json:"lineup"`
+	Games              int      `json:"games"`
+	Failed             int      `json:"failed,omitempty"`
+	Won                int      `json:"won"`
+	WinRate            float64  `json:"win_rate"`
+	WinRateLow         float64  `json:"win_rate_low"`
+	WinRateHigh        float64  `json:"win_rate_high"`
+	MeanCardsRemaining float64  `json:"mean_cards_remaining"`
+}
+
+// StrategyResult is how one strategy did over every table it sat at.
+type StrategyResult struct {
+	Name string `json:"name"`
+	// Games counts each game the strategy took part in once, however many
+	// seats it held. Games that failed are left out.
+	Games              int     `json:"games"`
+	Won                int     `json:"won"`
+	WinRate            float64 `json:"win_rate"`
+	WinRateLow         float64 `json:"win_rate_low"`
+	WinRateHigh        float64 `json:"win_rate_high"`
+	MeanCardsRemaining float64 `json:"mean_cards_remaining"`
+	// SeatCards and SeatWinRate are the strategy's seat value: its Shapley
+	// value in a table, against the baseline filling the seat, averaged over
+	// every seat it held. SeatCards is in cards played, SeatWinRate in win
+	// rate. The baseline's seat value is zero.
+	SeatCards   float64 `json:"seat_cards"`
+	SeatWinRate float64 `json:"seat_win_rate"`
+}
+
+// TournamentResult is the outcome of a round robin.
+type TournamentResult struct {
+	Players    int              `json:"players"`
+	Games      int              `json:"games"`
+	Seed       int64            `json:"seed"`
+	Baseline   string           `json:"baseline"`
+	Strategies []StrategyResult `json:"strategies"`
+	Tables     []TableResult    `json:"tables"`
+}
+
+// lineups returns every multiset of size players drawn from n strategies, as
+// non-decreasing indices.
+func lineups(n, players int) [][]int {
+	var all [][]int
+	lineup := make([]int, players)
+	var fill func(seat, from int)
+	fill = func(seat, from int) {
+		if seat == players {
+			all = append(all, append([]int(nil), lineup...))
+			return
+		}
+		for s := from; s < n; s++ {
+			lineup[seat] = s
+			fill(seat+1, s)
+		}
+	}
+	fill(0, 0)
+	return all
+}
+
+// rotations returns the distinct rotations of lineup: those that differ in
+// which strategy sits in some seat.
+func rotations(lineup []int) [][]int {
+	var all [][]int
+	seen := make(map[string]bool)
+	for r := range lineup {
+		rotated := append(append([]int(nil), lineup[r:]...), lineup[:r]...)
+		if key := fmt.Sprint(rotated); !seen[key] {
+			seen[key] = true
+			all = append(all, rotated)
+		}
+	}
+	return all
+}
+
+func lineupKey(lineup []int) string {
+	sorted := append([]int(nil), lineup...)
+	sort.Ints(sorted)
+	return fmt.Sprint(sorted)
+}
+
+// RunTournament plays every lineup of cfg.Players strategies on the same
+// deals. It gives up if ctx is cancelled.
+func RunTournament(ctx context.Context, cfg TournamentConfig) (*TournamentResult, error) {
+	n := len(cfg.Seats)
+	if n == 0 {
+		return nil, fmt.Errorf("need at least one strategy")
+	}
+	if cfg.Players < 1 {
+		return nil, fmt.Errorf("need at least one player, got %d", cfg.Players)
+	}
+	if cfg.Baseline < 0 || cfg.Baseline >= n {
+		return nil, fmt.Errorf("baseline %d is not one of the %d strategies", cfg.Baseline, n)
+	}
+	rules := game.ResolveRuleSet(cfg.Rules)
+	cards := float64(rules.MaxCard - rules.MinCard + 1)
+
+	type played struct {
+		summary Summary
+		results []Result
+	}
+	tables := make(map[string]played)
+	result := &TournamentResult{Players: cfg.Players, Games: cfg.Games, Seed: cfg.Seed, Baseline: cfg.Seats[cfg.Baseline].Name}
+	all := lineups(n, cfg.Players)
+	for _, lineup := range all {
+		names := make([]string, len(lineup))
+		for i, s := range lineup {
+			names[i] = cfg.Seats[s].Name
+		}
+		var results []Result
+		for _, order := range rotations(lineup) {
+			seats := make([]Seat, len(order))
+			for i, s := range order {
+				seats[i] = cfg.Seats[s]
+			}
+			rotated, err := Run(ctx, Config{Players: cfg.Players, Seats: seats, Games: cfg.Games, Seed: cfg.Seed, Workers: cfg.Workers, Rules: cfg.Rules})
+			if err != nil {
+				return nil, fmt.Errorf("table %s: %w", strings.Join(names, "; "), err)
+			}
+			results = append(results, rotated...)
+		}
+		summary := Summarize(results)
+		tables[lineupKey(lineup)] = played{summary: summary, results: results}
+		lo, hi := Wilson(summary.Won, summary.Games-summary.Failed, z95)
+		result.Tables = append(result.Tables, TableResult{
+			Lineup:             names,
+			Games:              summary.Games,
+			Failed:             summary.Failed,
+			Won:                summary.Won,
+			WinRate:            summary.WinRate,
+			WinRateLow:         lo,
+			WinRateHigh:        hi,
+			MeanCardsRemaining: summary.MeanCardsRemaining,
+		})
+	}
+
+	// value returns the cards played and win rate of lineup with only the
+	// seats in the bit set in kept, the rest taken by the baseline.
+	value := func(lineup []int, kept int) (float64, float64) {
+		seated := make([]int, len(lineup))
+		for i, s := range lineup {
+			seated[i] = cfg.Baseline
+			if kept&(1<<i) != 0 {
+				seated[i] = s
+			}
+		}
+		s := tables[lineupKey(seated)].summary
+		return cards - s.MeanCardsRemaining, s.WinRate
+	}
+
+	// weight[k] is the Shapley weight of a coalition of k seats that a further
+	// seat joins: k!(players-k-1)!/players!.
+	weight := make([]float64, cfg.Players)
+	for k := range weight {
+		weight[k] = factorial(k) * factorial(cfg.Players-k-1) / factorial(cfg.Players)
+	}
+
+	stats := make([]StrategyResult, n)
+	seatsHeld := make([]int, n)
+	remaining := make([]int, n)
+	for i, seat := range cfg.Seats {
+		stats[i].Name = seat.Name
+	}
+	for _, lineup := range all {
+		t := tables[lineupKey(lineup)]
+		present := make(map[int]bool)
+		for _, s := range lineup {
+			present[s] = true
+		}
+		for s := range present {
+			for _, r := range t.results {
+				if r.Err != nil {
+					continue
+				}
+				stats[s].Games++
+				remaining[s] += r.CardsRemaining
+				if r.Status == pb.GameStatus_WON {
+					stats[s].Won++
+				}
+			}
+		}
+
+		for seat, s := range lineup {
+			var cardsValue, winValue float64
+			for kept := 0; kept < 1<<cfg.Players; kept++ {
+				if kept&(1<<seat) != 0 {
+					continue
+				}
+				k := bitCount(kept)
+				withCards, withWins := value(lineup, kept|1<<seat)
+				withoutCards, withoutWins := value(lineup, kept)
+				cardsValue += weight[k] * (withCards - withoutCards)
+				winValue += weight[k] * (withWins - withoutWins)
+			}
+			stats[s].SeatCards += cardsValue
+			stats[s].SeatWinRate += winValue
+			seatsHeld[s]++
+		}
+	}
+	for s := range stats {
+		if g := stats[s].Games; g > 0 {
+			stats[s].WinRate = float64(stats[s].Won) / float64(g)
+			stats[s].WinRateLow, stats[s].WinRateHigh = Wilson(stats[s].Won, g, z95)
+			stats[s].MeanCardsRemaining = float64(remaining[s]) / float64(g)
+		}
+		if seatsHeld[s] > 0 {
+			stats[s].SeatCards /= float64(seatsHeld[s])
+			stats[s].SeatWinRate /= float64(seatsHeld[s])
+		}
+	}
+	result.Strategies = stats
+	return result, nil
+}
+
+func factorial(n int) float64 {
+	f := 1.0
+	for i := 2; i <= n; i++ {
+		f *= float64(i)
+	}
+	return f
+}
+
+func bitCount(x int) int {
+	n := 0
+	for ; x != 0; x &= x - 1 {
+		n++
+	}
+	return n
+}
+
+// WriteMarkdown writes the result as Markdown tables, strategies ranked by
+// seat value.
+func (r *TournamentResult) WriteMarkdown(w io.Writer) {
+	fmt.Fprintf(w, "# Tournament\n\n")
+	fmt.Fprintf(w, "%d games per table of %d, dealt from seed %d. Seat values are against %s.\n\n", r.Games, r.Players, r.Seed, r.Baseline)
+
+	strategies := append([]StrategyResult(nil), r.Strategies...)
+	sort.SliceStable(strategies, func(i, j int) bool { return strategies[i].SeatCards > strategies[j].SeatCards })
+	fmt.Fprintf(w, "## Strategies\n\n")
+	fmt.Fprintf(w, "| Strategy | Games | Win rate | 95%% interval | Mean cards left | Seat value (cards) | Seat value (win rate) |\n")
+	fmt.Fprintf(w, "|---|---:|---:|---:|---:|---:|---:|\n")
+	for _, s := range strategies {
+		fmt.Fprintf(w, "| %s | %d | %.2f%% | %.2f%%–%.2f%% | %.2f | %+.2f | %+.2f%% |\n",
+			s.Name, s.Games, 100*s.WinRate, 100*s.WinRateLow, 100*s.WinRateHigh, s.MeanCardsRemaining, s.SeatCards, 100*s.SeatWinRate)
+	}
+
+	fmt.Fprintf(w, "\n## Tables\n\n")
+	fmt.Fprintf(w, "| Lineup | Games | Win rate | 95%% interval | Mean cards left |\n")
+	fmt.Fprintf(w, "|---|---:|---:|---:|---:|\n")
+	for _, t := range r.Tables {
+		games := fmt.Sprint(t.Games)
+		if t.Failed > 0 {
+			games += fmt.Sprintf(" (%d failed)", t.Failed)
+		}
+		fmt.Fprintf(w, "| %s | %s | %.2f%% | %.2f%%–%.2f%% | %.2f |\n",
+			strings.Join(t.Lineup, "; "), games, 100*t.WinRate, 100*t.WinRateLow, 100*t.WinRateHigh, t.MeanCardsRemaining)
+	}
+}
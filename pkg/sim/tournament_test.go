@@ -0,0 +1,64 @@
+package sim
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWilson(t *testing.T) {
+	lo, hi := Wilson(5, 10, z95)
+	require.InDelta(t, 0.2366, lo, 1e-4)
+	require.InDelta(t, 0.7634, hi, 1e-4)
+
+	lo, hi = Wilson(0, 10, z95)
+	require.Zero(t, lo)
+	require.InDelta(t, 0.2775, hi, 1e-4)
+
+	lo, hi = Wilson(0, 0, z95)
+	require.Equal(t, [2]float64{0, 1}, [2]float64{lo, hi}, "no games say nothing")
+}
+
+func TestRotations(t *testing.T) {
+	require.Equal(t, [][]int{{0, 1, 2}, {1, 2, 0}, {2, 0, 1}}, rotations([]int{0, 1, 2}))
+	require.Equal(t, [][]int{{0, 1, 0, 1}, {1, 0, 1, 0}}, rotations([]int{0, 1, 0, 1}))
+	require.Equal(t, [][]int{{2, 2}}, rotations([]int{2, 2}))
+}
+
+func TestRunTournament_SoloSeatValueIsTheDifferenceFromTheBaseline(t *testing.T) {
+	cfg := TournamentConfig{Seats: []Seat{seat(t, "random:seed=1"), seat(t, "smart")}, Players: 1, Games: 30, Seed: 1}
+	r, err := RunTournament(context.Background(), cfg)
+	require.NoError(t, err)
+	require.Len(t, r.Tables, 2)
+
+	random, smart := r.Strategies[0], r.Strategies[1]
+	require.Zero(t, random.SeatCards, "the baseline adds nothing over itself")
+	require.InDelta(t, random.MeanCardsRemaining-smart.MeanCardsRemaining, smart.SeatCards, 1e-9)
+	require.InDelta(t, smart.WinRate-random.WinRate, smart.SeatWinRate, 1e-9)
+}
+
+func TestRunTournament_PlaysEveryLineup(t *testing.T) {
+	cfg := TournamentConfig{Seats: []Seat{seat(t, "random:seed=1"), seat(t, "smart"), seat(t, "counting")}, Players: 2, Games: 10, Seed: 1}
+	r, err := RunTournament(context.Background(), cfg)
+	require.NoError(t, err)
+	require.Len(t, r.Tables, 6, "three strategies fill two seats six ways")
+	for _, s := range r.Strategies {
+		// Each strategy sits at one table with itself, and at two with
+		// another strategy, where each deal is played with either seated
+		// first.
+		require.Equal(t, 5*cfg.Games, s.Games, "%s sits at three of the tables", s.Name)
+		require.LessOrEqual(t, s.WinRateLow, s.WinRate)
+		require.GreaterOrEqual(t, s.WinRateHigh, s.WinRate)
+	}
+	require.Greater(t, r.Strategies[1].SeatCards, 0.0, "smart plays more cards than random")
+
+	var md bytes.Buffer
+	r.WriteMarkdown(&md)
+	require.Contains(t, md.String(), "| smart; counting:held_weight=2,back_jump_value=10,draws=2,max_extra_cost=0 | 20 |")
+	require.Contains(t, md.String(), "Seat values are against random:seed=1")
+
+	_, err = RunTournament(context.Background(), TournamentConfig{Seats: cfg.Seats, Players: 2, Baseline: 3})
+	require.Error(t, err)
+}
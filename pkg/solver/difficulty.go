@@ -0,0 +1,54 @@
+package solver
+
+// Difficulty labels a deal by how hard the search found it to win.
+type Difficulty int
+
+const (
+	// Unknown deals were neither won nor searched completely.
+	Unknown Difficulty = iota
+	// Easy deals are won on one of the first lines searched, close to the
+	// preferred order of moves.
+	Easy
+	// Medium deals are won after some search.
+	Medium
+	// Hard deals are won only after a long search.
+	Hard
+	// Unwinnable deals were searched completely without a win.
+	Unwinnable
+)
+
+// Searches that find a win within easyNodes positions are easy, and within
+// mediumNodes medium.
+const (
+	easyNodes   = 10000
+	mediumNodes = 100000
+)
+
+func (d Difficulty) String() string {
+	switch d {
+	case Easy:
+		return "easy"
+	case Medium:
+		return "medium"
+	case Hard:
+		return "hard"
+	case Unwinnable:
+		return "unwinnable"
+	}
+	return "unknown"
+}
+
+// Difficulty labels the deal the search was run on.
+func (r *Result) Difficulty() Difficulty {
+	switch {
+	case r.Winnable && r.NodesToWin <= easyNodes:
+		return Easy
+	case r.Winnable && r.NodesToWin <= mediumNodes:
+		return Medium
+	case r.Winnable:
+		return Hard
+	case r.Exact:
+		return Unwinnable
+	}
+	return Unknown
+}
@@ -0,0 +1,468 @@
+// Package solver plays solo games with perfect information. Once a seeded deal
+// is known, so is the order of the whole deck, and a search over every line
+// of play can decide whether the deal can be won at all. That is the ceiling
+// any strategy can reach, and a measure of how hard each deal is.
+package solver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/bits"
+	"sort"
+
+	"the_game_card_game/pkg/game"
+	pb "the_game_card_game/proto"
+)
+
+const (
+	// DefaultMaxNodes is the search budget when Options leaves it unset.
+	DefaultMaxNodes = 1000000
+
+	// maxPiles and maxCards are the largest games the search can represent:
+	// pile tops are kept in a fixed array, and the cards still to be played in
+	// a 128-bit set.
+	maxPiles = 8
+	maxCards = 128
+)
+
+// errBudget stops a search that has expanded its budget of positions.
+var errBudget = errors.New("search budget exhausted")
+
+// Options configures a search.
+type Options struct {
+	// MaxNodes bounds the positions expanded; 0 means DefaultMaxNodes. A
+	// search that runs out returns the best line found so far.
+	MaxNodes int
+}
+
+// Result is the outcome of a search.
+type Result struct {
+	// Winnable is whether a winning line was found.
+	Winnable bool
+	// Exact is whether the search was completed, by finding a win or by
+	// following every line. If so, CardsRemaining is the fewest cards the game
+	// can end with; otherwise it is only the fewest found.
+	Exact bool
+	// CardsRemaining is the number of cards left at the end of the best line.
+	CardsRemaining int
+	// Turns holds the cards played in each turn of the best line, in order.
+	// Each turn is ended once its cards have been played, unless the game is
+	// over.
+	Turns [][]game.Move
+	// Nodes is the number of positions expanded, and NodesToWin the number
+	// expanded by the time a winning line was found.
+	Nodes      int
+	NodesToWin int
+}
+
+// Solve searches for the best line of play from state, which must be a solo
+// game with its deck visible. The search gives up when ctx is done.
+//
+// The search is depth first, trying the most promising moves first, and runs
+// in passes: the first pass follows only the preferred move everywhere, and
+// each pass after it allows one more departure from the preferred order. An
+// early misstep is therefore reconsidered long before every line beneath it
+// has been tried. Positions already explored are remembered, and lines that
+// can no longer end with fewer cards than the best so far are cut short.
+func Solve(ctx context.Context, state *pb.GameState, opts Options) (*Result, error) {
+	s, err := newSearch(ctx, state, opts)
+	if err != nil {
+		return nil, err
+	}
+	if game.IsOver(state) {
+		return &Result{Winnable: state.Status == pb.GameStatus_WON, Exact: true, CardsRemaining: game.CardsRemaining(state)}, nil
+	}
+
+	// Each pass allows one more discrepancy, until a pass explores every line
+	// or finds a win.
+	for discrepancies := 0; ; discrepancies++ {
+		s.limited = false
+		err = s.search(s.root, discrepancies)
+		if err != nil || s.bestValue == 0 || !s.limited {
+			break
+		}
+	}
+	if err != nil && err != errBudget {
+		return nil, err
+	}
+	r := &Result{
+		Winnable:       s.bestValue == 0,
+		Exact:          err == nil,
+		CardsRemaining: s.bestValue,
+		Turns:          s.turns(),
+		Nodes:          s.nodes,
+		NodesToWin:     s.nodesToWin,
+	}
+	if r.CardsRemaining > len(s.cards) {
+		// The budget ran out before any line was played to the end.
+		r.CardsRemaining = len(s.cards)
+	}
+	return r, nil
+}
+
+// position is a point in a game. Cards are identified by their index in
+// search.cards: the hand the search started from, then the deck in order.
+type position struct {
+	tops   [maxPiles]int32
+	hand   cardSet
+	drawn  int // Cards drawn from the deck since the search started.
+	played int // Cards played this turn.
+}
+
+// cardSet is a set of card indices.
+type cardSet [2]uint64
+
+func (c cardSet) has(i int) bool { return c[i/64]&(1<<(i%64)) != 0 }
+func (c *cardSet) add(i int)     { c[i/64] |= 1 << (i % 64) }
+func (c *cardSet) remove(i int)  { c[i/64] &^= 1 << (i % 64) }
+
+// memoKey identifies a position up to the order of piles that run the same
+// way, which are interchangeable.
+type memoKey struct {
+	tops   [maxPiles]int32
+	hand   cardSet
+	drawn  int16
+	played int16
+}
+
+// memoEntry records that a position has been explored with a number of
+// discrepancies to spare, and whether that explored every line from it.
+type memoEntry struct {
+	discrepancies int16
+	complete      bool
+}
+
+// step is a move on the current line: a play of a card on a pile, or the end
+// of the turn if card is -1.
+type step struct {
+	card, pile int
+}
+
+type search struct {
+	ctx       context.Context
+	pileIDs   []string
+	ascending []bool
+	ascIdx    []int
+	descIdx   []int
+	backJump  int32
+	minPlays  int
+	minEmpty  int
+	handSize  int // Cards in the hand the search started from.
+	cards     []int32
+	root      position
+
+	// remaining[v] is whether card v has still to be played.
+	remaining []bool
+	minCard   int32
+	maxCard   int32
+
+	memo     map[memoKey]memoEntry
+	nodes    int
+	maxNodes int
+	// limited is set when a search leaves lines unexplored for want of
+	// discrepancies.
+	limited bool
+
+	line       []step
+	bestValue  int
+	bestLine   []step
+	nodesToWin int
+}
+
+func newSearch(ctx context.Context, state *pb.GameState, opts Options) (*search, error) {
+	if len(state.GetPlayerIds()) != 1 {
+		return nil, fmt.Errorf("only solo games can be solved, game %s has %d players", state.GetGameId(), len(state.GetPlayerIds()))
+	}
+	if int(state.GetDeckSize()) != len(state.GetDeck()) {
+		return nil, fmt.Errorf("the deck of game %s is hidden", state.GetGameId())
+	}
+	if len(state.GetPiles()) > maxPiles {
+		return nil, fmt.Errorf("%d piles are too many to search", len(state.GetPiles()))
+	}
+	hand := state.Hands[state.PlayerIds[0]].GetCards()
+	if n := len(hand) + len(state.Deck); n > maxCards {
+		return nil, fmt.Errorf("%d cards are too many to search", n)
+	}
+
+	rules := game.RulesOf(state)
+	s := &search{
+		ctx:       ctx,
+		pileIDs:   game.PileIDs(state),
+		backJump:  rules.GetBackJump(),
+		minPlays:  int(rules.MinPlaysPerTurn),
+		minEmpty:  int(rules.MinPlaysDeckEmpty),
+		handSize:  len(hand),
+		minCard:   rules.MinCard,
+		maxCard:   rules.MaxCard,
+		remaining: make([]bool, rules.MaxCard+1),
+		memo:      make(map[memoKey]memoEntry),
+		maxNodes:  opts.MaxNodes,
+	}
+	if s.maxNodes <= 0 {
+		s.maxNodes = DefaultMaxNodes
+	}
+	for i, id := range s.pileIDs {
+		pile := state.Piles[id]
+		s.ascending = append(s.ascending, pile.Ascending)
+		if pile.Ascending {
+			s.ascIdx = append(s.ascIdx, i)
+		} else {
+			s.descIdx = append(s.descIdx, i)
+		}
+		s.root.tops[i] = pile.Cards[len(pile.Cards)-1].GetValue()
+	}
+	for _, c := range append(append([]*pb.Card(nil), hand...), state.Deck...) {
+		s.cards = append(s.cards, c.GetValue())
+		s.remaining[c.GetValue()] = true
+	}
+	for i := range hand {
+		s.root.hand.add(i)
+	}
+	s.root.played = int(state.CardsPlayedThisTurn)
+	s.bestValue = len(s.cards) + 1
+	return s, nil
+}
+
+// search explores the lines from pos that depart from the preferred order
+// of moves by at most discrepancies, recording the best line found whenever a
+// game ends with fewer cards than before. The n-th move in the order costs n
+// discrepancies. Lines that cannot beat the best so far are not followed.
+func (s *search) search(pos position, discrepancies int) error {
+	handCount := bits.OnesCount64(pos.hand[0]) + bits.OnesCount64(pos.hand[1])
+	deckLeft := len(s.cards) - s.handSize - pos.drawn
+	left := handCount + deckLeft
+	if left == 0 {
+		s.finish(0)
+		return nil
+	}
+
+	key := s.key(pos)
+	if entry, ok := s.memo[key]; ok && (entry.complete || int(entry.discrepancies) >= discrepancies) {
+		s.limited = s.limited || !entry.complete
+		return nil
+	}
+	if s.nodes%1024 == 0 {
+		if err := s.ctx.Err(); err != nil {
+			return err
+		}
+	}
+	if s.nodes >= s.maxNodes {
+		return errBudget
+	}
+	s.nodes++
+
+	if s.deadCards(pos.tops) >= s.bestValue {
+		s.memo[key] = memoEntry{complete: true}
+		return nil
+	}
+	moves := s.moves(pos, handCount, deckLeft)
+	if len(moves) == 0 {
+		s.finish(left)
+		s.memo[key] = memoEntry{complete: true}
+		return nil
+	}
+
+	// limited is set below if any line from here is left unexplored.
+	outerLimited := s.limited
+	s.limited = false
+	for i, m := range moves {
+		if i > discrepancies {
+			s.limited = true
+			break
+		}
+		next := pos
+		if m.card < 0 {
+			// Ending the turn draws a card for every card played.
+			for n := 0; n < pos.played && next.drawn < len(s.cards)-s.handSize; n++ {
+				next.hand.add(s.handSize + next.drawn)
+				next.drawn++
+			}
+			next.played = 0
+		} else {
+			next.hand.remove(m.card)
+			next.tops[m.pile] = s.cards[m.card]
+			next.played++
+			s.remaining[s.cards[m.card]] = false
+		}
+		s.line = append(s.line, m)
+		err := s.search(next, discrepancies-i)
+		s.line = s.line[:len(s.line)-1]
+		if m.card >= 0 {
+			s.remaining[s.cards[m.card]] = true
+		}
+		if err != nil {
+			return err
+		}
+		if s.bestValue == 0 {
+			// Nothing beats a win.
+			return nil
+		}
+	}
+	s.memo[key] = memoEntry{discrepancies: int16(discrepancies), complete: !s.limited}
+	s.limited = s.limited || outerLimited
+	return nil
+}
+
+// finish records the end of the current line, leaving left cards.
+func (s *search) finish(left int) {
+	if left >= s.bestValue {
+		return
+	}
+	s.bestValue = left
+	s.bestLine = append(s.bestLine[:0], s.line...)
+	if left == 0 {
+		s.nodesToWin = s.nodes
+	}
+}
+
+// moves returns the legal moves from pos, most promising first: back-jumps,
+// then the plays that skip the fewest cards still to be played, with the end
+// of the turn ahead of any play that skips one or more.
+func (s *search) moves(pos position, handCount, deckLeft int) []step {
+	type scored struct {
+		step
+		skipped int32
+	}
+	var moves []scored
+	for i := range s.cards {
+		if !pos.hand.has(i) {
+			continue
+		}
+		card := s.cards[i]
+		for p, ascending := range s.ascending {
+			top := pos.tops[p]
+			if !game.CanPlayOnTop(ascending, top, card, s.backJump) {
+				continue
+			}
+			moves = append(moves, scored{step{i, p}, s.skipped(ascending, top, card)})
+		}
+	}
+
+	required := s.minPlays
+	if deckLeft == 0 {
+		required = s.minEmpty
+	}
+	if held := pos.played + handCount; held < required {
+		required = held
+	}
+	if pos.played > 0 && pos.played >= required {
+		moves = append([]scored{{step{card: -1}, 1}}, moves...)
+	}
+
+	sort.SliceStable(moves, func(i, j int) bool { return moves[i].skipped < moves[j].skipped })
+	steps := make([]step, len(moves))
+	for i, m := range moves {
+		steps[i] = m.step
+	}
+	return steps
+}
+
+// skipped returns how many cards still to be played lie between top and card,
+// which playing card on the pile puts out of its reach; a back-jump counts as
+// -1.
+func (s *search) skipped(ascending bool, top, card int32) int32 {
+	lo, hi := top, card
+	if !ascending {
+		lo, hi = card, top
+	}
+	if hi < lo {
+		return -1
+	}
+	n := int32(0)
+	for v := lo + 1; v < hi; v++ {
+		if s.isRemaining(v) {
+			n++
+		}
+	}
+	return n
+}
+
+// deadCards counts the cards still to be played that can never be played,
+// whatever happens, on piles at tops. Each card must be dead on every pile:
+// on an ascending pile, a card at or below the top can only be reached by a
+// back-jump onto it or below it, which needs the card a back-jump above to be
+// on top first.
+func (s *search) deadCards(tops [maxPiles]int32) int {
+	// low[p] is the lowest card an ascending pile p could ever back-jump to;
+	// for descending piles, the highest.
+	var low [maxPiles]int32
+	for p, ascending := range s.ascending {
+		top := tops[p]
+		if ascending {
+			low[p] = s.maxCard + 1
+			for w := s.minCard; w < top && w < low[p]; w++ {
+				if s.remaining[w] && (w+s.backJump == top || s.isRemaining(w+s.backJump)) {
+					low[p] = w
+				}
+			}
+		} else {
+			low[p] = s.minCard - 1
+			for w := s.maxCard; w > top && w > low[p]; w-- {
+				if s.remaining[w] && (w-s.backJump == top || s.isRemaining(w-s.backJump)) {
+					low[p] = w
+				}
+			}
+		}
+	}
+
+	dead := 0
+	for v := s.minCard; v <= s.maxCard; v++ {
+		if !s.remaining[v] {
+			continue
+		}
+		alive := false
+		for p, ascending := range s.ascending {
+			if ascending {
+				alive = v > tops[p] || low[p] <= v
+			} else {
+				alive = v < tops[p] || low[p] >= v
+			}
+			if alive {
+				break
+			}
+		}
+		if !alive {
+			dead++
+		}
+	}
+	return dead
+}
+
+func (s *search) isRemaining(v int32) bool {
+	return v >= s.minCard && v <= s.maxCard && s.remaining[v]
+}
+
+// key returns the memo key of pos.
+func (s *search) key(pos position) memoKey {
+	key := memoKey{tops: pos.tops, hand: pos.hand, drawn: int16(pos.drawn), played: int16(pos.played)}
+	sortTops(&key.tops, s.ascIdx)
+	sortTops(&key.tops, s.descIdx)
+	return key
+}
+
+// sortTops sorts the tops at the given indices among themselves.
+func sortTops(tops *[maxPiles]int32, idx []int) {
+	for i := 1; i < len(idx); i++ {
+		for j := i; j > 0 && tops[idx[j]] < tops[idx[j-1]]; j-- {
+			tops[idx[j]], tops[idx[j-1]] = tops[idx[j-1]], tops[idx[j]]
+		}
+	}
+}
+
+// turns converts the best line into the cards played in each turn.
+func (s *search) turns() [][]game.Move {
+	turns := [][]game.Move{nil}
+	for _, m := range s.bestLine {
+		if m.card < 0 {
+			turns = append(turns, nil)
+			continue
+		}
+		last := len(turns) - 1
+		turns[last] = append(turns[last], game.Move{Card: &pb.Card{Value: s.cards[m.card]}, Pile: s.pileIDs[m.pile]})
+	}
+	if len(turns[len(turns)-1]) == 0 {
+		turns = turns[:len(turns)-1]
+	}
+	return turns
+}
@@ -0,0 +1,156 @@
+package solver
+
+import (
+	"context"
+	"testing"
+
+	"the_game_card_game/pkg/game"
+	pb "the_game_card_game/proto"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+)
+
+// smallRules deal a game small enough to search completely. It has no
+// back-jumps, so many of its deals are lost.
+var smallRules = &game.RuleSet{
+	MinCard:    2,
+	MaxCard:    13,
+	HandSize:   3,
+	MaxPlayers: 1,
+	BackJump:   proto.Int32(0),
+	Piles: []*pb.PileRule{
+		{Id: "up", Ascending: true},
+		{Id: "down", Ascending: false},
+	},
+}
+
+func startedGame(t *testing.T, seed int64, rules *game.RuleSet) *pb.GameState {
+	t.Helper()
+	state, err := game.StartGame(game.NewGameWithRules("solver-game", "p1", seed, rules))
+	require.NoError(t, err)
+	return state
+}
+
+// replay plays the line r found and returns the final state.
+func replay(t *testing.T, state *pb.GameState, r *Result) *pb.GameState {
+	t.Helper()
+	var err error
+	for _, turn := range r.Turns {
+		for _, move := range turn {
+			state, err = game.PlayCard(state, "p1", move.Card.Value, move.Pile)
+			require.NoError(t, err)
+		}
+		if !game.IsOver(state) {
+			state, err = game.EndTurn(state, "p1")
+			require.NoError(t, err)
+		}
+	}
+	return state
+}
+
+// fewestCards searches every line from c without pruning.
+func fewestCards(c *game.Compact) int {
+	if c.IsOver() {
+		return c.CardsRemaining()
+	}
+	best := -1
+	if c.PlayedThisTurn > 0 && c.PlayedThisTurn >= c.MinPlaysToEndTurn() {
+		next := c.Clone()
+		if err := next.EndTurn(); err == nil {
+			best = fewestCards(next)
+		}
+	}
+	for i, card := range c.Hands[c.Current] {
+		for p := range c.Tops {
+			if !c.CanPlay(card, p) {
+				continue
+			}
+			next := c.Clone()
+			next.Play(i, p)
+			if v := fewestCards(next); best < 0 || v < best {
+				best = v
+			}
+		}
+	}
+	if best < 0 {
+		return c.CardsRemaining()
+	}
+	return best
+}
+
+func TestSolve_FindsTheFewestCards(t *testing.T) {
+	lost := 0
+	for seed := int64(1); seed <= 10; seed++ {
+		state := startedGame(t, seed, smallRules)
+		r, err := Solve(context.Background(), state, Options{})
+		require.NoError(t, err)
+		require.True(t, r.Exact, "seed %d", seed)
+
+		c, err := game.NewCompact(state)
+		require.NoError(t, err)
+		require.Equal(t, fewestCards(c), r.CardsRemaining, "seed %d", seed)
+
+		final := replay(t, state, r)
+		require.True(t, game.IsOver(final), "seed %d", seed)
+		require.Equal(t, r.CardsRemaining, game.CardsRemaining(final), "seed %d", seed)
+		require.Equal(t, r.Winnable, final.Status == pb.GameStatus_WON, "seed %d", seed)
+		if !r.Winnable {
+			lost++
+		}
+	}
+	require.NotZero(t, lost, "some deals should be lost")
+}
+
+func TestSolve_WinsAStandardDeal(t *testing.T) {
+	state := startedGame(t, 16, nil)
+	r, err := Solve(context.Background(), state, Options{})
+	require.NoError(t, err)
+	require.True(t, r.Winnable)
+	require.True(t, r.Exact)
+	require.Equal(t, Easy, r.Difficulty())
+	require.Equal(t, pb.GameStatus_WON, replay(t, state, r).Status)
+}
+
+func TestSolve_StopsAtTheBudget(t *testing.T) {
+	state := startedGame(t, 2, nil)
+	r, err := Solve(context.Background(), state, Options{MaxNodes: 500})
+	require.NoError(t, err)
+	require.False(t, r.Exact)
+	require.Equal(t, 500, r.Nodes)
+	require.Equal(t, Unknown, r.Difficulty())
+	require.Equal(t, r.CardsRemaining, game.CardsRemaining(replay(t, state, r)))
+}
+
+func TestSolve_StopsWhenCancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := Solve(ctx, startedGame(t, 2, nil), Options{})
+	require.ErrorIs(t, err, context.Canceled)
+}
+
+func TestSolve_RejectsHiddenInformation(t *testing.T) {
+	state := startedGame(t, 2, nil)
+	_, err := Solve(context.Background(), game.PlayerView(state, "p1"), Options{})
+	require.Error(t, err)
+
+	table, err := game.AddPlayer(game.NewSeededGame("solver-game", "p1", 2), "p2")
+	require.NoError(t, err)
+	_, err = Solve(context.Background(), table, Options{})
+	require.Error(t, err)
+}
+
+func TestResult_Difficulty(t *testing.T) {
+	for _, tc := range []struct {
+		result Result
+		want   Difficulty
+	}{
+		{Result{Winnable: true, Exact: true, NodesToWin: 100}, Easy},
+		{Result{Winnable: true, Exact: true, NodesToWin: 50000}, Medium},
+		{Result{Winnable: true, Exact: true, NodesToWin: 500000}, Hard},
+		{Result{Exact: true, CardsRemaining: 3}, Unwinnable},
+		{Result{CardsRemaining: 3}, Unknown},
+	} {
+		require.Equal(t, tc.want, tc.result.Difficulty(), "%+v", tc.result)
+	}
+}
@@ -0,0 +1,257 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	pb "the_game_card_game/proto"
+
+	"google.golang.org/protobuf/proto"
+)
+
+// MemoryStore is a Storer that keeps everything in process. It needs no Redis
+// or PostgreSQL, which makes it suitable for local play and end-to-end tests.
+// Nothing survives a restart.
+type MemoryStore struct {
+	mu      sync.Mutex
+	games   map[string]bool   // game ID -> is_active
+	states  map[string][]byte // game ID -> marshaled pb.GameState
+	moves   map[string][]MoveRecord
+	results map[string]GameResult
+	events  map[string][]GameUpdate  // game ID -> event log, oldest first
+	wake    map[string]chan struct{} // closed when a game's log grows
+
+	closed    chan struct{}
+	closeOnce sync.Once
+}
+
+var _ Storer = (*MemoryStore)(nil)
+
+// NewMemoryStore creates an empty MemoryStore.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		games:   make(map[string]bool),
+		states:  make(map[string][]byte),
+		moves:   make(map[string][]MoveRecord),
+		results: make(map[string]GameResult),
+		events:  make(map[string][]GameUpdate),
+		wake:    make(map[string]chan struct{}),
+		closed:  make(chan struct{}),
+	}
+}
+
+// Close ends every open subscription.
+func (s *MemoryStore) Close() {
+	s.closeOnce.Do(func() { close(s.closed) })
+}
+
+// CreateGame records a new, active game.
+func (s *MemoryStore) CreateGame(ctx context.Context, gameID string, playerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.games[gameID]; ok {
+		return fmt.Errorf("failed to insert game: game %s already exists", gameID)
+	}
+	s.games[gameID] = true
+	return nil
+}
+
+// AddPlayer checks that the game exists. Seats are only kept in the game state.
+func (s *MemoryStore) AddPlayer(ctx context.Context, gameID string, playerID string, seat int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.games[gameID]; !ok {
+		return fmt.Errorf("failed to insert game player: game %s not found", gameID)
+	}
+	return nil
+}
+
+// GetGameForTest returns a game's active status.
+func (s *MemoryStore) GetGameForTest(ctx context.Context, gameID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	isActive, ok := s.games[gameID]
+	if !ok {
+		return false, fmt.Errorf("failed to get game for test: game %s not found", gameID)
+	}
+	return isActive, nil
+}
+
+// GetGameState returns a copy of a game's state.
+func (s *MemoryStore) GetGameState(ctx context.Context, gameID string) (*pb.GameState, error) {
+	s.mu.Lock()
+	data, ok := s.states[gameID]
+	s.mu.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("failed to get game state: game %s not found", gameID)
+	}
+
+	state := &pb.GameState{}
+	if err := proto.Unmarshal(data, state); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal game state: %w", err)
+	}
+	return state, nil
+}
+
+// UpdateGameState saves a copy of state, replacing whatever was stored.
+func (s *MemoryStore) UpdateGameState(ctx context.Context, gameID string, state *pb.GameState) error {
+	data, err := proto.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("failed to marshal game state: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.states[gameID] = data
+	return nil
+}
+
+// CompareAndSetGameState saves state only if the stored state still has
+// expectedVersion. On success state.Version is set to expectedVersion+1 and an
+// update of the given kind, with events, is appended to the game's event log.
+// A stale write returns ErrVersionConflict.
+func (s *MemoryStore) CompareAndSetGameState(ctx context.Context, gameID string, expectedVersion int64, state *pb.GameState, kind UpdateKind, events ...*pb.GameEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	data, ok := s.states[gameID]
+	if !ok {
+		return fmt.Errorf("failed to get game state: game %s not found", gameID)
+	}
+	current := &pb.GameState{}
+	if err := proto.Unmarshal(data, current); err != nil {
+		return fmt.Errorf("failed to unmarshal game state: %w", err)
+	}
+	if current.GetVersion() != expectedVersion {
+		return fmt.Errorf("%w: expected version %d, found %d", ErrVersionConflict, expectedVersion, current.GetVersion())
+	}
+
+	state.Version = expectedVersion + 1
+	data, err := proto.Marshal(state)
+	if err != nil {
+		state.Version = expectedVersion
+		return fmt.Errorf("failed to marshal game state: %w", err)
+	}
+	s.states[gameID] = data
+	s.appendUpdate(GameUpdate{GameID: gameID, Version: state.Version, Kind: kind, Events: events})
+	return nil
+}
+
+// appendUpdate adds update to its game's event log and wakes the game's
+// subscribers. s.mu must be held.
+func (s *MemoryStore) appendUpdate(update GameUpdate) {
+	log := append(s.events[update.GameID], update)
+	if len(log) > eventLogLength {
+		log = append([]GameUpdate(nil), log[len(log)-eventLogLength:]...)
+	}
+	s.events[update.GameID] = log
+	if wake, ok := s.wake[update.GameID]; ok {
+		close(wake)
+		delete(s.wake, update.GameID)
+	}
+}
+
+// SaveMove appends a move to the game's history.
+func (s *MemoryStore) SaveMove(ctx context.Context, move MoveRecord) error {
+	if move.PlayedAt.IsZero() {
+		move.PlayedAt = time.Now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.moves[move.GameID] = append(s.moves[move.GameID], move)
+	return nil
+}
+
+// Moves returns the moves saved for a game in the order they were made.
+func (s *MemoryStore) Moves(gameID string) []MoveRecord {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	moves := append([]MoveRecord(nil), s.moves[gameID]...)
+	// Moves may be saved out of order; the game version orders them.
+	sort.SliceStable(moves, func(i, j int) bool { return moves[i].Version < moves[j].Version })
+	return moves
+}
+
+// FinishGame marks a game as no longer active and records its outcome.
+func (s *MemoryStore) FinishGame(ctx context.Context, result GameResult) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.games[result.GameID]; !ok {
+		return fmt.Errorf("failed to finish game: game %s not found", result.GameID)
+	}
+	s.games[result.GameID] = false
+	s.results[result.GameID] = result
+	return nil
+}
+
+// Result returns the outcome of a finished game.
+func (s *MemoryStore) Result(gameID string) (GameResult, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result, ok := s.results[gameID]
+	return result, ok
+}
+
+// --- Event Log ---
+
+// SubscribeToGameUpdates tails a game's event log. Updates for versions after
+// afterVersion that are still in the log are delivered first, then new ones as
+// they are written. It returns a channel for updates, a function to close the
+// subscription, and an error.
+func (s *MemoryStore) SubscribeToGameUpdates(ctx context.Context, gameID string, afterVersion int64) (<-chan GameUpdate, func(), error) {
+	ch := make(chan GameUpdate)
+	done := make(chan struct{})
+	go func() {
+		defer close(ch)
+		last := afterVersion
+		for {
+			pending, wake := s.updatesAfter(gameID, last)
+			for _, update := range pending {
+				select {
+				case ch <- update:
+					last = update.Version
+				case <-done:
+					return
+				case <-s.closed:
+					return
+				}
+			}
+			if len(pending) > 0 {
+				continue
+			}
+			select {
+			case <-wake:
+			case <-done:
+				return
+			case <-s.closed:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	closeFunc := func() {
+		once.Do(func() { close(done) })
+	}
+	return ch, closeFunc, nil
+}
+
+// updatesAfter returns the logged updates of a game after version, and a
+// channel that is closed when the log next grows.
+func (s *MemoryStore) updatesAfter(gameID string, version int64) ([]GameUpdate, <-chan struct{}) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	log := s.events[gameID]
+	i := sort.Search(len(log), func(i int) bool { return log[i].Version > version })
+	pending := append([]GameUpdate(nil), log[i:]...)
+
+	wake, ok := s.wake[gameID]
+	if !ok {
+		wake = make(chan struct{})
+		s.wake[gameID] = wake
+	}
+	return pending, wake
+}
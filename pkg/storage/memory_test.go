@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	pb "the_game_card_game/proto"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMemoryStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	defer store.Close()
+	gameID := "memory-game"
+
+	require.NoError(t, store.CreateGame(ctx, gameID, "player1"))
+	isActive, err := store.GetGameForTest(ctx, gameID)
+	require.NoError(t, err)
+	require.True(t, isActive)
+
+	_, err = store.GetGameState(ctx, gameID)
+	require.Error(t, err, "no state has been saved yet")
+
+	state := &pb.GameState{GameId: gameID, DeckSize: 98}
+	require.NoError(t, store.UpdateGameState(ctx, gameID, state))
+
+	// Callers get copies, not the stored state itself.
+	state.DeckSize = 0
+	retrieved, err := store.GetGameState(ctx, gameID)
+	require.NoError(t, err)
+	require.Equal(t, int32(98), retrieved.GetDeckSize())
+
+	retrieved.DeckSize = 97
+	require.NoError(t, store.CompareAndSetGameState(ctx, gameID, 0, retrieved, UpdateCardPlayed))
+	require.Equal(t, int64(1), retrieved.GetVersion())
+
+	stale := &pb.GameState{GameId: gameID, DeckSize: 96}
+	require.ErrorIs(t, store.CompareAndSetGameState(ctx, gameID, 0, stale, UpdateCardPlayed), ErrVersionConflict)
+	require.Equal(t, int64(0), stale.GetVersion())
+
+	// Moves saved out of order come back in version order.
+	require.NoError(t, store.SaveMove(ctx, MoveRecord{GameID: gameID, Version: 3, Kind: MoveEndTurn}))
+	require.NoError(t, store.SaveMove(ctx, MoveRecord{GameID: gameID, Version: 2, Kind: MovePlay, Card: 12, PileID: "up1"}))
+	moves := store.Moves(gameID)
+	require.Len(t, moves, 2)
+	require.Equal(t, int32(12), moves[0].Card)
+	require.Equal(t, MoveEndTurn, moves[1].Kind)
+	require.False(t, moves[0].PlayedAt.IsZero())
+
+	require.NoError(t, store.FinishGame(ctx, GameResult{GameID: gameID, Status: pb.GameStatus_LOST, CardsRemaining: 40}))
+	isActive, err = store.GetGameForTest(ctx, gameID)
+	require.NoError(t, err)
+	require.False(t, isActive)
+	result, ok := store.Result(gameID)
+	require.True(t, ok)
+	require.Equal(t, 40, result.CardsRemaining)
+}
+
+func TestMemoryStore_EventLog(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryStore()
+	defer store.Close()
+	require.NoError(t, store.UpdateGameState(ctx, "game", &pb.GameState{GameId: "game"}))
+	require.NoError(t, store.UpdateGameState(ctx, "other-game", &pb.GameState{GameId: "other-game"}))
+
+	// receive returns the next update on ch, failing the test if none arrives.
+	receive := func(ch <-chan GameUpdate) GameUpdate {
+		t.Helper()
+		select {
+		case update, ok := <-ch:
+			require.True(t, ok, "subscription closed unexpectedly")
+			return update
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for game update")
+			return GameUpdate{}
+		}
+	}
+
+	save := func(gameID string, version int64, kind UpdateKind) {
+		t.Helper()
+		state, err := store.GetGameState(ctx, gameID)
+		require.NoError(t, err)
+		require.NoError(t, store.CompareAndSetGameState(ctx, gameID, version, state, kind))
+	}
+
+	save("game", 0, UpdatePlayerJoined)
+	save("game", 1, UpdateGameStarted)
+
+	// A subscriber from the start replays the log, then follows new updates.
+	replay, closeReplay, err := store.SubscribeToGameUpdates(ctx, "game", 0)
+	require.NoError(t, err)
+	defer closeReplay()
+	require.Equal(t, GameUpdate{GameID: "game", Version: 1, Kind: UpdatePlayerJoined}, receive(replay))
+	require.Equal(t, GameUpdate{GameID: "game", Version: 2, Kind: UpdateGameStarted}, receive(replay))
+
+	// A subscriber that has seen version 2 only gets what follows.
+	live, closeLive, err := store.SubscribeToGameUpdates(ctx, "game", 2)
+	require.NoError(t, err)
+	other, closeOther, err := store.SubscribeToGameUpdates(ctx, "other-game", 0)
+	require.NoError(t, err)
+	defer closeOther()
+
+	save("game", 2, UpdateCardPlayed)
+	want := GameUpdate{GameID: "game", Version: 3, Kind: UpdateCardPlayed}
+	require.Equal(t, want, receive(replay))
+	require.Equal(t, want, receive(live))
+	select {
+	case update := <-other:
+		t.Fatalf("subscribers of other games must not be notified, got %+v", update)
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	// A closed subscription stops receiving updates; closing twice is harmless.
+	closeLive()
+	closeLive()
+	_, ok := <-live
+	require.False(t, ok)
+}
@@ -2,10 +2,17 @@ package storage
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
 	pb "the_game_card_game/proto"
 
 	"github.com/go-redis/redis/v8"
+	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"google.golang.org/protobuf/proto"
 )
@@ -13,15 +20,79 @@ import (
 // Storer defines the interface for all database operations.
 type Storer interface {
 	CreateGame(ctx context.Context, gameID string, playerID string) error
+	AddPlayer(ctx context.Context, gameID string, playerID string, seat int) error
 	GetGameForTest(ctx context.Context, gameID string) (bool, error)
 	GetGameState(ctx context.Context, gameID string) (*pb.GameState, error)
 	UpdateGameState(ctx context.Context, gameID string, state *pb.GameState) error
-	SaveMove(ctx context.Context, gameID string, playerID string, card int, pileID string) error
-	PublishGameUpdate(ctx context.Context, gameID string) error
-	SubscribeToGameUpdates(ctx context.Context, gameID string) (<-chan *redis.Message, func(), error)
+	CompareAndSetGameState(ctx context.Context, gameID string, expectedVersion int64, state *pb.GameState, kind UpdateKind, events ...*pb.GameEvent) error
+	SaveMove(ctx context.Context, move MoveRecord) error
+	FinishGame(ctx context.Context, result GameResult) error
+	SubscribeToGameUpdates(ctx context.Context, gameID string, afterVersion int64) (<-chan GameUpdate, func(), error)
 	Close()
 }
 
+// MoveKind says what a player did in a MoveRecord.
+type MoveKind string
+
+const (
+	MovePlay     MoveKind = "play"
+	MoveEndTurn  MoveKind = "end_turn"
+	MoveLeave    MoveKind = "leave"
+	MoveGameOver MoveKind = "game_over"
+)
+
+// MoveRecord is one entry in a game's history. A move that ends the game is
+// followed by a MoveGameOver record with the same version.
+type MoveRecord struct {
+	GameID     string
+	Version    int64 // Game version after the move; orders a game's moves.
+	TurnNumber int32
+	PlayerID   string
+	Kind       MoveKind
+	Card       int32  // Plays only.
+	PileID     string // Plays only.
+	// Top card of every pile before and after the move.
+	PilesBefore map[string]int32
+	PilesAfter  map[string]int32
+	PlayedAt    time.Time
+}
+
+// GameResult is the outcome of a finished game.
+type GameResult struct {
+	GameID         string
+	Status         pb.GameStatus
+	CardsRemaining int
+	Turns          int32 // Turn number the game ended on.
+}
+
+// UpdateKind says what caused a game update.
+type UpdateKind string
+
+const (
+	UpdatePlayerJoined UpdateKind = "player_join"
+	UpdateGameStarted  UpdateKind = "start_game"
+	UpdateCardPlayed   UpdateKind = "play_card"
+	UpdateTurnEnded    UpdateKind = "end_turn"
+	UpdateGameLeft     UpdateKind = "leave_game"
+)
+
+// GameUpdate is an entry in a game's event log, written whenever a new version
+// of the game's state is saved. The version doubles as the entry's position in
+// the log. Subscribers re-read the state to see what changed.
+type GameUpdate struct {
+	GameID  string     `json:"game_id"`
+	Version int64      `json:"version"`
+	Kind    UpdateKind `json:"kind"`
+	// What happened, in order. Subscribers share the events and must not
+	// modify them.
+	Events []*pb.GameEvent `json:"-"`
+}
+
+// ErrVersionConflict is returned by CompareAndSetGameState when the stored game
+// state no longer has the expected version. The caller should re-read the state
+// and retry.
+var ErrVersionConflict = errors.New("game state version conflict")
+
 // Store holds the clients for our databases. It implements the Storer interface.
 type Store struct {
 	Redis *redis.Client
@@ -60,13 +131,35 @@ func (s *Store) Close() {
 
 // --- Game Logic ---
 
-// CreateGame inserts a new game into PostgreSQL.
+// CreateGame inserts a new game and its creator into PostgreSQL.
 func (s *Store) CreateGame(ctx context.Context, gameID string, playerID string) error {
-	_, err := s.DB.Exec(ctx, "INSERT INTO games (game_id, is_active) VALUES ($1, $2)", gameID, true)
+	return s.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, "INSERT INTO games (game_id, is_active) VALUES ($1, $2)", gameID, true)
+		if err != nil {
+			return fmt.Errorf("failed to insert game: %w", err)
+		}
+		return addPlayer(ctx, tx, gameID, playerID, 0)
+	})
+}
+
+// AddPlayer records that playerID has taken seat in a game, creating the
+// player if this is their first game.
+func (s *Store) AddPlayer(ctx context.Context, gameID string, playerID string, seat int) error {
+	return s.DB.BeginFunc(ctx, func(tx pgx.Tx) error {
+		return addPlayer(ctx, tx, gameID, playerID, seat)
+	})
+}
+
+func addPlayer(ctx context.Context, tx pgx.Tx, gameID string, playerID string, seat int) error {
+	// Players are identified by the ID they play under, which doubles as their name.
+	_, err := tx.Exec(ctx, "INSERT INTO players (player_id, name) VALUES ($1, $1) ON CONFLICT (player_id) DO NOTHING", playerID)
 	if err != nil {
-		return fmt.Errorf("failed to insert game: %w", err)
+		return fmt.Errorf("failed to insert player: %w", err)
+	}
+	_, err = tx.Exec(ctx, "INSERT INTO game_players (game_id, player_id, seat) VALUES ($1, $2, $3) ON CONFLICT (game_id, player_id) DO NOTHING", gameID, playerID, seat)
+	if err != nil {
+		return fmt.Errorf("failed to insert game player: %w", err)
 	}
-	// We would also add the player to the players table here
 	return nil
 }
 
@@ -82,7 +175,7 @@ func (s *Store) GetGameForTest(ctx context.Context, gameID string) (bool, error)
 
 // GetGameState retrieves a game's state from Redis.
 func (s *Store) GetGameState(ctx context.Context, gameID string) (*pb.GameState, error) {
-	val, err := s.Redis.Get(ctx, fmt.Sprintf("game:%s", gameID)).Result()
+	val, err := s.Redis.Get(ctx, gameKey(gameID)).Result()
 	if err != nil {
 		return nil, fmt.Errorf("failed to get game state from redis: %w", err)
 	}
@@ -101,43 +194,241 @@ func (s *Store) UpdateGameState(ctx context.Context, gameID string, state *pb.Ga
 		return fmt.Errorf("failed to marshal game state: %w", err)
 	}
 
-	err = s.Redis.Set(ctx, fmt.Sprintf("game:%s", gameID), data, 0).Err()
+	err = s.Redis.Set(ctx, gameKey(gameID), data, 0).Err()
 	if err != nil {
 		return fmt.Errorf("failed to set game state in redis: %w", err)
 	}
 	return nil
 }
 
-func (s *Store) SaveMove(ctx context.Context, gameID string, playerID string, card int, pileID string) error {
-	// TODO: Implement logic to save a move to PostgreSQL
-	return nil
+// CompareAndSetGameState saves state only if the stored state still has
+// expectedVersion, using WATCH/MULTI so that concurrent writers cannot
+// interleave. On success state.Version is set to expectedVersion+1 and an
+// update of the given kind, with events, is appended to the game's event log
+// under that version. A stale write returns ErrVersionConflict.
+func (s *Store) CompareAndSetGameState(ctx context.Context, gameID string, expectedVersion int64, state *pb.GameState, kind UpdateKind, events ...*pb.GameEvent) error {
+	key := gameKey(gameID)
+	state.Version = expectedVersion + 1
+	data, err := proto.Marshal(state)
+	if err != nil {
+		state.Version = expectedVersion
+		return fmt.Errorf("failed to marshal game state: %w", err)
+	}
+	update, err := json.Marshal(GameUpdate{GameID: gameID, Version: state.Version, Kind: kind})
+	if err != nil {
+		state.Version = expectedVersion
+		return fmt.Errorf("failed to marshal game update: %w", err)
+	}
+	encodedEvents, err := marshalEvents(events)
+	if err != nil {
+		state.Version = expectedVersion
+		return err
+	}
+
+	txf := func(tx *redis.Tx) error {
+		val, err := tx.Get(ctx, key).Bytes()
+		if err != nil {
+			return fmt.Errorf("failed to get game state from redis: %w", err)
+		}
+		current := &pb.GameState{}
+		if err := proto.Unmarshal(val, current); err != nil {
+			return fmt.Errorf("failed to unmarshal game state: %w", err)
+		}
+		if current.GetVersion() != expectedVersion {
+			return fmt.Errorf("%w: expected version %d, found %d", ErrVersionConflict, expectedVersion, current.GetVersion())
+		}
+
+		// The log entry is written in the same transaction as the state, so
+		// entry IDs always increase with the version.
+		_, err = tx.TxPipelined(ctx, func(pipe redis.Pipeliner) error {
+			pipe.Set(ctx, key, data, 0)
+			pipe.XAdd(ctx, &redis.XAddArgs{
+				Stream:       eventsKey(gameID),
+				ID:           streamID(state.Version),
+				MaxLenApprox: eventLogLength,
+				Values:       map[string]interface{}{"update": update, "events": encodedEvents},
+			})
+			return nil
+		})
+		return err
+	}
+
+	err = s.Redis.Watch(ctx, txf, key)
+	if err != nil {
+		state.Version = expectedVersion
+	}
+	if errors.Is(err, redis.TxFailedErr) {
+		// The key changed between WATCH and EXEC.
+		return fmt.Errorf("%w: game %s was modified concurrently", ErrVersionConflict, gameID)
+	}
+	return err
 }
 
-// --- Pub/Sub ---
+// eventLogLength is roughly how many updates are kept in a game's event log.
+// Subscribers resuming from further back only get the updates that are left,
+// which is enough to know that they should re-read the state.
+const eventLogLength = 1000
 
-// PublishGameUpdate sends a notification to a game's channel that it has been updated.
-func (s *Store) PublishGameUpdate(ctx context.Context, gameID string) error {
-	channel := fmt.Sprintf("game-updates:%s", gameID)
-	// The message can be simple, its presence is the notification.
-	return s.Redis.Publish(ctx, channel, "update").Err()
+func gameKey(gameID string) string {
+	return fmt.Sprintf("game:%s", gameID)
 }
 
-// SubscribeToGameUpdates subscribes to a game's update channel.
-// It returns a channel for messages, a function to close the subscription, and an error.
-func (s *Store) SubscribeToGameUpdates(ctx context.Context, gameID string) (<-chan *redis.Message, func(), error) {
-	channel := fmt.Sprintf("game-updates:%s", gameID)
-	pubsub := s.Redis.Subscribe(ctx, channel)
+func eventsKey(gameID string) string {
+	return fmt.Sprintf("game-events:%s", gameID)
+}
+
+// streamID returns the event log ID of a game version.
+func streamID(version int64) string {
+	return fmt.Sprintf("%d-0", version)
+}
 
-	// Wait for subscription to be confirmed.
-	_, err := pubsub.Receive(ctx)
+// marshalEvents encodes events for an event log entry.
+func marshalEvents(events []*pb.GameEvent) (string, error) {
+	raw := make([][]byte, len(events))
+	for i, event := range events {
+		data, err := proto.Marshal(event)
+		if err != nil {
+			return "", fmt.Errorf("failed to marshal game event: %w", err)
+		}
+		raw[i] = data
+	}
+	data, err := json.Marshal(raw)
 	if err != nil {
-		return nil, nil, fmt.Errorf("failed to subscribe to game updates: %w", err)
+		return "", fmt.Errorf("failed to marshal game events: %w", err)
+	}
+	return string(data), nil
+}
+
+// unmarshalEvents decodes the events of an event log entry. Entries written
+// before events were logged have none. An entry without events decodes to
+// nil, as the MemoryStore returns it.
+func unmarshalEvents(encoded string) ([]*pb.GameEvent, error) {
+	if encoded == "" {
+		return nil, nil
+	}
+	var raw [][]byte
+	if err := json.Unmarshal([]byte(encoded), &raw); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal game events: %w", err)
+	}
+	if len(raw) == 0 {
+		return nil, nil
+	}
+	events := make([]*pb.GameEvent, len(raw))
+	for i, data := range raw {
+		events[i] = &pb.GameEvent{}
+		if err := proto.Unmarshal(data, events[i]); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal game event: %w", err)
+		}
+	}
+	return events, nil
+}
+
+// streamVersion returns the game version of an event log ID.
+func streamVersion(id string) int64 {
+	ms, _, _ := strings.Cut(id, "-")
+	version, _ := strconv.ParseInt(ms, 10, 64)
+	return version
+}
+
+// SaveMove appends a move to the game's history in PostgreSQL.
+func (s *Store) SaveMove(ctx context.Context, move MoveRecord) error {
+	pilesBefore, err := json.Marshal(move.PilesBefore)
+	if err != nil {
+		return fmt.Errorf("failed to marshal piles: %w", err)
+	}
+	pilesAfter, err := json.Marshal(move.PilesAfter)
+	if err != nil {
+		return fmt.Errorf("failed to marshal piles: %w", err)
+	}
+
+	// Only plays have a card and a pile.
+	var card *int32
+	var pileID *string
+	if move.Kind == MovePlay {
+		card, pileID = &move.Card, &move.PileID
+	}
+	playedAt := move.PlayedAt
+	if playedAt.IsZero() {
+		playedAt = time.Now()
+	}
+
+	_, err = s.DB.Exec(ctx, `INSERT INTO moves
+		(game_id, player_id, kind, game_version, turn_number, card_played, pile_id, piles_before, piles_after, move_timestamp)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)`,
+		move.GameID, move.PlayerID, string(move.Kind), move.Version, move.TurnNumber, card, pileID, pilesBefore, pilesAfter, playedAt)
+	if err != nil {
+		return fmt.Errorf("failed to insert move: %w", err)
+	}
+	return nil
+}
+
+// FinishGame marks a game as no longer active and records its outcome.
+func (s *Store) FinishGame(ctx context.Context, result GameResult) error {
+	tag, err := s.DB.Exec(ctx, `UPDATE games
+		SET is_active = FALSE, status = $2, cards_remaining = $3, turns = $4, finished_at = CURRENT_TIMESTAMP
+		WHERE game_id = $1`,
+		result.GameID, result.Status.String(), result.CardsRemaining, result.Turns)
+	if err != nil {
+		return fmt.Errorf("failed to finish game: %w", err)
 	}
+	if tag.RowsAffected() == 0 {
+		return fmt.Errorf("failed to finish game: game %s not found", result.GameID)
+	}
+	return nil
+}
+
+// --- Event Log ---
 
-	ch := pubsub.Channel()
-	closeFunc := func() {
-		pubsub.Close()
+// SubscribeToGameUpdates tails a game's event log. Updates for versions after
+// afterVersion that are still in the log are delivered first, then new ones as
+// they are written. It returns a channel for updates, a function to close the
+// subscription, and an error. The channel is closed if the log can no longer
+// be read; subscribers can resubscribe from the last version they saw.
+func (s *Store) SubscribeToGameUpdates(ctx context.Context, gameID string, afterVersion int64) (<-chan GameUpdate, func(), error) {
+	// Fail early if Redis is unreachable.
+	if err := s.Redis.Ping(ctx).Err(); err != nil {
+		return nil, nil, fmt.Errorf("failed to subscribe to game updates: %w", err)
 	}
 
-	return ch, closeFunc, nil
+	ch := make(chan GameUpdate)
+	readCtx, cancel := context.WithCancel(context.Background())
+	go func() {
+		defer close(ch)
+		lastID := streamID(afterVersion)
+		for readCtx.Err() == nil {
+			streams, err := s.Redis.XRead(readCtx, &redis.XReadArgs{
+				Streams: []string{eventsKey(gameID), lastID},
+				Count:   100,
+				Block:   time.Second,
+			}).Result()
+			if errors.Is(err, redis.Nil) {
+				continue // Nothing new yet.
+			}
+			if err != nil {
+				return
+			}
+			for _, stream := range streams {
+				for _, msg := range stream.Messages {
+					lastID = msg.ID
+					update := GameUpdate{GameID: gameID}
+					payload, _ := msg.Values["update"].(string)
+					if err := json.Unmarshal([]byte(payload), &update); err != nil {
+						// An entry we cannot decode is still a signal to re-read.
+						update = GameUpdate{GameID: gameID, Version: streamVersion(msg.ID)}
+					}
+					encodedEvents, _ := msg.Values["events"].(string)
+					if update.Events, err = unmarshalEvents(encodedEvents); err != nil {
+						update.Events = nil
+					}
+					select {
+					case ch <- update:
+					case <-readCtx.Done():
+						return
+					}
+				}
+			}
+		}
+	}()
+
+	return ch, cancel, nil
 }
@@ -9,6 +9,7 @@ import (
 
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
 )
 
 func TestMain(m *testing.M) {
@@ -25,6 +26,22 @@ func TestMain(m *testing.M) {
 	os.Exit(m.Run())
 }
 
+func TestMarshalEvents(t *testing.T) {
+	encoded, err := marshalEvents(nil)
+	require.NoError(t, err)
+	events, err := unmarshalEvents(encoded)
+	require.NoError(t, err)
+	require.Nil(t, events, "an update without events has none, not an empty list")
+
+	played := &pb.GameEvent{Sequence: 3, Event: &pb.GameEvent_CardPlayed{CardPlayed: &pb.CardPlayed{PlayerId: "p1", Card: &pb.Card{Value: 20}, PileId: "up1"}}}
+	encoded, err = marshalEvents([]*pb.GameEvent{played})
+	require.NoError(t, err)
+	events, err = unmarshalEvents(encoded)
+	require.NoError(t, err)
+	require.Len(t, events, 1)
+	require.True(t, proto.Equal(played, events[0]))
+}
+
 func TestDatabaseConnections(t *testing.T) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
@@ -48,6 +65,30 @@ func TestDatabaseConnections(t *testing.T) {
 		require.True(t, isActive, "game should be active")
 	})
 
+	t.Run("PostgreSQLHistory", func(t *testing.T) {
+		require.NoError(t, store.AddPlayer(ctx, gameID, "player-test-456", 1))
+		// Joining twice is harmless.
+		require.NoError(t, store.AddPlayer(ctx, gameID, "player-test-456", 1))
+
+		require.NoError(t, store.SaveMove(ctx, MoveRecord{
+			GameID: gameID, Version: 2, TurnNumber: 1, PlayerID: playerID, Kind: MovePlay,
+			Card: 12, PileID: "up1", PilesBefore: map[string]int32{"up1": 1}, PilesAfter: map[string]int32{"up1": 12},
+		}))
+		require.NoError(t, store.SaveMove(ctx, MoveRecord{
+			GameID: gameID, Version: 3, TurnNumber: 1, PlayerID: playerID, Kind: MoveEndTurn,
+		}))
+
+		var moves int
+		err := store.DB.QueryRow(ctx, "SELECT count(*) FROM moves WHERE game_id = $1 AND card_played IS NOT NULL", gameID).Scan(&moves)
+		require.NoError(t, err)
+		require.Equal(t, 1, moves, "only plays have a card")
+
+		require.NoError(t, store.FinishGame(ctx, GameResult{GameID: gameID, Status: pb.GameStatus_LOST, CardsRemaining: 40, Turns: 1}))
+		isActive, err := store.GetGameForTest(ctx, gameID)
+		require.NoError(t, err)
+		require.False(t, isActive, "a finished game is no longer active")
+	})
+
 	// --- Test Redis ---
 	t.Run("Redis", func(t *testing.T) {
 		// Write
@@ -65,4 +106,35 @@ func TestDatabaseConnections(t *testing.T) {
 		require.Equal(t, gameID, retrievedState.GetGameId())
 		require.Equal(t, int32(98), retrievedState.GetDeckSize())
 	})
-} 
\ No newline at end of file
+
+	t.Run("CompareAndSet", func(t *testing.T) {
+		state, err := store.GetGameState(ctx, gameID)
+		require.NoError(t, err)
+		version := state.GetVersion()
+
+		state.DeckSize = 97
+		require.NoError(t, store.CompareAndSetGameState(ctx, gameID, version, state, UpdateCardPlayed))
+		require.Equal(t, version+1, state.GetVersion())
+
+		// A writer that read the old version must be rejected.
+		stale := &pb.GameState{GameId: gameID, DeckSize: 96}
+		err = store.CompareAndSetGameState(ctx, gameID, version, stale, UpdateCardPlayed)
+		require.ErrorIs(t, err, ErrVersionConflict)
+
+		retrievedState, err := store.GetGameState(ctx, gameID)
+		require.NoError(t, err)
+		require.Equal(t, int32(97), retrievedState.GetDeckSize())
+		require.Equal(t, version+1, retrievedState.GetVersion())
+
+		// The saved version was logged for subscribers; the stale one was not.
+		updates, closeSub, err := store.SubscribeToGameUpdates(ctx, gameID, version)
+		require.NoError(t, err)
+		defer closeSub()
+		select {
+		case update := <-updates:
+			require.Equal(t, GameUpdate{GameID: gameID, Version: version + 1, Kind: UpdateCardPlayed}, update)
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for game update")
+		}
+	})
+}